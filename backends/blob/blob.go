@@ -0,0 +1,58 @@
+// Package blob stores uploaded files such as recipe images. The local
+// filesystem is used by default, any S3 compatible service can be used
+// instead.
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned when there is no blob with the requested key
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored blob
+type Info struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Store is somewhere blobs can be kept. Keys are slash separated paths.
+type Store interface {
+	Put(key string, contentType string, body io.ReadSeeker) error
+	Get(key string) (io.ReadCloser, *Info, error)
+	Delete(key string) error
+}
+
+// FromEnv picks a store based on BLOB_STORE, which is either "local" (the
+// default) or "s3"
+func FromEnv() (Store, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint: os.Getenv("S3_ENDPOINT"),
+			Region:   os.Getenv("S3_REGION"),
+			Bucket:   os.Getenv("S3_BUCKET"),
+		})
+	}
+
+	return nil, fmt.Errorf("unknown blob store %q", os.Getenv("BLOB_STORE"))
+}
+
+// Must panics if the store could not be created, like session.Must
+func Must(store Store, err error) Store {
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
@@ -0,0 +1,56 @@
+package blob
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating store: %s", err.Error())
+	}
+
+	err = store.Put("recipes/1/original.jpg", "image/jpeg", strings.NewReader("jpeg bytes"))
+	if err != nil {
+		t.Fatalf("Error putting blob: %s", err.Error())
+	}
+
+	body, info, err := store.Get("recipes/1/original.jpg")
+	if err != nil {
+		t.Fatalf("Error getting blob: %s", err.Error())
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(data) != "jpeg bytes" || info.ContentType != "image/jpeg" || info.Size != 10 {
+		t.Errorf("Got wrong blob: %q %+v", data, info)
+	}
+
+	err = store.Delete("recipes/1/original.jpg")
+	if err != nil {
+		t.Fatalf("Error deleting blob: %s", err.Error())
+	}
+	_, _, err = store.Get("recipes/1/original.jpg")
+	if err != ErrNotFound {
+		t.Errorf("Found deleted blob")
+	}
+}
+
+func TestLocalStoreStaysInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir + "/blobs")
+	if err != nil {
+		t.Fatalf("Error creating store: %s", err.Error())
+	}
+
+	err = store.Put("../escaped", "text/plain", strings.NewReader("oops"))
+	if err != nil {
+		t.Fatalf("Error putting blob: %s", err.Error())
+	}
+
+	_, err = ioutil.ReadFile(dir + "/escaped")
+	if err == nil {
+		t.Error("Blob was written outside of the store's directory")
+	}
+}
@@ -0,0 +1,149 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// S3Config points at a bucket on any S3 compatible service
+type S3Config struct {
+	// Endpoint defaults to AWS, set it for MinIO and friends
+	Endpoint string
+	Region   string
+	Bucket   string
+}
+
+// S3Store keeps blobs in an S3 bucket using path style requests, which every
+// S3 compatible service supports
+type S3Store struct {
+	config     S3Config
+	signer     *v4.Signer
+	httpClient *http.Client
+}
+
+// NewS3Store uses the standard AWS credential chain to sign requests
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("S3_BUCKET is required for the s3 blob store")
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating aws session: %w", err)
+	}
+
+	return newS3Store(config, sess.Config.Credentials), nil
+}
+
+func newS3Store(config S3Config, creds *credentials.Credentials) *S3Store {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+
+	return &S3Store{
+		config: config,
+		signer: v4.NewSigner(creds, func(signer *v4.Signer) {
+			// S3 expects object keys to be escaped exactly once
+			signer.DisableURIPathEscaping = true
+		}),
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (store *S3Store) objectURL(key string) string {
+	return strings.TrimSuffix(store.config.Endpoint, "/") + "/" + store.config.Bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+// sign and send a request for the object
+func (store *S3Store) do(method string, key string, contentType string, body io.ReadSeeker) (*http.Response, error) {
+	request, err := http.NewRequest(method, store.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if body != nil {
+		// S3 rejects chunked uploads, so the length has to be known up front
+		size, err := body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		_, err = body.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		request.ContentLength = size
+	}
+
+	_, err = store.signer.Sign(request, body, "s3", store.config.Region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error signing request: %w", err)
+	}
+
+	return store.httpClient.Do(request)
+}
+
+// Put uploads the blob
+func (store *S3Store) Put(key string, contentType string, body io.ReadSeeker) error {
+	response, err := store.do(http.MethodPut, key, contentType, body)
+	if err != nil {
+		return fmt.Errorf("error uploading blob: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error uploading blob: s3 returned %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Get downloads the blob, callers must close the returned body
+func (store *S3Store) Get(key string) (io.ReadCloser, *Info, error) {
+	response, err := store.do(http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error downloading blob: %w", err)
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, nil, ErrNotFound
+	default:
+		response.Body.Close()
+		return nil, nil, fmt.Errorf("error downloading blob: s3 returned %d", response.StatusCode)
+	}
+
+	modTime, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return response.Body, &Info{
+		ContentType: response.Header.Get("Content-Type"),
+		Size:        response.ContentLength,
+		ModTime:     modTime,
+	}, nil
+}
+
+// Delete removes the blob, S3 doesn't complain about missing keys
+func (store *S3Store) Delete(key string) error {
+	response, err := store.do(http.MethodDelete, key, "", nil)
+	if err != nil {
+		return fmt.Errorf("error deleting blob: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error deleting blob: s3 returned %d", response.StatusCode)
+	}
+	return nil
+}
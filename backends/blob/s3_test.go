@@ -0,0 +1,115 @@
+package blob
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// fakeS3 keeps objects in memory, checking each request is signed for the
+// bucket's region
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	contentType string
+	data        []byte
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(authorization, "/us-west-2/s3/aws4_request") {
+		s3.t.Errorf("Request wasn't signed for the bucket: %q", authorization)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		s3.t.Errorf("Request is missing signing headers: %v", r.Header)
+	}
+
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			s3.t.Error("Upload was chunked")
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		s3.objects[r.URL.Path] = fakeObject{contentType: r.Header.Get("Content-Type"), data: data}
+	case http.MethodGet:
+		object, ok := s3.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.data)
+	case http.MethodDelete:
+		delete(s3.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	s3 := &fakeS3{t: t, objects: map[string]fakeObject{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	store := newS3Store(
+		S3Config{Endpoint: server.URL, Region: "us-west-2", Bucket: "thyme"},
+		credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	)
+
+	err := store.Put("recipes/1/original.jpg", "image/jpeg", strings.NewReader("jpeg bytes"))
+	if err != nil {
+		t.Fatalf("Error putting blob: %s", err.Error())
+	}
+	if _, ok := s3.objects["/thyme/recipes/1/original.jpg"]; !ok {
+		t.Fatalf("Expected a path style upload, got %v", s3.objects)
+	}
+
+	body, info, err := store.Get("recipes/1/original.jpg")
+	if err != nil {
+		t.Fatalf("Error getting blob: %s", err.Error())
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(data) != "jpeg bytes" || info.ContentType != "image/jpeg" || info.Size != 10 {
+		t.Errorf("Got wrong blob: %q %+v", data, info)
+	}
+
+	err = store.Delete("recipes/1/original.jpg")
+	if err != nil {
+		t.Fatalf("Error deleting blob: %s", err.Error())
+	}
+	_, _, err = store.Get("recipes/1/original.jpg")
+	if err != ErrNotFound {
+		t.Errorf("Found deleted blob")
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := newS3Store(S3Config{Endpoint: server.URL, Bucket: "thyme"}, credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	if err := store.Put("key", "text/plain", strings.NewReader("data")); err == nil {
+		t.Error("Expected failed uploads to be errors")
+	}
+	if _, _, err := store.Get("key"); err == nil || err == ErrNotFound {
+		t.Errorf("Expected failed downloads to be errors, got %v", err)
+	}
+	if err := store.Delete("key"); err == nil {
+		t.Error("Expected failed deletes to be errors")
+	}
+}
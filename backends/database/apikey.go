@@ -0,0 +1,238 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key token so they are easy to spot in scripts
+// and secret scanners
+const APIKeyPrefix = "thyme_"
+
+// ErrInvalidAPIKey is returned when a token is malformed, unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// Scope limits what an API key is allowed to do
+type Scope string
+
+const (
+	// ScopeRead only allows reading data
+	ScopeRead Scope = "read"
+	// ScopeReadWrite allows reading and modifying data
+	ScopeReadWrite Scope = "read-write"
+)
+
+// Valid reports whether the scope is one we know about
+func (scope Scope) Valid() bool {
+	return scope == ScopeRead || scope == ScopeReadWrite
+}
+
+// APIKey is a personal key used by scripts and integrations. Only a hash of
+// the secret half of the token is stored.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	Name       string     `json:"name"`
+	Scope      Scope      `json:"scope"`
+	SecretHash string     `json:"-" dynamodbav:"secretHash"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateAPIKey creates a new key for the user, returning the stored key and
+// the plaintext token. The token cannot be recovered later.
+func (client *Client) CreateAPIKey(userID string, name string, scope Scope) (*APIKey, string, error) {
+	if !scope.Valid() {
+		return nil, "", fmt.Errorf("unknown scope: %s", scope)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating UUID: %w", err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := APIKey{
+		ID:         id.String(),
+		UserID:     userID,
+		Name:       name,
+		Scope:      scope,
+		SecretHash: hashSecret(secret),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	av, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshalling api key: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(APIKeyTable),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error saving api key: %w", err)
+	}
+
+	return &key, APIKeyPrefix + key.ID + "_" + secret, nil
+}
+
+// GetAPIKey fetches an API key by it's ID
+func (client *Client) GetAPIKey(id string) (*APIKey, error) {
+	var key *APIKey
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(APIKeyTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find api key with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns every key (including revoked ones) owned by the user
+func (client *Client) ListAPIKeys(userID string) ([]APIKey, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(APIKeyTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []APIKey{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := []APIKey{}
+	for _, key := range keys {
+		if key.UserID == userID {
+			owned = append(owned, key)
+		}
+	}
+
+	return owned, nil
+}
+
+// RevokeAPIKey marks a key as revoked so it can no longer authenticate
+func (client *Client) RevokeAPIKey(id string) error {
+	_, err := client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(APIKeyTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression: aws.String("SET revokedAt = :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {S: aws.String(time.Now().UTC().Format(time.RFC3339Nano))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext token to it's key and records
+// when it was last used
+func (client *Client) AuthenticateAPIKey(token string) (*APIKey, error) {
+	id, secret, ok := parseToken(APIKeyPrefix, token)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := client.GetAPIKey(id)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	_, err = client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(APIKeyTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression: aws.String("SET lastUsedAt = :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {S: aws.String(now.Format(time.RFC3339Nano))},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating api key: %w", err)
+	}
+	key.LastUsedAt = &now
+
+	return key, nil
+}
+
+// splits a token of the form <prefix><id>_<secret>
+func parseToken(prefix string, token string) (string, string, bool) {
+	if !strings.HasPrefix(token, prefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(token, prefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// generates the random half of a token
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("error generating secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
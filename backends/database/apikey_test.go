@@ -0,0 +1,53 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	mockClient := newMockClient()
+
+	key, token, err := mockClient.CreateAPIKey("sam", "import script", ScopeRead)
+	if err != nil {
+		t.Fatalf("Error creating api key: %s", err.Error())
+	}
+	if strings.Contains(key.SecretHash, strings.TrimPrefix(token, APIKeyPrefix+key.ID+"_")) {
+		t.Errorf("Secret stored in plaintext")
+	}
+
+	authenticated, err := mockClient.AuthenticateAPIKey(token)
+	if err != nil {
+		t.Fatalf("Error authenticating api key: %s", err.Error())
+	}
+	if authenticated.UserID != "sam" || authenticated.Scope != ScopeRead {
+		t.Errorf("Authenticated wrong key: %+v", authenticated)
+	}
+	if authenticated.LastUsedAt == nil {
+		t.Errorf("Last used timestamp not recorded")
+	}
+
+	_, err = mockClient.AuthenticateAPIKey(token + "0")
+	if err != ErrInvalidAPIKey {
+		t.Errorf("Authenticated api key with wrong secret")
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	mockClient := newMockClient()
+
+	key, token, err := mockClient.CreateAPIKey("sam", "sync", ScopeReadWrite)
+	if err != nil {
+		t.Fatalf("Error creating api key: %s", err.Error())
+	}
+
+	err = mockClient.RevokeAPIKey(key.ID)
+	if err != nil {
+		t.Fatalf("Error revoking api key: %s", err.Error())
+	}
+
+	_, err = mockClient.AuthenticateAPIKey(token)
+	if err != ErrInvalidAPIKey {
+		t.Errorf("Authenticated revoked api key")
+	}
+}
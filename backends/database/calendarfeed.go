@@ -0,0 +1,124 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// CalendarFeed lets calendar apps, which can't log in, subscribe to a user's
+// meal plan. Like shares, it's ID is the hash of the token in the feed's URL.
+type CalendarFeed struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	// Household feeds have the meal plans of everyone in the user's
+	// household, whoever is in it when the feed is read
+	Household bool      `json:"household,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateCalendarFeed mints a feed for the user, replacing any feed of the
+// same kind they already had. It returns the feed and the token to put in
+// it's URL.
+func (client *Client) CreateCalendarFeed(userID string, household bool) (*CalendarFeed, string, error) {
+	err := client.DeleteCalendarFeeds(userID, household)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	feed := CalendarFeed{
+		ID:        hashSecret(token),
+		UserID:    userID,
+		Household: household,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	av, err := dynamodbattribute.MarshalMap(feed)
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshalling calendar feed: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(CalendarFeedTable),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error saving calendar feed: %w", err)
+	}
+
+	return &feed, token, nil
+}
+
+// GetCalendarFeedByToken resolves the token from a feed URL
+func (client *Client) GetCalendarFeedByToken(token string) (*CalendarFeed, error) {
+	var feed *CalendarFeed
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(CalendarFeedTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(hashSecret(token)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find calendar feed")
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &feed)
+	if err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// DeleteCalendarFeeds revokes the user's household feeds, or their own meal
+// plan's feeds
+func (client *Client) DeleteCalendarFeeds(userID string, household bool) error {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(CalendarFeedTable),
+	})
+	if err != nil {
+		return err
+	}
+
+	feeds := []CalendarFeed{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &feeds)
+	if err != nil {
+		return err
+	}
+
+	for _, feed := range feeds {
+		if feed.UserID != userID || feed.Household != household {
+			continue
+		}
+
+		_, err = client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(CalendarFeedTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {
+					S: aws.String(feed.ID),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting calendar feed: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// Category is a node in the category taxonomy, like Cookies under Dessert
+// under Course. Categories without a ParentID are at the top.
+type Category struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CategoryNode is a category and the ones under it, by name
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// CategoryTree arranges categories into a tree, by name
+func CategoryTree(categories []Category) []CategoryNode {
+	children := map[string][]Category{}
+	for _, category := range categories {
+		children[category.ParentID] = append(children[category.ParentID], category)
+	}
+
+	var grow func(parentID string) []CategoryNode
+	grow = func(parentID string) []CategoryNode {
+		nodes := []CategoryNode{}
+		for _, category := range children[parentID] {
+			nodes = append(nodes, CategoryNode{Category: category, Children: grow(category.ID)})
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+		return nodes
+	}
+	return grow("")
+}
+
+// CategoryPath returns the categories from the top of the taxonomy down to
+// the one with the ID, like Course, Dessert, Cookies
+func CategoryPath(categories []Category, id string) []Category {
+	byID := map[string]Category{}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	path := []Category{}
+	for category, ok := byID[id]; ok; category, ok = byID[category.ParentID] {
+		path = append([]Category{category}, path...)
+		// a broken taxonomy shouldn't loop forever
+		if len(path) > len(categories) {
+			break
+		}
+	}
+	return path
+}
+
+// CategoryDescendants returns the ID of a category and of every category
+// under it
+func CategoryDescendants(categories []Category, id string) map[string]bool {
+	descendants := map[string]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, category := range categories {
+			if descendants[category.ParentID] && !descendants[category.ID] {
+				descendants[category.ID] = true
+				grew = true
+			}
+		}
+	}
+	return descendants
+}
+
+// InCategory reports whether the recipe is in any of the categories
+func (recipe Recipe) InCategory(categoryIDs map[string]bool) bool {
+	for _, categoryID := range recipe.CategoryIDs {
+		if categoryIDs[categoryID] {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveCategory adds a category to the taxonomy
+func (client *Client) SaveCategory(category Category) (*Category, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	category.ID = id.String()
+	category.CreatedAt = time.Now().UTC()
+
+	err = client.putCategory(category)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// UpdateCategory renames or moves an existing category
+func (client *Client) UpdateCategory(category Category, categoryID string) error {
+	category.ID = categoryID
+	return client.putCategory(category)
+}
+
+func (client *Client) putCategory(category Category) error {
+	av, err := dynamodbattribute.MarshalMap(category)
+	if err != nil {
+		return fmt.Errorf("error marshalling category: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(CategoryTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving category: %w", err)
+	}
+
+	return nil
+}
+
+// GetCategory fetches a category by it's ID
+func (client *Client) GetCategory(id string) (*Category, error) {
+	var category *Category
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(CategoryTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find category with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &category)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// ListCategories returns the whole taxonomy, by name
+func (client *Client) ListCategories() ([]Category, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(CategoryTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []Category{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &categories)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+// DeleteCategory deletes a category given it's ID and takes it off every
+// recipe in it
+func (client *Client) DeleteCategory(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(CategoryTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	if err != nil {
+		return err
+	}
+
+	recipes, err := client.ListAllRecipes()
+	if err != nil {
+		return err
+	}
+	for _, recipe := range recipes {
+		if !recipe.InCategory(map[string]bool{id: true}) {
+			continue
+		}
+
+		_, err = client.updateRecipeList(recipe.ID, "categoryIds", func(recipe Recipe) []string {
+			categoryIDs := []string{}
+			for _, categoryID := range recipe.CategoryIDs {
+				if categoryID != id {
+					categoryIDs = append(categoryIDs, categoryID)
+				}
+			}
+			return categoryIDs
+		})
+		if errors.Is(err, ErrRecipeNotFound) {
+			// deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
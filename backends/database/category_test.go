@@ -0,0 +1,64 @@
+package database
+
+import "testing"
+
+func TestCategoryTaxonomy(t *testing.T) {
+	categories := []Category{
+		{ID: "course", Name: "Course"},
+		{ID: "dessert", Name: "Dessert", ParentID: "course"},
+		{ID: "cookies", Name: "Cookies", ParentID: "dessert"},
+		{ID: "cakes", Name: "Cakes", ParentID: "dessert"},
+		{ID: "cuisine", Name: "Cuisine"},
+	}
+
+	tree := CategoryTree(categories)
+	if len(tree) != 2 || tree[0].ID != "course" || tree[1].ID != "cuisine" {
+		t.Fatalf("Wrong top level categories: %+v", tree)
+	}
+	dessert := tree[0].Children[0]
+	if dessert.ID != "dessert" || len(dessert.Children) != 2 || dessert.Children[0].ID != "cakes" {
+		t.Errorf("Wrong dessert categories: %+v", dessert)
+	}
+
+	path := CategoryPath(categories, "cookies")
+	if len(path) != 3 || path[0].ID != "course" || path[2].ID != "cookies" {
+		t.Errorf("Wrong path to cookies: %+v", path)
+	}
+
+	descendants := CategoryDescendants(categories, "dessert")
+	if len(descendants) != 3 || !descendants["cookies"] || descendants["course"] {
+		t.Errorf("Wrong descendants of dessert: %v", descendants)
+	}
+	if !(Recipe{CategoryIDs: []string{"cookies"}}).InCategory(CategoryDescendants(categories, "course")) {
+		t.Error("Expected cookies to be a course")
+	}
+}
+
+func TestDeleteCategory(t *testing.T) {
+	mockClient := newMockClient()
+	category, _ := mockClient.SaveCategory(Category{Name: "Cookies"})
+	recipe, _ := mockClient.SaveRecipe(Recipe{Name: "Snickerdoodles", CategoryIDs: []string{category.ID, "other"}})
+
+	err := mockClient.DeleteCategory(category.ID)
+	if err != nil {
+		t.Fatalf("Error deleting category: %s", err.Error())
+	}
+
+	updated, _ := mockClient.GetRecipe(recipe.ID)
+	if len(updated.CategoryIDs) != 1 || updated.CategoryIDs[0] != "other" {
+		t.Errorf("Expected the category to be taken off the recipe, got %v", updated.CategoryIDs)
+	}
+
+	// it's a new revision, so edits from before it have to merge with it
+	if updated.Revision != recipe.Revision+1 {
+		t.Errorf("Expected revision %d, got %d", recipe.Revision+1, updated.Revision)
+	}
+	stored, err := mockClient.GetRecipeRevision(recipe.ID, updated.Revision)
+	if err != nil || len(stored.CategoryIDs) != 1 {
+		t.Errorf("Expected the revision to be kept, got %+v, %v", stored, err)
+	}
+	err = mockClient.ReplaceRecipe(*recipe, recipe.ID, recipe.Revision)
+	if err != ErrRecipeChanged {
+		t.Errorf("Expected a stale edit to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// Collection is a user's cookbook, an ordered list of recipes like
+// "Thanksgiving" or "Weeknight". A recipe can be in any number of them.
+type Collection struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userId"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// RecipeIDs are in the order the recipes are shown
+	RecipeIDs []string `json:"recipeIds"`
+	// Shared collections can be seen and added to by the owner's household
+	Shared    bool      `json:"shared"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Version goes up each time the collection is saved, so changes made
+	// from the same read can't overwrite each other
+	Version int `json:"version"`
+}
+
+// VisibleTo reports whether the user can see and change the collection,
+// given the members of the user's household
+func (collection Collection) VisibleTo(userID string, memberIDs []string) bool {
+	if collection.UserID == userID {
+		return true
+	}
+	if !collection.Shared {
+		return false
+	}
+	for _, memberID := range memberIDs {
+		if memberID == collection.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether the recipe is in the collection
+func (collection Collection) Contains(recipeID string) bool {
+	return collection.position(recipeID) >= 0
+}
+
+// AddRecipe puts a recipe at a position in the collection, moving it there
+// if it's already in it. Positions past the end add it at the end.
+func (collection *Collection) AddRecipe(recipeID string, position int) {
+	collection.RemoveRecipe(recipeID)
+
+	if position < 0 || position > len(collection.RecipeIDs) {
+		position = len(collection.RecipeIDs)
+	}
+	recipeIDs := append([]string{}, collection.RecipeIDs[:position]...)
+	recipeIDs = append(recipeIDs, recipeID)
+	collection.RecipeIDs = append(recipeIDs, collection.RecipeIDs[position:]...)
+}
+
+// RemoveRecipe takes a recipe out of the collection, reporting whether it
+// was in it
+func (collection *Collection) RemoveRecipe(recipeID string) bool {
+	i := collection.position(recipeID)
+	if i < 0 {
+		return false
+	}
+	collection.RecipeIDs = append(collection.RecipeIDs[:i:i], collection.RecipeIDs[i+1:]...)
+	return true
+}
+
+// SaveCollection saves a new collection
+func (client *Client) SaveCollection(collection Collection) (*Collection, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	collection.ID = id.String()
+	collection.CreatedAt = time.Now().UTC()
+	collection.UpdatedAt = collection.CreatedAt
+	collection.Version = 0
+
+	err = client.putCollection(&collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+// UpdateCollection changes the latest version of a collection and saves it,
+// trying again with the newer version if someone else saved it in the
+// meantime. Change reports whether it changed anything, when it didn't
+// nothing is saved.
+func (client *Client) UpdateCollection(collectionID string, change func(*Collection) bool) (*Collection, error) {
+	for attempt := 0; attempt < collectionAttempts; attempt++ {
+		collection, err := client.GetCollection(collectionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !change(collection) {
+			return collection, nil
+		}
+		collection.ID = collectionID
+		collection.UpdatedAt = time.Now().UTC()
+		err = client.putCollection(collection)
+		if err == errCollectionChanged {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return collection, nil
+	}
+	return nil, fmt.Errorf("error saving collection: %w", errCollectionChanged)
+}
+
+// GetCollection fetches a collection by it's ID
+func (client *Client) GetCollection(id string) (*Collection, error) {
+	var collection *Collection
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(CollectionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find collection with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// ListCollections returns the user's collections and the ones their
+// household shares with them, sorted by name
+func (client *Client) ListCollections(userID string, memberIDs []string) ([]Collection, error) {
+	return client.listCollections(func(collection Collection) bool {
+		return collection.VisibleTo(userID, memberIDs)
+	})
+}
+
+// ListRecipeCollections returns the collections the user can see that the
+// recipe is in
+func (client *Client) ListRecipeCollections(recipeID string, userID string, memberIDs []string) ([]Collection, error) {
+	return client.listCollections(func(collection Collection) bool {
+		return collection.Contains(recipeID) && collection.VisibleTo(userID, memberIDs)
+	})
+}
+
+// DeleteCollection deletes a collection given it's ID, the recipes in it
+// are left alone
+func (client *Client) DeleteCollection(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(CollectionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
+
+// RemoveRecipeFromCollections takes a recipe out of every collection it's
+// in, for when the recipe itself is deleted
+func (client *Client) RemoveRecipeFromCollections(recipeID string) error {
+	collections, err := client.listCollections(func(collection Collection) bool {
+		return collection.Contains(recipeID)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range collections {
+		_, err = client.UpdateCollection(collection.ID, func(collection *Collection) bool {
+			return collection.RemoveRecipe(recipeID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// - MARK: Helper Functions
+
+// errCollectionChanged is returned by putCollection when someone else saved
+// the collection first
+var errCollectionChanged = errors.New("collection has changed")
+
+// how many times a collection change is tried against the latest collection
+const collectionAttempts = 5
+
+// saves the collection, as long as it's the version that was read
+func (client *Client) putCollection(collection *Collection) error {
+	if collection.RecipeIDs == nil {
+		collection.RecipeIDs = []string{}
+	}
+	expected := collection.Version
+	collection.Version++
+
+	av, err := dynamodbattribute.MarshalMap(collection)
+	if err != nil {
+		return fmt.Errorf("error marshalling collection: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:                     av,
+		TableName:                aws.String(CollectionTable),
+		ConditionExpression:      aws.String("#version = :version"),
+		ExpressionAttributeNames: map[string]*string{"#version": aws.String("version")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":version": {N: aws.String(strconv.Itoa(expected))},
+		},
+	}
+	// new collections, and ones saved before they had versions
+	if expected == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(#version)")
+		input.ExpressionAttributeValues = nil
+	}
+
+	_, err = client.dbService.PutItem(input)
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return errCollectionChanged
+	}
+	if err != nil {
+		return fmt.Errorf("error saving collection: %w", err)
+	}
+
+	return nil
+}
+
+func (collection Collection) position(recipeID string) int {
+	for i, id := range collection.RecipeIDs {
+		if id == recipeID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (client *Client) listCollections(keep func(Collection) bool) ([]Collection, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(CollectionTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []Collection{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &collections)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []Collection{}
+	for _, collection := range collections {
+		if keep(collection) {
+			kept = append(kept, collection)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].Name != kept[j].Name {
+			return kept[i].Name < kept[j].Name
+		}
+		return kept[i].ID < kept[j].ID
+	})
+	return kept, nil
+}
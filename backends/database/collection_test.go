@@ -0,0 +1,98 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectionOrder(t *testing.T) {
+	collection := Collection{RecipeIDs: []string{"a", "b", "c"}}
+
+	collection.AddRecipe("d", 1)
+	collection.AddRecipe("c", 0)
+	collection.AddRecipe("e", 99)
+	expected := []string{"c", "a", "d", "b", "e"}
+	if !reflect.DeepEqual(collection.RecipeIDs, expected) {
+		t.Errorf("Expected %v, got %v", expected, collection.RecipeIDs)
+	}
+
+	if !collection.RemoveRecipe("d") || collection.RemoveRecipe("d") || collection.Contains("d") {
+		t.Errorf("Expected d to be removed once, got %v", collection.RecipeIDs)
+	}
+}
+
+func TestCollectionVisibility(t *testing.T) {
+	collection := Collection{UserID: "owner"}
+	household := []string{"owner", "member"}
+
+	if !collection.VisibleTo("owner", nil) {
+		t.Error("Expected the owner to see their collection")
+	}
+	if collection.VisibleTo("member", household) {
+		t.Error("Expected unshared collections to be private")
+	}
+
+	collection.Shared = true
+	if !collection.VisibleTo("member", household) {
+		t.Error("Expected shared collections to be visible to the household")
+	}
+	if collection.VisibleTo("stranger", []string{"stranger"}) {
+		t.Error("Expected shared collections to stay in the household")
+	}
+}
+
+func TestRemoveRecipeFromCollections(t *testing.T) {
+	mockClient := newMockClient()
+	weeknight, _ := mockClient.SaveCollection(Collection{UserID: "user", Name: "Weeknight", RecipeIDs: []string{"tacos", "soup"}})
+	holidays, _ := mockClient.SaveCollection(Collection{UserID: "other", Name: "Holidays", Shared: true, RecipeIDs: []string{"roast", "tacos"}})
+
+	found, err := mockClient.ListRecipeCollections("tacos", "user", []string{"user", "other"})
+	if err != nil || len(found) != 2 || found[0].ID != holidays.ID || found[1].ID != weeknight.ID {
+		t.Fatalf("Expected both collections by name, got %+v, %v", found, err)
+	}
+
+	err = mockClient.RemoveRecipeFromCollections("tacos")
+	if err != nil {
+		t.Fatalf("Error removing recipe: %s", err.Error())
+	}
+
+	updated, _ := mockClient.GetCollection(weeknight.ID)
+	if !reflect.DeepEqual(updated.RecipeIDs, []string{"soup"}) {
+		t.Errorf("Expected only soup left, got %v", updated.RecipeIDs)
+	}
+	found, _ = mockClient.ListRecipeCollections("tacos", "user", []string{"user", "other"})
+	if len(found) != 0 {
+		t.Errorf("Expected no collections with tacos, got %+v", found)
+	}
+}
+
+func TestCollectionChangesFromStaleReads(t *testing.T) {
+	mockClient := newMockClient()
+	collection, _ := mockClient.SaveCollection(Collection{UserID: "user", Name: "Weeknight", RecipeIDs: []string{"soup"}})
+
+	// someone else adds tacos after the collection is read for the curry
+	interrupted := false
+	updated, err := mockClient.UpdateCollection(collection.ID, func(collection *Collection) bool {
+		if !interrupted {
+			interrupted = true
+			_, err := mockClient.UpdateCollection(collection.ID, func(collection *Collection) bool {
+				collection.AddRecipe("tacos", -1)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("Error adding recipe: %s", err.Error())
+			}
+		}
+		collection.AddRecipe("curry", -1)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Error adding recipe: %s", err.Error())
+	}
+
+	expected := []string{"soup", "tacos", "curry"}
+	saved, _ := mockClient.GetCollection(collection.ID)
+	if !reflect.DeepEqual(updated.RecipeIDs, expected) || !reflect.DeepEqual(saved.RecipeIDs, expected) {
+		t.Errorf("Expected %v, got %v and %v", expected, updated.RecipeIDs, saved.RecipeIDs)
+	}
+}
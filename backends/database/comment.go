@@ -0,0 +1,260 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// Comment is something said about a recipe. Replies have a ParentID, and
+// Private comments are notes only their author can see.
+type Comment struct {
+	ID       string `json:"id"`
+	RecipeID string `json:"recipeId"`
+	UserID   string `json:"userId"`
+	ParentID string `json:"parentId,omitempty"`
+	Text     string `json:"text"`
+	Private  bool   `json:"private,omitempty"`
+	// StepIndex optionally pins the comment to one of Recipe.Steps
+	StepIndex *int `json:"stepIndex,omitempty"`
+	// Mentions are the IDs of household members the comment mentions
+	Mentions []string `json:"mentions,omitempty"`
+	// Deleted comments keep their place in the thread for their replies
+	Deleted   bool      `json:"deleted,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CommentThread is a comment and the replies to it and to them, oldest first
+type CommentThread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// VisibleTo reports whether the user can see the comment. Anonymous callers,
+// with an empty userID, can't see anyone's private notes.
+func (comment Comment) VisibleTo(userID string) bool {
+	return !comment.Private || (userID != "" && comment.UserID == userID)
+}
+
+// ThreadComments groups comments into threads, oldest first, leaving out the
+// ones the user can't see. Replies to comments that are gone start their own
+// thread.
+func ThreadComments(comments []Comment, userID string) []CommentThread {
+	sorted := append([]Comment{}, comments...)
+	sortComments(sorted)
+
+	ids := map[string]bool{}
+	for _, comment := range sorted {
+		ids[comment.ID] = true
+	}
+
+	threads := []CommentThread{}
+	index := map[string]int{}
+	for _, comment := range sorted {
+		if !comment.VisibleTo(userID) {
+			continue
+		}
+		if comment.ParentID != "" && ids[comment.ParentID] {
+			// replies to replies stay in the same thread
+			if i, ok := index[comment.ParentID]; ok {
+				threads[i].Replies = append(threads[i].Replies, comment)
+				index[comment.ID] = i
+			}
+			continue
+		}
+		index[comment.ID] = len(threads)
+		threads = append(threads, CommentThread{Comment: comment, Replies: []Comment{}})
+	}
+
+	// deleted comments are only kept for their replies
+	kept := []CommentThread{}
+	for _, thread := range threads {
+		if !thread.Deleted || len(thread.Replies) > 0 {
+			kept = append(kept, thread)
+		}
+	}
+	return kept
+}
+
+// ErrInvalidCursor is returned when a page is asked for after something that
+// isn't a cursor PageThreads handed out
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageThreads returns up to limit threads after the cursor after, and the
+// cursor to ask for the next page after. Next is empty on the last page.
+// Cursors are where a thread sits in the order, so they keep working when
+// the thread they came from is deleted.
+func PageThreads(threads []CommentThread, after string, limit int) (page []CommentThread, next string, err error) {
+	start := 0
+	if after != "" {
+		parts := strings.SplitN(after, ".", 2)
+		nanoseconds, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			return nil, "", ErrInvalidCursor
+		}
+		createdAt, id := time.Unix(0, nanoseconds), parts[1]
+
+		start = sort.Search(len(threads), func(i int) bool {
+			if !threads[i].CreatedAt.Equal(createdAt) {
+				return threads[i].CreatedAt.After(createdAt)
+			}
+			return threads[i].ID > id
+		})
+	}
+
+	end := start + limit
+	if end >= len(threads) {
+		return threads[start:], "", nil
+	}
+	last := threads[end-1]
+	return threads[start:end], strconv.FormatInt(last.CreatedAt.UnixNano(), 10) + "." + last.ID, nil
+}
+
+// SaveComment saves a new comment
+func (client *Client) SaveComment(comment Comment) (*Comment, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	comment.ID = id.String()
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+
+	err = client.putComment(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// UpdateComment replaces an existing comment
+func (client *Client) UpdateComment(comment Comment, commentID string) error {
+	comment.ID = commentID
+	comment.UpdatedAt = time.Now().UTC()
+	return client.putComment(comment)
+}
+
+func (client *Client) putComment(comment Comment) error {
+	av, err := dynamodbattribute.MarshalMap(comment)
+	if err != nil {
+		return fmt.Errorf("error marshalling comment: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(CommentTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving comment: %w", err)
+	}
+
+	return nil
+}
+
+// GetComment fetches a comment by it's ID
+func (client *Client) GetComment(id string) (*Comment, error) {
+	var comment *Comment
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(CommentTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find comment with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// ListComments returns every comment on a recipe, oldest first
+func (client *Client) ListComments(recipeID string) ([]Comment, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(CommentTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []Comment{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &comments)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeComments := []Comment{}
+	for _, comment := range comments {
+		if comment.RecipeID == recipeID {
+			recipeComments = append(recipeComments, comment)
+		}
+	}
+
+	sortComments(recipeComments)
+	return recipeComments, nil
+}
+
+// DeleteComment deletes a comment given it's ID
+func (client *Client) DeleteComment(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(CommentTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
+
+// DeleteRecipeComments deletes every comment on a recipe, for when the
+// recipe itself is deleted
+func (client *Client) DeleteRecipeComments(recipeID string) error {
+	comments, err := client.ListComments(recipeID)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		err = client.DeleteComment(comment.ID)
+		if err != nil {
+			return fmt.Errorf("error deleting comment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// - MARK: Helper Functions
+
+func sortComments(comments []Comment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThreadComments(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	comments := []Comment{
+		{ID: "reply", ParentID: "question", UserID: "gran", Text: "yes", CreatedAt: at(2)},
+		{ID: "question", UserID: "kid", Text: "can I use less sugar?", CreatedAt: at(1)},
+		{ID: "note", UserID: "gran", Text: "used less sugar, great", Private: true, CreatedAt: at(3)},
+		{ID: "nested", ParentID: "reply", UserID: "kid", Text: "thanks", CreatedAt: at(4)},
+		{ID: "gone", UserID: "kid", Deleted: true, CreatedAt: at(5)},
+		{ID: "last", UserID: "kid", Text: "made it again", CreatedAt: at(6)},
+	}
+
+	threads := ThreadComments(comments, "gran")
+	if len(threads) != 3 || threads[0].ID != "question" || threads[1].ID != "note" || threads[2].ID != "last" {
+		t.Fatalf("Wrong threads: %+v", threads)
+	}
+	if len(threads[0].Replies) != 2 || threads[0].Replies[1].ID != "nested" {
+		t.Errorf("Expected both replies in the first thread, got %+v", threads[0].Replies)
+	}
+
+	if threads := ThreadComments(comments, "kid"); len(threads) != 2 {
+		t.Errorf("Expected gran's note to be hidden from others, got %+v", threads)
+	}
+
+	page, next, err := PageThreads(threads, "", 2)
+	if err != nil || len(page) != 2 || page[1].ID != "note" {
+		t.Errorf("Wrong first page: %d threads, %v", len(page), err)
+	}
+	// the cursor still works once the thread it came from is gone
+	page, next, err = PageThreads([]CommentThread{threads[0], threads[2]}, next, 2)
+	if err != nil || len(page) != 1 || page[0].ID != "last" || next != "" {
+		t.Errorf("Wrong last page: %+v, next %q, %v", page, next, err)
+	}
+
+	if _, _, err = PageThreads(threads, "note", 2); err != ErrInvalidCursor {
+		t.Errorf("Expected a thread ID not to be a cursor, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// ErrRecipeCycle is returned when a recipe ends up being one of its own
+// ingredients, through its components or theirs
+var ErrRecipeCycle = errors.New("recipe uses itself as an ingredient")
+
+// Component makes one of a recipe's ingredients another recipe, like a pie
+// crust or a frosting
+type Component struct {
+	RecipeID string `json:"recipeId"`
+	// Batches is how many times the component recipe is made, like 0.5 for
+	// half of it. Zero counts as one.
+	Batches float64 `json:"batches,omitempty"`
+}
+
+// RecipeLookup fetches a recipe by it's ID
+type RecipeLookup func(id string) (*Recipe, error)
+
+// Flatten replaces the recipe's component ingredients with the ingredients
+// of the recipes they refer to, all the way down, scaled to how much of
+// each is made. The same ingredient from different recipes is added up where
+// the amounts can be. Components whose recipe can't be found are kept as
+// plain ingredients. ErrRecipeCycle is returned if the recipe turns out to
+// be one of its own ingredients.
+func (recipe Recipe) Flatten(lookup RecipeLookup) (Recipe, error) {
+	flattener := flattener{ingredients: map[string]string{}, keys: map[string]string{}, lookup: lookup}
+	err := flattener.add(recipe, 1, map[string]bool{recipe.ID: true})
+	if err != nil {
+		return recipe, err
+	}
+
+	recipe.Ingredients = flattener.ingredients
+	recipe.Components = nil
+	return recipe, nil
+}
+
+// batches is how many times the component recipe is made
+func (component Component) batches() float64 {
+	if component.Batches <= 0 {
+		return 1
+	}
+	return component.Batches
+}
+
+// - MARK: Helper Functions
+
+type flattener struct {
+	ingredients map[string]string
+	// keys maps normalized names to the name used in ingredients
+	keys   map[string]string
+	lookup RecipeLookup
+}
+
+// adds the recipe's ingredients made scale times, then its components', path
+// holding the IDs of the recipes it's a component of
+func (flattener *flattener) add(recipe Recipe, scale float64, path map[string]bool) error {
+	subs, batches := []*Recipe{}, []float64{}
+	for _, name := range ingredientNames(recipe) {
+		if component, ok := recipe.Components[name]; ok && component.RecipeID != "" {
+			if path[component.RecipeID] {
+				return ErrRecipeCycle
+			}
+			if sub, err := flattener.lookup(component.RecipeID); err == nil {
+				subs, batches = append(subs, sub), append(batches, component.batches())
+				continue
+			}
+		}
+
+		flattener.addIngredient(name, recipe.Ingredients[name], scale, recipe.Name)
+	}
+
+	for i, sub := range subs {
+		path[sub.ID] = true
+		err := flattener.add(*sub, scale*batches[i], path)
+		delete(path, sub.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (flattener *flattener) addIngredient(name string, amount string, scale float64, recipeName string) {
+	quantity, parsed := ingredient.Parse(amount)
+	if parsed && scale != 1 {
+		quantity = quantity.Scale(scale)
+		amount = quantity.String()
+	}
+
+	key := ingredient.Normalize(name)
+	existingName, ok := flattener.keys[key]
+	if !ok {
+		flattener.keys[key] = name
+		flattener.ingredients[name] = amount
+		return
+	}
+
+	if existing, ok := ingredient.Parse(flattener.ingredients[existingName]); ok && parsed {
+		if sum, ok := ingredient.Add(existing, quantity); ok {
+			flattener.ingredients[existingName] = sum.String()
+			return
+		}
+	}
+
+	// amounts that can't be added are kept apart, named for their recipe
+	name = strings.TrimSpace(name) + " (" + recipeName + ")"
+	if other, ok := flattener.ingredients[name]; ok {
+		amount = other + " + " + amount
+	}
+	flattener.ingredients[name] = amount
+}
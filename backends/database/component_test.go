@@ -0,0 +1,62 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenRecipe(t *testing.T) {
+	recipes := map[string]*Recipe{
+		"crust": {
+			ID:          "crust",
+			Name:        "Pie Crust",
+			Ingredients: map[string]string{"flour": "1 1/4 cups", "butter": "1/2 cup", "salt": "a pinch"},
+		},
+	}
+	lookup := func(id string) (*Recipe, error) {
+		if recipe, ok := recipes[id]; ok {
+			return recipe, nil
+		}
+		return nil, errors.New("Could not find recipe with id: " + id)
+	}
+
+	pie := Recipe{
+		ID:          "pie",
+		Name:        "Apple Pie",
+		Ingredients: map[string]string{"pie crust": "2 crusts", "apples": "6", "butter": "2 tbsp", "salt": "1/4 tsp", "filling": "1 jar"},
+		Components: map[string]Component{
+			"pie crust": {RecipeID: "crust", Batches: 2},
+			"filling":   {RecipeID: "deleted"},
+		},
+	}
+
+	flat, err := pie.Flatten(lookup)
+	if err != nil {
+		t.Fatalf("Error flattening recipe: %s", err.Error())
+	}
+
+	expected := map[string]string{
+		"apples":           "6",
+		"butter":           "18 tbsp",
+		"filling":          "1 jar",
+		"flour":            "2 1/2 cups",
+		"salt":             "1/4 tsp",
+		"salt (Pie Crust)": "a pinch",
+	}
+	if !reflect.DeepEqual(flat.Ingredients, expected) {
+		t.Errorf("Expected %v, got %v", expected, flat.Ingredients)
+	}
+	if flat.Components != nil || len(pie.Components) != 2 {
+		t.Error("Expected the flattened recipe to have no components, and the original to keep them")
+	}
+
+	// the crust can't use the pie
+	crust := *recipes["crust"]
+	crust.Ingredients = map[string]string{"apple pie": "1"}
+	crust.Components = map[string]Component{"apple pie": {RecipeID: "pie"}}
+	recipes["pie"] = &pie
+	if _, err := crust.Flatten(lookup); err != ErrRecipeCycle {
+		t.Errorf("Expected a cycle, got %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// CookLogEntry records a user making a recipe
+type CookLogEntry struct {
+	ID       string `json:"id"`
+	UserID   string `json:"userId"`
+	RecipeID string `json:"recipeId"`
+	// RecipeName is copied from the recipe so the log still makes sense if
+	// the recipe is deleted
+	RecipeName string `json:"recipeName"`
+	// CookedOn is formatted with DateFormat
+	CookedOn string `json:"cookedOn"`
+	Servings int    `json:"servings,omitempty"`
+	// Rating is how it turned out this time, from 1 to MaxRating
+	Rating int    `json:"rating,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+	// Photo is managed by the photo upload endpoints
+	Photo     *CookLogPhoto `json:"photo,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// CookLogPhoto is a photo of how the recipe turned out
+type CookLogPhoto struct {
+	ContentType string    `json:"contentType"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+	// Key is where the photo is in the blob store
+	Key string `json:"-" dynamodbav:"key"`
+}
+
+// RecipeCookCount is how often a recipe was cooked, and when it last was
+type RecipeCookCount struct {
+	RecipeID   string `json:"recipeId"`
+	RecipeName string `json:"recipeName"`
+	Count      int    `json:"count"`
+	LastCooked string `json:"lastCooked"`
+}
+
+// CookCounts adds up how often each recipe in the log was cooked, most
+// cooked first, then most recently cooked
+func CookCounts(entries []CookLogEntry) []RecipeCookCount {
+	byRecipe := map[string]*RecipeCookCount{}
+	for _, entry := range entries {
+		count, ok := byRecipe[entry.RecipeID]
+		if !ok {
+			count = &RecipeCookCount{RecipeID: entry.RecipeID}
+			byRecipe[entry.RecipeID] = count
+		}
+		count.Count++
+		if entry.CookedOn >= count.LastCooked {
+			count.LastCooked = entry.CookedOn
+			count.RecipeName = entry.RecipeName
+		}
+	}
+
+	counts := []RecipeCookCount{}
+	for _, count := range byRecipe {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		if counts[i].LastCooked != counts[j].LastCooked {
+			return counts[i].LastCooked > counts[j].LastCooked
+		}
+		return counts[i].RecipeID < counts[j].RecipeID
+	})
+	return counts
+}
+
+// CookedSince keeps the entries cooked on or after the date
+func CookedSince(entries []CookLogEntry, since string) []CookLogEntry {
+	kept := []CookLogEntry{}
+	for _, entry := range entries {
+		if entry.CookedOn >= since {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// NotCookedSince returns the recipes in the log that haven't been cooked on
+// or after the date, the longest ago first
+func NotCookedSince(entries []CookLogEntry, since string) []RecipeCookCount {
+	stale := []RecipeCookCount{}
+	for _, count := range CookCounts(entries) {
+		if count.LastCooked < since {
+			stale = append(stale, count)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastCooked < stale[j].LastCooked
+	})
+	return stale
+}
+
+// SaveCookLogEntry records a recipe being cooked
+func (client *Client) SaveCookLogEntry(entry CookLogEntry) (*CookLogEntry, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	entry.ID = id.String()
+	entry.CreatedAt = time.Now().UTC()
+
+	err = client.putCookLogEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// UpdateCookLogEntry replaces an existing cook log entry
+func (client *Client) UpdateCookLogEntry(entry CookLogEntry, entryID string) error {
+	entry.ID = entryID
+	return client.putCookLogEntry(entry)
+}
+
+func (client *Client) putCookLogEntry(entry CookLogEntry) error {
+	av, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return fmt.Errorf("error marshalling cook log entry: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(CookLogTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving cook log entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetCookLogEntry fetches a cook log entry by it's ID
+func (client *Client) GetCookLogEntry(id string) (*CookLogEntry, error) {
+	var entry *CookLogEntry
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(CookLogTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find cook log entry with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ListCookLog returns the cook log of the users, most recent first
+func (client *Client) ListCookLog(userIDs []string) ([]CookLogEntry, error) {
+	return client.listCookLog(func(entry CookLogEntry) bool {
+		for _, userID := range userIDs {
+			if entry.UserID == userID {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ListRecipeCookLog returns when the users cooked a recipe, most recent
+// first
+func (client *Client) ListRecipeCookLog(recipeID string, userIDs []string) ([]CookLogEntry, error) {
+	entries, err := client.ListCookLog(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeEntries := []CookLogEntry{}
+	for _, entry := range entries {
+		if entry.RecipeID == recipeID {
+			recipeEntries = append(recipeEntries, entry)
+		}
+	}
+	return recipeEntries, nil
+}
+
+// DeleteCookLogEntry deletes a cook log entry given it's ID
+func (client *Client) DeleteCookLogEntry(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(CookLogTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
+
+// - MARK: Helper Functions
+
+func (client *Client) listCookLog(keep func(CookLogEntry) bool) ([]CookLogEntry, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(CookLogTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CookLogEntry{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []CookLogEntry{}
+	for _, entry := range entries {
+		if keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].CookedOn != kept[j].CookedOn {
+			return kept[i].CookedOn > kept[j].CookedOn
+		}
+		return kept[i].CreatedAt.After(kept[j].CreatedAt)
+	})
+	return kept, nil
+}
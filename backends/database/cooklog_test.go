@@ -0,0 +1,32 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCookLogStats(t *testing.T) {
+	entries := []CookLogEntry{
+		{RecipeID: "tacos", RecipeName: "Tacos", CookedOn: "2024-05-01"},
+		{RecipeID: "tacos", RecipeName: "Fish Tacos", CookedOn: "2024-05-08"},
+		{RecipeID: "soup", RecipeName: "Soup", CookedOn: "2024-02-01"},
+		{RecipeID: "soup", RecipeName: "Soup", CookedOn: "2023-11-01"},
+		{RecipeID: "roast", RecipeName: "Roast", CookedOn: "2023-12-25"},
+		{RecipeID: "pizza", RecipeName: "Pizza", CookedOn: "2024-05-10"},
+	}
+
+	counts := CookCounts(CookedSince(entries, "2024-01-01"))
+	expected := []RecipeCookCount{
+		{RecipeID: "tacos", RecipeName: "Fish Tacos", Count: 2, LastCooked: "2024-05-08"},
+		{RecipeID: "pizza", RecipeName: "Pizza", Count: 1, LastCooked: "2024-05-10"},
+		{RecipeID: "soup", RecipeName: "Soup", Count: 1, LastCooked: "2024-02-01"},
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Wrong cook counts this year: %+v", counts)
+	}
+
+	stale := NotCookedSince(entries, "2024-03-01")
+	if len(stale) != 2 || stale[0].RecipeID != "roast" || stale[1].RecipeID != "soup" || stale[1].Count != 2 {
+		t.Errorf("Wrong recipes not cooked lately: %+v", stale)
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"math"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// CostEstimate is what a recipe or shopping list costs from a price catalog.
+// Unpriced ingredients are left out of the total, so it's a lower bound
+// unless Unpriced is empty.
+type CostEstimate struct {
+	Total      float64    `json:"total"`
+	Servings   int        `json:"servings,omitempty"`
+	PerServing float64    `json:"perServing,omitempty"`
+	Items      []ItemCost `json:"items"`
+	Unpriced   []string   `json:"unpriced"`
+}
+
+// ItemCost is what one ingredient costs, and which price it came from
+type ItemCost struct {
+	Name    string  `json:"name"`
+	Amount  string  `json:"amount"`
+	Cost    float64 `json:"cost"`
+	PriceID string  `json:"priceId,omitempty"`
+	Store   string  `json:"store,omitempty"`
+}
+
+// EstimateRecipeCost prices the recipe's ingredients, scaled for making more
+// or fewer servings. A scale of zero counts as one.
+func EstimateRecipeCost(recipe Recipe, scale float64, prices []Price) CostEstimate {
+	if scale <= 0 {
+		scale = 1
+	}
+
+	estimate := newCostEstimate()
+	for _, name := range ingredientNames(recipe) {
+		amount := recipe.Ingredients[name]
+		if quantity, ok := ingredient.Parse(amount); ok {
+			amount = quantity.Scale(scale).String()
+		}
+		estimate.add(name, amount, prices)
+	}
+
+	if recipe.Servings > 0 {
+		estimate.Servings = int(math.Round(float64(recipe.Servings) * scale))
+	}
+	estimate.finish()
+	return estimate
+}
+
+// EstimateShoppingCost prices a shopping list's items
+func EstimateShoppingCost(items []ShoppingItem, prices []Price) CostEstimate {
+	estimate := newCostEstimate()
+	for _, item := range items {
+		estimate.add(item.Name, item.Amount, prices)
+	}
+	estimate.finish()
+	return estimate
+}
+
+// - MARK: Helper Functions
+
+func newCostEstimate() CostEstimate {
+	return CostEstimate{Items: []ItemCost{}, Unpriced: []string{}}
+}
+
+// prices an ingredient, amounts like "1 cup + 2 tbsp" a part at a time
+func (estimate *CostEstimate) add(name string, amount string, prices []Price) {
+	item := ItemCost{Name: name, Amount: amount}
+	for _, part := range strings.Split(amount, " + ") {
+		quantity, ok := ingredient.Parse(part)
+		if !ok {
+			estimate.Unpriced = append(estimate.Unpriced, name)
+			return
+		}
+
+		price, cost, ok := cheapestPrice(prices, name, quantity)
+		if !ok {
+			estimate.Unpriced = append(estimate.Unpriced, name)
+			return
+		}
+		item.Cost += cost
+		item.PriceID, item.Store = price.ID, price.Store
+	}
+
+	item.Cost = roundCents(item.Cost)
+	estimate.Total += item.Cost
+	estimate.Items = append(estimate.Items, item)
+}
+
+func (estimate *CostEstimate) finish() {
+	estimate.Total = roundCents(estimate.Total)
+	if estimate.Servings > 0 {
+		estimate.PerServing = roundCents(estimate.Total / float64(estimate.Servings))
+	}
+}
+
+// the cheapest way to buy quantity of an ingredient. Prices for exactly
+// the ingredient are preferred over ones that only match, so "butter"
+// isn't priced as "peanut butter" when butter has a price.
+func cheapestPrice(prices []Price, name string, quantity ingredient.Quantity) (Price, float64, bool) {
+	key := ingredient.Normalize(name)
+	for _, exact := range []bool{true, false} {
+		var best Price
+		bestCost, found := 0.0, false
+		for _, price := range prices {
+			if exact != (ingredient.Normalize(price.Ingredient) == key) {
+				continue
+			}
+			if !exact && !ingredient.Matches(price.Ingredient, name) {
+				continue
+			}
+
+			cost, ok := price.CostOf(quantity)
+			if ok && (!found || cost < bestCost) {
+				best, bestCost, found = price, cost, true
+			}
+		}
+		if found {
+			return best, bestCost, true
+		}
+	}
+	return Price{}, 0, false
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
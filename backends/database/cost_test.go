@@ -0,0 +1,54 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEstimateRecipeCost(t *testing.T) {
+	prices := []Price{
+		{ID: "flour", Ingredient: "all-purpose flour", Price: 4, Per: "5 lb"},
+		{ID: "butter", Ingredient: "butter", Price: 5, Per: "1 lb", Store: "Market"},
+		{ID: "costco-butter", Ingredient: "butter", Price: 16, Per: "4 lb", Store: "Costco"},
+		{ID: "peanut-butter", Ingredient: "peanut butter", Price: 1, Per: "1 lb"},
+		{ID: "eggs", Ingredient: "eggs", Price: 3.6, Per: "12"},
+	}
+	recipe := Recipe{
+		Servings: 4,
+		Ingredients: map[string]string{
+			"all-purpose flour": "2 lb",
+			"butter":            "8 oz",
+			"eggs":              "2",
+			"vanilla":           "1 tsp",
+		},
+	}
+
+	estimate := EstimateRecipeCost(recipe, 2, prices)
+
+	// 4 lb of flour, 1 lb of butter at Costco and 4 eggs
+	if estimate.Total != 3.2+4+1.2 {
+		t.Errorf("Expected a total of 8.40, got %v", estimate.Total)
+	}
+	if estimate.Servings != 8 || estimate.PerServing != 1.05 {
+		t.Errorf("Expected 8 servings at 1.05, got %d at %v", estimate.Servings, estimate.PerServing)
+	}
+	if estimate.Items[1].Name != "butter" || estimate.Items[1].Store != "Costco" {
+		t.Errorf("Expected butter to be priced at Costco, got %+v", estimate.Items[1])
+	}
+	if !reflect.DeepEqual(estimate.Unpriced, []string{"vanilla"}) {
+		t.Errorf("Expected vanilla to be unpriced, got %v", estimate.Unpriced)
+	}
+}
+
+func TestEstimateShoppingCost(t *testing.T) {
+	prices := []Price{{Ingredient: "milk", Price: 4, Per: "1 gallon"}}
+	items := []ShoppingItem{
+		{Name: "milk", Amount: "2 cups + 1 l"},
+		{Name: "milk", Amount: "2 cups"},
+	}
+
+	estimate := EstimateShoppingCost(items, prices)
+	if estimate.Items[0].Cost != 1.56 || estimate.Total != 2.06 {
+		t.Errorf("Expected 1.56 and a total of 2.06, got %+v", estimate)
+	}
+}
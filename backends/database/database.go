@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -18,8 +19,54 @@ import (
 const (
 	// RecipeTable is the table name for recipes
 	RecipeTable = "recipe"
+	// APIKeyTable is the table name for personal API keys
+	APIKeyTable = "apikey"
+	// UserTable is the table name for users
+	UserTable = "user"
+	// SessionTable is the table name for interactive login sessions
+	SessionTable = "session"
+	// LoginTable is the table name for logins that are in progress
+	LoginTable = "login"
+	// ShareTable is the table name for public recipe share links
+	ShareTable = "share"
+	// MealPlanTable is the table name for meal plan entries
+	MealPlanTable = "mealplan"
+	// CalendarFeedTable is the table name for meal plan calendar feeds
+	CalendarFeedTable = "calendarfeed"
+	// ShoppingListTable is the table name for shopping lists
+	ShoppingListTable = "shoppinglist"
+	// PantryTable is the table name for pantry items
+	PantryTable = "pantry"
+	// WebhookTable is the table name for webhooks
+	WebhookTable = "webhook"
+	// HouseholdTable is the table name for households
+	HouseholdTable = "household"
+	// PriceTable is the table name for household ingredient prices
+	PriceTable = "price"
+	// ReviewTable is the table name for recipe reviews
+	ReviewTable = "review"
+	// CommentTable is the table name for recipe comments and notes
+	CommentTable = "comment"
+	// CookLogTable is the table name for cook log entries
+	CookLogTable = "cooklog"
+	// CollectionTable is the table name for recipe collections
+	CollectionTable = "collection"
+	// RecipeTagTable is the table name for the recipe tag index
+	RecipeTagTable = "recipetag"
+	// CategoryTable is the table name for the category taxonomy
+	CategoryTable = "category"
+	// RecipeRevisionTable is the table name for past revisions of recipes
+	RecipeRevisionTable = "reciperevision"
 )
 
+// ErrImageReplaced is returned when a recipe's image changed while it was
+// being processed
+var ErrImageReplaced = errors.New("recipe image was replaced")
+
+// ErrRecipeNotFound is returned when there's no recipe with an ID, to tell
+// deleted recipes apart from failing to look them up
+var ErrRecipeNotFound = errors.New("Could not find recipe")
+
 type Client struct {
 	dbService dynamodbiface.DynamoDBAPI
 }
@@ -45,7 +92,47 @@ func New() *Client {
 	}
 }
 
+// NewWithService returns a client that uses the given DynamoDB service,
+// like an in-memory one for tests
+func NewWithService(dbService dynamodbiface.DynamoDBAPI) *Client {
+	return &Client{
+		dbService: dbService,
+	}
+}
+
+// EnsureTables creates every table the server needs, logging (but otherwise
+// ignoring) tables that already exist
 func (client *Client) EnsureTables() {
+	tables := []string{
+		RecipeTable,
+		APIKeyTable,
+		UserTable,
+		SessionTable,
+		LoginTable,
+		ShareTable,
+		MealPlanTable,
+		CalendarFeedTable,
+		ShoppingListTable,
+		PantryTable,
+		WebhookTable,
+		HouseholdTable,
+		PriceTable,
+		ReviewTable,
+		CommentTable,
+		CookLogTable,
+		CollectionTable,
+		CategoryTable,
+		RecipeRevisionTable,
+	}
+	for _, table := range tables {
+		client.ensureTable(table)
+	}
+	client.ensureTable(RecipeTagTable, "tag")
+}
+
+// ensureTable creates a table keyed by "id", with a global secondary index
+// named "<attribute>-index" for each of the indexed string attributes
+func (client *Client) ensureTable(table string, indexed ...string) {
 	input := &dynamodb.CreateTableInput{
 		AttributeDefinitions: []*dynamodb.AttributeDefinition{
 			{
@@ -63,12 +150,32 @@ func (client *Client) EnsureTables() {
 			ReadCapacityUnits:  aws.Int64(10),
 			WriteCapacityUnits: aws.Int64(10),
 		},
-		TableName: aws.String(RecipeTable),
+		TableName: aws.String(table),
+	}
+
+	for _, attribute := range indexed {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(attribute),
+			AttributeType: aws.String("S"),
+		})
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName: aws.String(attribute + "-index"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{
+					AttributeName: aws.String(attribute),
+					KeyType:       aws.String("HASH"),
+				},
+			},
+			Projection: &dynamodb.Projection{
+				ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+			},
+			ProvisionedThroughput: input.ProvisionedThroughput,
+		})
 	}
 
 	_, err := client.dbService.CreateTable(input)
 	if err != nil {
-		log.Default().Printf("error creating table: %v", err)
+		log.Default().Printf("error creating table %s: %v", table, err)
 	}
 }
 
@@ -82,6 +189,12 @@ func (client *Client) SaveRecipe(recipe Recipe) (*Recipe, error) {
 		return nil, fmt.Errorf("error generating UUID: %w", err)
 	}
 	recipe.ID = id.String()
+	// new recipes haven't been reviewed yet
+	recipe.RatingCount, recipe.RatingTotal, recipe.AverageRating = 0, 0, 0
+	recipe.Revision = 1
+	recipe.Tags = NormalizeTags(recipe.Tags)
+	recipe.ApplyTimings()
+	recipe.ApplyStepIngredients()
 
 	// marshal recipe
 	av, err := dynamodbattribute.MarshalMap(recipe)
@@ -101,29 +214,43 @@ func (client *Client) SaveRecipe(recipe Recipe) (*Recipe, error) {
 		return nil, fmt.Errorf("error saving recipe: %w", err)
 	}
 
-	return &recipe, nil
-}
-
-// UpdateRecipe updates an existing recipe
-func (client *Client) UpdateRecipe(recipe Recipe, recipeID string) error {
-	recipe.ID = recipeID
-	av, err := dynamodbattribute.MarshalMap(recipe)
+	err = client.indexRecipeTags(recipe.ID, nil, recipe.Tags)
 	if err != nil {
-		return fmt.Errorf("error marshalling recipe item: %w", err)
-	}
-
-	input := &dynamodb.PutItemInput{
-		Item:         av,
-		TableName:    aws.String(RecipeTable),
-		ReturnValues: aws.String("ALL_OLD"),
+		return nil, err
 	}
-
-	_, err = client.dbService.PutItem(input)
+	err = client.saveRecipeRevision(recipe)
 	if err != nil {
-		return fmt.Errorf("error updating recipe: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return &recipe, nil
+}
+
+// UpdateRecipe updates an existing recipe, whatever revision it's at, and
+// returns the revision it's saved as. The image and ratings are left as they
+// are. ErrRecipeChanged is returned if the recipe has been deleted.
+func (client *Client) UpdateRecipe(recipe Recipe, recipeID string) (int, error) {
+	for attempt := 0; attempt < recipeAttempts; attempt++ {
+		current, err := client.GetRecipe(recipeID)
+		if errors.Is(err, ErrRecipeNotFound) {
+			return 0, ErrRecipeChanged
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// saved as the revision after the one just read, so two updates
+		// can't both claim the same revision
+		err = client.ReplaceRecipe(recipe, recipeID, current.Revision)
+		if err == ErrRecipeChanged {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return current.Revision + 1, nil
+	}
+	return 0, fmt.Errorf("error updating recipe: %w", ErrRecipeChanged)
 }
 
 // ListAllRecipes returns a list of all recipes as a slice of recipe structs
@@ -132,7 +259,7 @@ func (client *Client) ListAllRecipes() ([]Recipe, error) {
 		TableName: aws.String(RecipeTable),
 	}
 
-	result, err := client.dbService.Scan(params)
+	result, err := client.scan(params)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +270,9 @@ func (client *Client) ListAllRecipes() ([]Recipe, error) {
 		return nil, err
 	}
 
+	for i := range recipes {
+		recipes[i].averageRating()
+	}
 	return recipes, nil
 }
 
@@ -163,7 +293,7 @@ func (client *Client) GetRecipe(id string) (*Recipe, error) {
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("Could not find recipe with id: " + id)
+		return nil, fmt.Errorf("%w with id: %s", ErrRecipeNotFound, id)
 	}
 
 	err = dynamodbattribute.UnmarshalMap(result.Item, &recipe)
@@ -171,9 +301,81 @@ func (client *Client) GetRecipe(id string) (*Recipe, error) {
 		return nil, err
 	}
 
+	recipe.averageRating()
 	return recipe, nil
 }
 
+// SetRecipeImage records a recipe's uploaded image, or removes it when image
+// is nil
+func (client *Client) SetRecipeImage(recipeID string, image *RecipeImage) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(RecipeTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeID),
+			},
+		},
+		ConditionExpression:      aws.String("attribute_exists(id)"),
+		ExpressionAttributeNames: map[string]*string{"#image": aws.String("image")},
+	}
+
+	if image == nil {
+		input.UpdateExpression = aws.String("REMOVE #image")
+	} else {
+		av, err := dynamodbattribute.Marshal(image)
+		if err != nil {
+			return fmt.Errorf("error marshalling recipe image: %w", err)
+		}
+		input.UpdateExpression = aws.String("SET #image = :image")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":image": av}
+	}
+
+	_, err := client.dbService.UpdateItem(input)
+	if err != nil {
+		return fmt.Errorf("error updating recipe image: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecipeImage updates a recipe's image, but only if it is still the
+// same version. ErrImageReplaced is returned if a newer image was uploaded in
+// the meantime.
+func (client *Client) ReplaceRecipeImage(recipeID string, image *RecipeImage) error {
+	av, err := dynamodbattribute.Marshal(image)
+	if err != nil {
+		return fmt.Errorf("error marshalling recipe image: %w", err)
+	}
+
+	_, err = client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(RecipeTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeID),
+			},
+		},
+		ConditionExpression: aws.String("#image.#version = :version"),
+		UpdateExpression:    aws.String("SET #image = :image"),
+		ExpressionAttributeNames: map[string]*string{
+			"#image":   aws.String("image"),
+			"#version": aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":image":   av,
+			":version": {S: aws.String(image.Version)},
+		},
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrImageReplaced
+	}
+	if err != nil {
+		return fmt.Errorf("error updating recipe image: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteRecipe deletes a recipe given it's ID
 func (client *Client) DeleteRecipe(id string) error {
 	input := &dynamodb.DeleteItemInput{
@@ -183,8 +385,40 @@ func (client *Client) DeleteRecipe(id string) error {
 				S: aws.String(id),
 			},
 		},
+		ReturnValues: aws.String("ALL_OLD"),
 	}
 
-	_, err := client.dbService.DeleteItem(input)
-	return err
+	result, err := client.dbService.DeleteItem(input)
+	if err != nil {
+		return err
+	}
+
+	var old Recipe
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &old)
+	if err != nil {
+		return err
+	}
+	return client.indexRecipeTags(id, old.Tags, nil)
+}
+
+// - MARK: Helper Functions
+
+// scan runs a scan to the end of the table, following LastEvaluatedKey so
+// items past the first page (1MB) aren't silently left out
+func (client *Client) scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	output := &dynamodb.ScanOutput{}
+	for {
+		page, err := client.dbService.Scan(input)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, page.Items...)
+
+		if len(page.LastEvaluatedKey) == 0 {
+			return output, nil
+		}
+		next := *input
+		next.ExclusiveStartKey = page.LastEvaluatedKey
+		input = &next
+	}
 }
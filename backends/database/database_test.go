@@ -4,29 +4,11 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/slichlyter12/thyme-apiserver/backends/database/dynamotest"
 )
 
-type mockDynamoDBClient struct {
-	dynamodbiface.DynamoDBAPI
-}
-
-func (m *mockDynamoDBClient) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
-	return nil, nil
-}
-
-func (m *mockDynamoDBClient) Scan(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-	return &dynamodb.ScanOutput{}, nil
-}
-
-func (m *mockDynamoDBClient) GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
-	return &dynamodb.GetItemOutput{}, nil
-}
-
 func newMockClient() *Client {
-	return &Client{
-		dbService: &mockDynamoDBClient{},
-	}
+	return NewWithService(&dynamotest.Client{})
 }
 
 func TestSaveRecipe(t *testing.T) {
@@ -56,12 +38,85 @@ func TestUpdateRecipe(t *testing.T) {
 	newRecipe := recipe
 	newRecipe.Name = "Better Butternut Sqash Soup"
 
-	err = mockClient.UpdateRecipe(newRecipe, savedRecipe.ID)
+	_, err = mockClient.UpdateRecipe(newRecipe, savedRecipe.ID)
 	if err != nil {
 		t.Errorf("Error updating recipe: %s", err.Error())
 	}
 }
 
+func TestUpdateRecipeKeepsNewerImage(t *testing.T) {
+	mockClient := newMockClient()
+	savedRecipe, err := mockClient.SaveRecipe(Recipe{Name: "Focaccia"})
+	if err != nil {
+		t.Fatalf("Error saving recipe: %s", err.Error())
+	}
+
+	// the image finishes processing after the edit read the recipe
+	read, _ := mockClient.GetRecipe(savedRecipe.ID)
+	err = mockClient.SetRecipeImage(savedRecipe.ID, &RecipeImage{Version: "1", Status: ImageReady})
+	if err != nil {
+		t.Fatalf("Error setting recipe image: %s", err.Error())
+	}
+	read.Name = "Rosemary Focaccia"
+	_, err = mockClient.UpdateRecipe(*read, read.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+
+	updated, _ := mockClient.GetRecipe(savedRecipe.ID)
+	if updated.Name != "Rosemary Focaccia" || updated.Image == nil || updated.Image.Status != ImageReady {
+		t.Errorf("Expected the edit and the image, got %+v", updated)
+	}
+
+	_, err = mockClient.UpdateRecipe(*read, "deleted")
+	if err != ErrRecipeChanged {
+		t.Errorf("Expected updating a deleted recipe to fail, got %v", err)
+	}
+}
+
+// interruptedRecipes runs interrupt before the next recipe update, like
+// someone else saving the recipe first
+type interruptedRecipes struct {
+	*dynamotest.Client
+	interrupt func()
+}
+
+func (service *interruptedRecipes) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if service.interrupt != nil && *input.TableName == RecipeTable {
+		interrupt := service.interrupt
+		service.interrupt = nil
+		interrupt()
+	}
+	return service.Client.UpdateItem(input)
+}
+
+func TestUpdateRecipeClaimsTheNextRevision(t *testing.T) {
+	service := &interruptedRecipes{Client: &dynamotest.Client{}}
+	mockClient := NewWithService(service)
+	saved, _ := mockClient.SaveRecipe(Recipe{Name: "Soup"})
+
+	// someone else's update lands between reading the recipe and saving it
+	service.interrupt = func() {
+		revision, err := mockClient.UpdateRecipe(Recipe{Name: "Stew"}, saved.ID)
+		if err != nil || revision != saved.Revision+1 {
+			t.Fatalf("Expected the other update to be the next revision, got %d and %v", revision, err)
+		}
+	}
+	revision, err := mockClient.UpdateRecipe(Recipe{Name: "Chowder"}, saved.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+
+	updated, _ := mockClient.GetRecipe(saved.ID)
+	if revision != saved.Revision+2 || updated.Revision != revision || updated.Name != "Chowder" {
+		t.Errorf("Expected chowder as revision %d, got %+v as %d", saved.Revision+2, updated, revision)
+	}
+	stew, err := mockClient.GetRecipeRevision(saved.ID, saved.Revision+1)
+	if err != nil || stew.Name != "Stew" {
+		t.Errorf("Expected the other update's revision to be kept, got %+v and %v", stew, err)
+	}
+}
+
 func TestGetRecipeById(t *testing.T) {
 	mockClient := newMockClient()
 	recipe := Recipe{
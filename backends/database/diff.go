@@ -0,0 +1,289 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// RecipeDiff is what changed between a recipe and a variant of it, like a
+// fork and its parent or two revisions of the same recipe
+type RecipeDiff struct {
+	// Fields are the other fields that changed, keyed by their JSON name
+	Fields      map[string]FieldChange `json:"fields"`
+	Ingredients IngredientDiff         `json:"ingredients"`
+	// Steps lists the steps added, removed and changed, in order
+	Steps []StepChange `json:"steps"`
+}
+
+// FieldChange is a field's value before and after
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// IngredientDiff is the ingredients the variant added or removed, and the
+// ones whose amounts changed, keyed by name
+type IngredientDiff struct {
+	Added   map[string]string       `json:"added"`
+	Removed map[string]string       `json:"removed"`
+	Changed map[string]AmountChange `json:"changed"`
+}
+
+// AmountChange is an ingredient's amount before and after
+type AmountChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+const (
+	// StepAdded steps are only in the variant
+	StepAdded = "added"
+	// StepRemoved steps are only in the original
+	StepRemoved = "removed"
+	// StepChanged steps were reworded, or their details changed
+	StepChanged = "changed"
+)
+
+// StepChange is a step that was added, removed or changed. From is its
+// index in the original and To its index in the variant.
+type StepChange struct {
+	Change string `json:"change"`
+	From   *int   `json:"from,omitempty"`
+	To     *int   `json:"to,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// recipeField is a field diffs and merges treat as a single value
+type recipeField struct {
+	name string
+	get  func(recipe Recipe) interface{}
+	// set copies the field from one recipe to another
+	set func(recipe *Recipe, from Recipe)
+}
+
+// recipeFields are the fields compared as a whole. Ingredients and steps are
+// compared one by one, and fields managed by the server aren't compared.
+var recipeFields = []recipeField{
+	{"name", func(r Recipe) interface{} { return r.Name }, func(r *Recipe, f Recipe) { r.Name = f.Name }},
+	{"author", func(r Recipe) interface{} { return r.Author }, func(r *Recipe, f Recipe) { r.Author = f.Author }},
+	{"description", func(r Recipe) interface{} { return r.Description }, func(r *Recipe, f Recipe) { r.Description = f.Description }},
+	{"cuisine", func(r Recipe) interface{} { return r.Cuisine }, func(r *Recipe, f Recipe) { r.Cuisine = f.Cuisine }},
+	{"imageName", func(r Recipe) interface{} { return r.ImageName }, func(r *Recipe, f Recipe) { r.ImageName = f.ImageName }},
+	{"servings", func(r Recipe) interface{} { return r.Servings }, func(r *Recipe, f Recipe) { r.Servings = f.Servings }},
+	{"prepTime", func(r Recipe) interface{} { return r.PrepTime }, func(r *Recipe, f Recipe) { r.PrepTime = f.PrepTime }},
+	{"cookTime", func(r Recipe) interface{} { return r.CookTime }, func(r *Recipe, f Recipe) { r.CookTime = f.CookTime }},
+	{"tags", func(r Recipe) interface{} { return NormalizeTags(r.Tags) }, func(r *Recipe, f Recipe) { r.Tags = f.Tags }},
+	{"categoryIds", func(r Recipe) interface{} { return sortedStrings(r.CategoryIDs) }, func(r *Recipe, f Recipe) { r.CategoryIDs = f.CategoryIDs }},
+	{"labelOverrides", func(r Recipe) interface{} { return r.LabelOverrides }, func(r *Recipe, f Recipe) { r.LabelOverrides = f.LabelOverrides }},
+	{"components", func(r Recipe) interface{} { return componentsOrEmpty(r.Components) }, func(r *Recipe, f Recipe) { r.Components = f.Components }},
+}
+
+// DiffRecipes works out how variant differs from original: field by field,
+// ingredient by ingredient and step by step. Ingredients are matched by name
+// ignoring case and spacing, and steps are lined up so a step inserted in
+// the middle doesn't make every later one look changed.
+func DiffRecipes(original Recipe, variant Recipe) RecipeDiff {
+	diff := RecipeDiff{
+		Fields:      map[string]FieldChange{},
+		Ingredients: diffIngredients(original.Ingredients, variant.Ingredients),
+		Steps:       diffSteps(recipeSteps(original), recipeSteps(variant)),
+	}
+
+	for _, field := range recipeFields {
+		from, to := field.get(original), field.get(variant)
+		if !reflect.DeepEqual(from, to) {
+			diff.Fields[field.name] = FieldChange{From: from, To: to}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether the recipes were the same
+func (diff RecipeDiff) Empty() bool {
+	return len(diff.Fields) == 0 && len(diff.Ingredients.Added) == 0 && len(diff.Ingredients.Removed) == 0 &&
+		len(diff.Ingredients.Changed) == 0 && len(diff.Steps) == 0
+}
+
+// - MARK: Helper Functions
+
+// recipeStep is a step's text and details together, so they stay together
+// when steps move
+type recipeStep struct {
+	Text   string     `json:"text"`
+	Detail StepDetail `json:"detail"`
+}
+
+func recipeSteps(recipe Recipe) []recipeStep {
+	steps := []recipeStep{}
+	for i, text := range recipe.Steps {
+		step := recipeStep{Text: text}
+		if i < len(recipe.StepDetails) {
+			step.Detail = recipe.StepDetails[i]
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// sets the recipe's steps and step details, leaving out trailing steps
+// without details
+func setRecipeSteps(recipe *Recipe, steps []recipeStep) {
+	recipe.Steps = []string{}
+	recipe.StepDetails = nil
+	for i, step := range steps {
+		recipe.Steps = append(recipe.Steps, step.Text)
+		if !reflect.DeepEqual(step.Detail, StepDetail{}) {
+			for len(recipe.StepDetails) < i {
+				recipe.StepDetails = append(recipe.StepDetails, StepDetail{})
+			}
+			recipe.StepDetails = append(recipe.StepDetails, step.Detail)
+		}
+	}
+}
+
+func sameStep(a recipeStep, b recipeStep) bool {
+	return strings.Join(strings.Fields(a.Text), " ") == strings.Join(strings.Fields(b.Text), " ") &&
+		reflect.DeepEqual(a.Detail, b.Detail)
+}
+
+func sameSteps(a []recipeStep, b []recipeStep) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameStep(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSteps lines b up against a by their longest common subsequence,
+// returning the index in b each step of a matches, or -1
+func matchSteps(a []recipeStep, b []recipeStep) []int {
+	// lengths[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if sameStep(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for i < len(a) {
+		switch {
+		case j < len(b) && sameStep(a[i], b[j]):
+			matches[i] = j
+			i, j = i+1, j+1
+		case j == len(b) || lengths[i+1][j] >= lengths[i][j+1]:
+			matches[i] = -1
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+func diffIngredients(original map[string]string, variant map[string]string) IngredientDiff {
+	diff := IngredientDiff{
+		Added:   map[string]string{},
+		Removed: map[string]string{},
+		Changed: map[string]AmountChange{},
+	}
+
+	originalNames := map[string]string{}
+	for name := range original {
+		originalNames[ingredient.Normalize(name)] = name
+	}
+
+	kept := map[string]bool{}
+	for name, amount := range variant {
+		originalName, ok := originalNames[ingredient.Normalize(name)]
+		if !ok {
+			diff.Added[name] = amount
+			continue
+		}
+		kept[originalName] = true
+		if strings.TrimSpace(original[originalName]) != strings.TrimSpace(amount) {
+			diff.Changed[name] = AmountChange{From: original[originalName], To: amount}
+		}
+	}
+
+	for name, amount := range original {
+		if !kept[name] {
+			diff.Removed[name] = amount
+		}
+	}
+	return diff
+}
+
+// pairs off steps removed and added in the same place as changed
+func diffSteps(original []recipeStep, variant []recipeStep) []StepChange {
+	changes := []StepChange{}
+	removed := []int{}
+	flush := func(added []int) {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				from, to := removed[k], added[k]
+				changes = append(changes, StepChange{Change: StepChanged, From: &from, To: &to, Old: original[from].Text, New: variant[to].Text})
+			case k < len(removed):
+				from := removed[k]
+				changes = append(changes, StepChange{Change: StepRemoved, From: &from, Old: original[from].Text})
+			default:
+				to := added[k]
+				changes = append(changes, StepChange{Change: StepAdded, To: &to, New: variant[to].Text})
+			}
+		}
+		removed = removed[:0]
+	}
+
+	matches := matchSteps(original, variant)
+	next := 0
+	for i, j := range matches {
+		if j < 0 {
+			removed = append(removed, i)
+			continue
+		}
+		flush(indexRange(next, j))
+		next = j + 1
+	}
+	flush(indexRange(next, len(variant)))
+	return changes
+}
+
+func indexRange(from int, to int) []int {
+	indexes := []int{}
+	for i := from; i < to; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func componentsOrEmpty(components map[string]Component) map[string]Component {
+	if components == nil {
+		return map[string]Component{}
+	}
+	return components
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
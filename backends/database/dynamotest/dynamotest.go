@@ -0,0 +1,321 @@
+// Package dynamotest provides an in-memory stand-in for DynamoDB for tests,
+// so code using the database package can run without a real table.
+package dynamotest
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Client is an in-memory stand-in for DynamoDB, keyed by table name and
+// then by the item's "id" attribute
+type Client struct {
+	dynamodbiface.DynamoDBAPI
+	// ScanPageSize, when set, splits scans into pages of that many items
+	ScanPageSize int
+
+	mutex  sync.Mutex
+	tables map[string]map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *Client) table(name *string) map[string]map[string]*dynamodb.AttributeValue {
+	if m.tables == nil {
+		m.tables = map[string]map[string]map[string]*dynamodb.AttributeValue{}
+	}
+	if m.tables[*name] == nil {
+		m.tables[*name] = map[string]map[string]*dynamodb.AttributeValue{}
+	}
+	return m.tables[*name]
+}
+
+// PutItem stores the item, checking ConditionExpression against the item
+// it replaces, and returns the old item
+func (m *Client) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old := m.table(input.TableName)[*input.Item["id"].S]
+	if input.ConditionExpression != nil && !conditionHolds(old, *input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+		return nil, conditionFailed()
+	}
+	m.table(input.TableName)[*input.Item["id"].S] = input.Item
+	return &dynamodb.PutItemOutput{Attributes: old}, nil
+}
+
+// Scan returns every item in the table, ignoring any filters. With a
+// ScanPageSize items come back in pages, in "id" order, starting after
+// ExclusiveStartKey.
+func (m *Client) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ids := []string{}
+	for id := range m.table(input.TableName) {
+		if input.ExclusiveStartKey == nil || id > *input.ExclusiveStartKey["id"].S {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	output := &dynamodb.ScanOutput{}
+	for _, id := range ids {
+		if m.ScanPageSize > 0 && len(output.Items) == m.ScanPageSize {
+			output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{
+				"id": output.Items[len(output.Items)-1]["id"],
+			}
+			break
+		}
+		output.Items = append(output.Items, m.table(input.TableName)[id])
+	}
+	return output, nil
+}
+
+// Query understands "attribute = :value" key conditions, on the table or any
+// of it's indexes
+func (m *Client) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	condition := strings.SplitN(*input.KeyConditionExpression, "=", 2)
+	attribute := strings.TrimSpace(condition[0])
+	if alias, ok := input.ExpressionAttributeNames[attribute]; ok {
+		attribute = *alias
+	}
+	value := input.ExpressionAttributeValues[strings.TrimSpace(condition[1])]
+
+	output := &dynamodb.QueryOutput{}
+	for _, item := range m.table(input.TableName) {
+		if item[attribute] != nil && aws.StringValue(item[attribute].S) == *value.S {
+			output.Items = append(output.Items, item)
+		}
+	}
+	return output, nil
+}
+
+// GetItem returns the item with the key's "id", if there is one
+func (m *Client) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return &dynamodb.GetItemOutput{
+		Item: m.table(input.TableName)[*input.Key["id"].S],
+	}, nil
+}
+
+// DeleteItem removes the item, returning it, if it passes ConditionExpression
+func (m *Client) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old := m.table(input.TableName)[*input.Key["id"].S]
+	if input.ConditionExpression != nil && !conditionHolds(old, *input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+		return nil, conditionFailed()
+	}
+	delete(m.table(input.TableName), *input.Key["id"].S)
+	return &dynamodb.DeleteItemOutput{Attributes: old}, nil
+}
+
+// UpdateItem understands simple SET, REMOVE and (numeric) ADD clauses. It
+// checks ConditionExpression against the item being updated, and returns the
+// old item for ReturnValues ALL_OLD and the new one otherwise.
+func (m *Client) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	old := m.table(input.TableName)[*input.Key["id"].S]
+	if input.ConditionExpression != nil && !conditionHolds(old, *input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+		return nil, conditionFailed()
+	}
+
+	item := map[string]*dynamodb.AttributeValue{"id": input.Key["id"]}
+	for attribute, value := range old {
+		item[attribute] = value
+	}
+
+	expression := *input.UpdateExpression
+	actions := updateActions.FindAllStringIndex(expression, -1)
+	for i, action := range actions {
+		end := len(expression)
+		if i+1 < len(actions) {
+			end = actions[i+1][0]
+		}
+
+		for _, part := range strings.Split(expression[action[1]:end], ",") {
+			switch strings.TrimSpace(expression[action[0]:action[1]]) {
+			case "SET":
+				assignment := strings.SplitN(part, "=", 2)
+				item[attributeName(assignment[0], input.ExpressionAttributeNames)] = input.ExpressionAttributeValues[strings.TrimSpace(assignment[1])]
+			case "REMOVE":
+				delete(item, attributeName(part, input.ExpressionAttributeNames))
+			case "ADD":
+				fields := strings.Fields(part)
+				attribute := attributeName(fields[0], input.ExpressionAttributeNames)
+				total, _ := strconv.ParseFloat(*input.ExpressionAttributeValues[fields[1]].N, 64)
+				if existing, ok := item[attribute]; ok {
+					value, _ := strconv.ParseFloat(*existing.N, 64)
+					total += value
+				}
+				item[attribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(total, 'f', -1, 64))}
+			}
+		}
+	}
+	m.table(input.TableName)[*input.Key["id"].S] = item
+
+	if aws.StringValue(input.ReturnValues) == "ALL_OLD" {
+		return &dynamodb.UpdateItemOutput{Attributes: old}, nil
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// TransactWriteItems understands Put, Delete and ConditionCheck items. It
+// checks every condition before writing anything, so either all of the
+// writes happen or none do.
+func (m *Client) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, item := range input.TransactItems {
+		var table, id, condition *string
+		var names map[string]*string
+		var values map[string]*dynamodb.AttributeValue
+		switch {
+		case item.Put != nil:
+			table, id, condition = item.Put.TableName, item.Put.Item["id"].S, item.Put.ConditionExpression
+			names, values = item.Put.ExpressionAttributeNames, item.Put.ExpressionAttributeValues
+		case item.Delete != nil:
+			table, id, condition = item.Delete.TableName, item.Delete.Key["id"].S, item.Delete.ConditionExpression
+			names, values = item.Delete.ExpressionAttributeNames, item.Delete.ExpressionAttributeValues
+		case item.ConditionCheck != nil:
+			table, id, condition = item.ConditionCheck.TableName, item.ConditionCheck.Key["id"].S, item.ConditionCheck.ConditionExpression
+			names, values = item.ConditionCheck.ExpressionAttributeNames, item.ConditionCheck.ExpressionAttributeValues
+		}
+		if condition != nil && !conditionHolds(m.table(table)[*id], *condition, names, values) {
+			return nil, awserr.New(dynamodb.ErrCodeTransactionCanceledException, "Transaction cancelled, please refer cancellation reasons for specific reasons [ConditionalCheckFailed]", nil)
+		}
+	}
+
+	for _, item := range input.TransactItems {
+		switch {
+		case item.Put != nil:
+			m.table(item.Put.TableName)[*item.Put.Item["id"].S] = item.Put.Item
+		case item.Delete != nil:
+			delete(m.table(item.Delete.TableName), *item.Delete.Key["id"].S)
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+var updateActions = regexp.MustCompile(`(SET|REMOVE|ADD)\s`)
+
+func conditionFailed() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func attributeName(name string, names map[string]*string) string {
+	name = strings.TrimSpace(name)
+	if alias, ok := names[name]; ok {
+		return *alias
+	}
+	return name
+}
+
+// conditionHolds understands conditions made of attribute_exists(a),
+// attribute_not_exists(a), a = :value and a <> :value, joined by AND and OR
+// and grouped with brackets. Attributes can be paths into maps, "a.b".
+func conditionHolds(item map[string]*dynamodb.AttributeValue, condition string, names map[string]*string, values map[string]*dynamodb.AttributeValue) bool {
+	condition = strings.TrimSpace(condition)
+	for strings.HasPrefix(condition, "(") && closingBracket(condition) == len(condition)-1 {
+		condition = strings.TrimSpace(condition[1 : len(condition)-1])
+	}
+
+	if clauses := splitCondition(condition, " OR "); len(clauses) > 1 {
+		for _, clause := range clauses {
+			if conditionHolds(item, clause, names, values) {
+				return true
+			}
+		}
+		return false
+	}
+	if clauses := splitCondition(condition, " AND "); len(clauses) > 1 {
+		for _, clause := range clauses {
+			if !conditionHolds(item, clause, names, values) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if match := conditionFunctions.FindStringSubmatch(condition); match != nil {
+		return (attributePath(item, match[2], names) != nil) == (match[1] == "attribute_exists")
+	}
+
+	operator := "="
+	if strings.Contains(condition, "<>") {
+		operator = "<>"
+	}
+	parts := strings.SplitN(condition, operator, 2)
+	existing, value := attributePath(item, parts[0], names), values[strings.TrimSpace(parts[1])]
+	equal := existing != nil && aws.StringValue(existing.S) == aws.StringValue(value.S) && aws.StringValue(existing.N) == aws.StringValue(value.N)
+	return equal == (operator == "=")
+}
+
+var conditionFunctions = regexp.MustCompile(`^(attribute_exists|attribute_not_exists)\(([\w#.]+)\)$`)
+
+// the index of the bracket closing the one condition starts with
+func closingBracket(condition string) int {
+	depth := 0
+	for i, r := range condition {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splits condition on separator where it isn't inside brackets
+func splitCondition(condition string, separator string) []string {
+	clauses := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(condition); i++ {
+		switch condition[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(condition[i:], separator) {
+			clauses = append(clauses, condition[start:i])
+			start = i + len(separator)
+		}
+	}
+	return append(clauses, condition[start:])
+}
+
+func attributePath(item map[string]*dynamodb.AttributeValue, path string, names map[string]*string) *dynamodb.AttributeValue {
+	var value *dynamodb.AttributeValue
+	for _, part := range strings.Split(strings.TrimSpace(path), ".") {
+		if item == nil {
+			return nil
+		}
+		value = item[attributeName(part, names)]
+		if value == nil {
+			return nil
+		}
+		item = value.M
+	}
+	return value
+}
@@ -0,0 +1,58 @@
+package database
+
+// Fork copies a recipe for the owner to adapt, remembering where it came
+// from. Uploaded images belong to the original so they aren't copied.
+func (recipe Recipe) Fork(ownerID string) Recipe {
+	fork := recipe
+	fork.ID = ""
+	fork.OwnerID = ownerID
+	fork.ParentID, fork.ParentRevision = recipe.ID, recipe.Revision
+	fork.Image = nil
+	fork.RatingCount, fork.RatingTotal, fork.AverageRating = 0, 0, 0
+	// the parent's owner vouched for it's labels, not whoever forked it
+	fork.LabelOverrides = nil
+
+	fork.Ingredients = map[string]string{}
+	for name, amount := range recipe.Ingredients {
+		fork.Ingredients[name] = amount
+	}
+	fork.Components = nil
+	for name, component := range recipe.Components {
+		if fork.Components == nil {
+			fork.Components = map[string]Component{}
+		}
+		fork.Components[name] = component
+	}
+	fork.Steps = append([]string{}, recipe.Steps...)
+	fork.Tags = append([]string{}, recipe.Tags...)
+	fork.CategoryIDs = append([]string{}, recipe.CategoryIDs...)
+
+	fork.StepDetails = nil
+	for _, detail := range recipe.StepDetails {
+		copied := StepDetail{Body: detail.Body, IngredientsSet: detail.IngredientsSet}
+		copied.Ingredients = append(copied.Ingredients, detail.Ingredients...)
+		for _, media := range detail.Media {
+			if media.Type == MediaVideo {
+				copied.Media = append(copied.Media, media)
+			}
+		}
+		fork.StepDetails = append(fork.StepDetails, copied)
+	}
+	return fork
+}
+
+// ListRecipeForks returns the recipes forked from a recipe
+func (client *Client) ListRecipeForks(recipeID string) ([]Recipe, error) {
+	recipes, err := client.ListAllRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	forks := []Recipe{}
+	for _, recipe := range recipes {
+		if recipe.ParentID == recipeID {
+			forks = append(forks, recipe)
+		}
+	}
+	return forks, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestForkRecipe(t *testing.T) {
+	mockClient := newMockClient()
+	parent, _ := mockClient.SaveRecipe(Recipe{
+		Name:        "Chili",
+		Ingredients: map[string]string{"beans": "2 cans"},
+		Steps:       []string{"Simmer"},
+		StepDetails: []StepDetail{{Media: []StepMedia{
+			{ID: "photo", Type: MediaImage, Key: "recipe/photo.jpg"},
+			{ID: "video", Type: MediaVideo, URL: "https://example.com/video"},
+		}}},
+		Image:          &RecipeImage{Version: "1"},
+		LabelOverrides: &LabelOverrides{Allergens: map[string]bool{"nuts": false}},
+	})
+
+	fork := parent.Fork("cousin")
+	fork.Ingredients["corn"] = "1 cup"
+	saved, err := mockClient.SaveRecipe(fork)
+	if err != nil {
+		t.Fatalf("Error saving fork: %s", err.Error())
+	}
+
+	if saved.ParentID != parent.ID || saved.ParentRevision != parent.Revision || saved.OwnerID != "cousin" || saved.Image != nil || saved.LabelOverrides != nil {
+		t.Errorf("Wrong fork: %+v", saved)
+	}
+	if _, ok := parent.Ingredients["corn"]; ok {
+		t.Error("Expected changing the fork to leave the parent alone")
+	}
+	if media := saved.StepDetails[0].Media; len(media) != 1 || media[0].ID != "video" {
+		t.Errorf("Expected only videos to be copied, got %+v", media)
+	}
+
+	forks, err := mockClient.ListRecipeForks(parent.ID)
+	if err != nil || len(forks) != 1 || forks[0].ID != saved.ID {
+		t.Errorf("Expected the fork to be listed, got %+v, %v", forks, err)
+	}
+}
+
+func TestDiffRecipes(t *testing.T) {
+	original := Recipe{
+		Ingredients: map[string]string{"Flour": "2 cups", "sugar": "1 cup", "salt": "1 tsp"},
+		Steps:       []string{"Preheat the oven", "Mix the dry ingredients", "Add the eggs", "Bake for 20 minutes"},
+	}
+	variant := Recipe{
+		Ingredients: map[string]string{"flour": "2 cups", "sugar": "1/2 cup", "honey": "1/4 cup"},
+		Steps:       []string{"Preheat the oven", "Whisk the honey and eggs", "Mix the dry ingredients", "Add the eggs", "Bake for 25 minutes"},
+	}
+
+	diff := DiffRecipes(original, variant)
+
+	expected := IngredientDiff{
+		Added:   map[string]string{"honey": "1/4 cup"},
+		Removed: map[string]string{"salt": "1 tsp"},
+		Changed: map[string]AmountChange{"sugar": {From: "1 cup", To: "1/2 cup"}},
+	}
+	if !reflect.DeepEqual(diff.Ingredients, expected) {
+		t.Errorf("Wrong ingredient diff: %+v", diff.Ingredients)
+	}
+
+	if len(diff.Steps) != 2 {
+		t.Fatalf("Expected 2 step changes, got %+v", diff.Steps)
+	}
+	if added := diff.Steps[0]; added.Change != StepAdded || *added.To != 1 || added.From != nil {
+		t.Errorf("Expected step 1 to be added, got %+v", added)
+	}
+	if changed := diff.Steps[1]; changed.Change != StepChanged || *changed.From != 3 || *changed.To != 4 || changed.Old != "Bake for 20 minutes" {
+		t.Errorf("Expected the baking step to change, got %+v", changed)
+	}
+
+	if diff := DiffRecipes(original, original); len(diff.Steps) != 0 || len(diff.Ingredients.Changed) != 0 {
+		t.Errorf("Expected no differences, got %+v", diff)
+	}
+}
@@ -0,0 +1,447 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// ErrInHousehold is returned when a user who is already in a household is
+// added to another one
+var ErrInHousehold = errors.New("user is already in a household")
+
+// ErrNotInvited is returned when a user accepts an invite to a household that
+// hasn't invited them
+var ErrNotInvited = errors.New("user has not been invited to the household")
+
+// Household is a group of users who shop and cook together. A user can be in
+// one household at a time.
+type Household struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	OwnerID   string   `json:"ownerId"`
+	MemberIDs []string `json:"memberIds"`
+	// Invites are the (lowercased) emails of people invited to join, who
+	// haven't accepted or declined yet
+	Invites   []string  `json:"invites,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Version goes up by one every time the household is saved
+	Version int `json:"version"`
+}
+
+// HasMember reports whether the user is in the household
+func (household Household) HasMember(userID string) bool {
+	return ContainsString(household.MemberIDs, userID)
+}
+
+// Invited reports whether email has been invited to join the household
+func (household Household) Invited(email string) bool {
+	return email != "" && ContainsString(household.Invites, inviteKey(email))
+}
+
+// CreateHousehold starts a household with the owner as it's only member
+func (client *Client) CreateHousehold(name string, ownerID string) (*Household, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	household := Household{
+		ID:        id.String(),
+		Name:      name,
+		OwnerID:   ownerID,
+		MemberIDs: []string{ownerID},
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = client.joinHousehold(ownerID, household.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = client.putHousehold(&household)
+	if err != nil {
+		client.leaveHousehold(ownerID, household.ID)
+		return nil, err
+	}
+
+	return &household, nil
+}
+
+// RenameHousehold changes a household's name
+func (client *Client) RenameHousehold(household Household, name string) error {
+	_, err := client.updateHousehold(household.ID, func(household *Household) {
+		household.Name = name
+	})
+	return err
+}
+
+// InviteHouseholdMember invites whoever logs in with email to join the
+// household. Nobody is added until they accept.
+func (client *Client) InviteHouseholdMember(household Household, email string) (*Household, error) {
+	return client.updateHousehold(household.ID, func(household *Household) {
+		household.Invites = AppendUnique(household.Invites, inviteKey(email))
+	})
+}
+
+// ListHouseholdInvites returns the households that have invited email
+func (client *Client) ListHouseholdInvites(email string) ([]Household, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(HouseholdTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	households := []Household{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &households)
+	if err != nil {
+		return nil, err
+	}
+
+	invited := []Household{}
+	for _, household := range households {
+		if household.Invited(email) {
+			invited = append(invited, household)
+		}
+	}
+	sort.Slice(invited, func(i, j int) bool {
+		return invited[i].CreatedAt.Before(invited[j].CreatedAt)
+	})
+	return invited, nil
+}
+
+// AcceptHouseholdInvite adds the user to a household that invited their
+// email. ErrNotInvited is returned if it hasn't, and ErrInHousehold if
+// they're already in one.
+func (client *Client) AcceptHouseholdInvite(householdID string, user User) (*Household, error) {
+	household, err := client.GetHousehold(householdID)
+	if err != nil {
+		return nil, err
+	}
+	if !household.Invited(user.Email) {
+		return nil, ErrNotInvited
+	}
+
+	err = client.joinHousehold(user.ID, household.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := client.updateHousehold(household.ID, func(household *Household) {
+		household.Invites = RemoveString(household.Invites, inviteKey(user.Email))
+		household.MemberIDs = AppendUnique(household.MemberIDs, user.ID)
+	})
+	if err != nil {
+		client.leaveHousehold(user.ID, household.ID)
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeclineHouseholdInvite takes email's invite off the household
+func (client *Client) DeclineHouseholdInvite(householdID string, email string) (*Household, error) {
+	return client.updateHousehold(householdID, func(household *Household) {
+		household.Invites = RemoveString(household.Invites, inviteKey(email))
+	})
+}
+
+// AddHouseholdMember adds a user who isn't in a household yet.
+// ErrInHousehold is returned if they are, even if they joined one since they
+// were last read.
+func (client *Client) AddHouseholdMember(household Household, userID string) (*Household, error) {
+	err := client.joinHousehold(userID, household.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := client.updateHousehold(household.ID, func(household *Household) {
+		if !household.HasMember(userID) {
+			household.MemberIDs = append(household.MemberIDs, userID)
+		}
+	})
+	if err != nil {
+		client.leaveHousehold(userID, household.ID)
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// RemoveHouseholdMember takes a user out of a household
+func (client *Client) RemoveHouseholdMember(household Household, userID string) (*Household, error) {
+	updated, err := client.updateHousehold(household.ID, func(household *Household) {
+		household.MemberIDs = RemoveString(household.MemberIDs, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.leaveHousehold(userID, household.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// GetHousehold fetches a household by it's ID
+func (client *Client) GetHousehold(id string) (*Household, error) {
+	var household *Household
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(HouseholdTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find household with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &household)
+	if err != nil {
+		return nil, err
+	}
+
+	return household, nil
+}
+
+// GetUserHousehold fetches the household the user is in
+func (client *Client) GetUserHousehold(userID string) (*Household, error) {
+	user, err := client.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.HouseholdID == "" {
+		return nil, errors.New("User is not in a household: " + userID)
+	}
+	return client.GetHousehold(user.HouseholdID)
+}
+
+// HouseholdIDFor is the ID that things shared by a household, like the
+// price catalog, are kept under. Users who aren't in a household keep
+// them to themselves, under their own ID.
+func (client *Client) HouseholdIDFor(userID string) string {
+	user, err := client.GetUser(userID)
+	if err != nil || user.HouseholdID == "" {
+		return userID
+	}
+	return user.HouseholdID
+}
+
+// DeleteHousehold deletes a household, leaving it's members in none. The
+// latest household is deleted, so members who joined since it was read
+// aren't left in a household that doesn't exist.
+func (client *Client) DeleteHousehold(household Household) error {
+	for attempt := 0; attempt < householdAttempts; attempt++ {
+		latest, err := client.GetHousehold(household.ID)
+		if err != nil {
+			return err
+		}
+
+		input := &dynamodb.DeleteItemInput{
+			TableName: aws.String(HouseholdTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {
+					S: aws.String(household.ID),
+				},
+			},
+			ConditionExpression:      aws.String("#version = :version"),
+			ExpressionAttributeNames: map[string]*string{"#version": aws.String("version")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":version": {N: aws.String(strconv.Itoa(latest.Version))},
+			},
+		}
+		if latest.Version == 0 {
+			input.ConditionExpression = aws.String("attribute_not_exists(#version)")
+			input.ExpressionAttributeValues = nil
+		}
+
+		_, err = client.dbService.DeleteItem(input)
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error deleting household: %w", err)
+		}
+
+		for _, id := range latest.MemberIDs {
+			err = client.leaveHousehold(id, household.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("error deleting household: %w", errHouseholdChanged)
+}
+
+// FindUserByEmail finds the user who logged in with an email address
+func (client *Client) FindUserByEmail(email string) (*User, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(UserTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := []User{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+
+	return nil, errors.New("Could not find user with email: " + email)
+}
+
+// - MARK: Helper Functions
+
+// saves a household, as long as it hasn't been saved since it was read. The
+// version goes up by one each time.
+func (client *Client) putHousehold(household *Household) error {
+	expected := household.Version
+	household.Version++
+	av, err := dynamodbattribute.MarshalMap(household)
+	if err != nil {
+		return fmt.Errorf("error marshalling household: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:                     av,
+		TableName:                aws.String(HouseholdTable),
+		ConditionExpression:      aws.String("#version = :version"),
+		ExpressionAttributeNames: map[string]*string{"#version": aws.String("version")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":version": {N: aws.String(strconv.Itoa(expected))},
+		},
+	}
+	// new households, and ones saved before they had versions
+	if expected == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(#version)")
+		input.ExpressionAttributeValues = nil
+	}
+
+	_, err = client.dbService.PutItem(input)
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return errHouseholdChanged
+	}
+	if err != nil {
+		return fmt.Errorf("error saving household: %w", err)
+	}
+
+	return nil
+}
+
+// errHouseholdChanged is returned by putHousehold when someone else saved
+// the household first
+var errHouseholdChanged = errors.New("household has changed")
+
+// how many times a household change is tried against the latest household
+const householdAttempts = 5
+
+// reads the latest household, changes it and saves it, trying again if
+// someone else saved it in the meantime
+func (client *Client) updateHousehold(id string, change func(*Household)) (*Household, error) {
+	for attempt := 0; attempt < householdAttempts; attempt++ {
+		household, err := client.GetHousehold(id)
+		if err != nil {
+			return nil, err
+		}
+
+		change(household)
+		err = client.putHousehold(household)
+		if err == errHouseholdChanged {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return household, nil
+	}
+	return nil, fmt.Errorf("error saving household: %w", errHouseholdChanged)
+}
+
+// invites are kept by email, whatever case it's typed in
+func inviteKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// puts the user in the household, unless they're already in one
+func (client *Client) joinHousehold(userID string, householdID string) error {
+	_, err := client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(UserTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(userID),
+			},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(householdId)"),
+		UpdateExpression:    aws.String("SET householdId = :household"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":household": {S: aws.String(householdID)},
+		},
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		// users who don't exist can't join either
+		_, err = client.GetUser(userID)
+		if err != nil {
+			return err
+		}
+		return ErrInHousehold
+	}
+	if err != nil {
+		return fmt.Errorf("error updating user's household: %w", err)
+	}
+
+	return nil
+}
+
+// takes the user out of the household, if they're still in it
+func (client *Client) leaveHousehold(userID string, householdID string) error {
+	_, err := client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(UserTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(userID),
+			},
+		},
+		ConditionExpression: aws.String("householdId = :household"),
+		UpdateExpression:    aws.String("REMOVE householdId"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":household": {S: aws.String(householdID)},
+		},
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error updating user's household: %w", err)
+	}
+
+	return nil
+}
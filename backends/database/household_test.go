@@ -0,0 +1,136 @@
+package database
+
+import "testing"
+
+func TestHousehold(t *testing.T) {
+	mockClient := newMockClient()
+	owner, _ := mockClient.EnsureUser("issuer", "owner", "owner@example.com", "Owner")
+	member, _ := mockClient.EnsureUser("issuer", "member", "Member@Example.com", "Member")
+
+	household, err := mockClient.CreateHousehold("Home", owner.ID)
+	if err != nil {
+		t.Fatalf("Error creating household: %s", err.Error())
+	}
+
+	found, err := mockClient.FindUserByEmail("member@example.com")
+	if err != nil || found.ID != member.ID {
+		t.Fatalf("Expected to find the member by email, got %v", err)
+	}
+	household, err = mockClient.AddHouseholdMember(*household, member.ID)
+	if err != nil {
+		t.Fatalf("Error adding member: %s", err.Error())
+	}
+
+	if mockClient.HouseholdIDFor(member.ID) != household.ID {
+		t.Error("Expected the member to share the household")
+	}
+	if _, err := mockClient.CreateHousehold("Other", member.ID); err != ErrInHousehold {
+		t.Errorf("Expected members not to be able to start another household, got %v", err)
+	}
+
+	// logging in again keeps the household
+	mockClient.EnsureUser("issuer", "member", "member@example.com", "Member")
+	if mockClient.HouseholdIDFor(member.ID) != household.ID {
+		t.Error("Expected logging in to keep the household")
+	}
+
+	household, err = mockClient.RemoveHouseholdMember(*household, member.ID)
+	if err != nil || household.HasMember(member.ID) {
+		t.Fatalf("Expected the member to be removed, got %v", err)
+	}
+	if mockClient.HouseholdIDFor(member.ID) != member.ID {
+		t.Error("Expected a user without a household to keep things to themselves")
+	}
+
+	err = mockClient.DeleteHousehold(*household)
+	if err != nil || mockClient.HouseholdIDFor(owner.ID) != owner.ID {
+		t.Errorf("Expected deleting the household to leave the owner in none, got %v", err)
+	}
+}
+
+func TestHouseholdChangesFromStaleReads(t *testing.T) {
+	mockClient := newMockClient()
+	owner, _ := mockClient.EnsureUser("issuer", "owner", "owner@example.com", "Owner")
+	first, _ := mockClient.EnsureUser("issuer", "first", "first@example.com", "First")
+	second, _ := mockClient.EnsureUser("issuer", "second", "second@example.com", "Second")
+
+	// both invitations were sent from the household as it was created
+	household, _ := mockClient.CreateHousehold("Home", owner.ID)
+	_, err := mockClient.AddHouseholdMember(*household, first.ID)
+	if err != nil {
+		t.Fatalf("Error adding member: %s", err.Error())
+	}
+	updated, err := mockClient.AddHouseholdMember(*household, second.ID)
+	if err != nil {
+		t.Fatalf("Error adding member: %s", err.Error())
+	}
+	if !updated.HasMember(owner.ID) || !updated.HasMember(first.ID) || !updated.HasMember(second.ID) {
+		t.Errorf("Expected nobody to be lost, got %v", updated.MemberIDs)
+	}
+
+	// the second member was read before they joined
+	_, err = mockClient.AddHouseholdMember(*household, second.ID)
+	if err != ErrInHousehold {
+		t.Errorf("Expected the second member to already be in a household, got %v", err)
+	}
+
+	err = mockClient.RenameHousehold(*household, "Our Place")
+	renamed, _ := mockClient.GetHousehold(household.ID)
+	if err != nil || renamed.Name != "Our Place" || len(renamed.MemberIDs) != 3 {
+		t.Errorf("Expected renaming to keep the members, got %+v and %v", renamed, err)
+	}
+}
+
+func TestHouseholdInvites(t *testing.T) {
+	mockClient := newMockClient()
+	owner, _ := mockClient.EnsureUser("issuer", "owner", "owner@example.com", "Owner")
+	stranger, _ := mockClient.EnsureUser("issuer", "stranger", "stranger@example.com", "Stranger")
+	household, _ := mockClient.CreateHousehold("Home", owner.ID)
+
+	// people can be invited before they've ever logged in
+	_, err := mockClient.InviteHouseholdMember(*household, " Member@Example.com")
+	if err != nil {
+		t.Fatalf("Error inviting member: %s", err.Error())
+	}
+	member, _ := mockClient.EnsureUser("issuer", "member", "member@example.com", "Member")
+	if mockClient.HouseholdIDFor(member.ID) != member.ID {
+		t.Fatal("Expected nobody to be added until they accept")
+	}
+
+	invites, err := mockClient.ListHouseholdInvites(member.Email)
+	if err != nil || len(invites) != 1 || invites[0].ID != household.ID {
+		t.Fatalf("Expected the member to be invited, got %+v and %v", invites, err)
+	}
+	if _, err := mockClient.AcceptHouseholdInvite(household.ID, *stranger); err != ErrNotInvited {
+		t.Errorf("Expected someone else not to be able to accept, got %v", err)
+	}
+
+	household, err = mockClient.AcceptHouseholdInvite(household.ID, *member)
+	if err != nil {
+		t.Fatalf("Error accepting invite: %s", err.Error())
+	}
+	if !household.HasMember(member.ID) || household.Invited(member.Email) || mockClient.HouseholdIDFor(member.ID) != household.ID {
+		t.Errorf("Expected the member to join and the invite to be used up, got %+v", household)
+	}
+}
+
+func TestDeleteHouseholdFromStaleRead(t *testing.T) {
+	mockClient := newMockClient()
+	owner, _ := mockClient.EnsureUser("issuer", "owner", "owner@example.com", "Owner")
+	member, _ := mockClient.EnsureUser("issuer", "member", "member@example.com", "Member")
+
+	// the member joins after the owner read the household to delete it
+	household, _ := mockClient.CreateHousehold("Home", owner.ID)
+	_, err := mockClient.AddHouseholdMember(*household, member.ID)
+	if err != nil {
+		t.Fatalf("Error adding member: %s", err.Error())
+	}
+
+	err = mockClient.DeleteHousehold(*household)
+	if err != nil {
+		t.Fatalf("Error deleting household: %s", err.Error())
+	}
+	if mockClient.HouseholdIDFor(member.ID) != member.ID || mockClient.HouseholdIDFor(owner.ID) != owner.ID {
+		t.Error("Expected deleting the household to leave everyone in it in none")
+	}
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// LabelOverrides correct the inferred labels by hand. True adds an allergen
+// or diet and false removes a diet. An allergen set to false or a diet set
+// to true is also vouched for, so unknown ingredients no longer keep the
+// recipe out of filters for it. Allergens an ingredient is known to have
+// can't be removed.
+type LabelOverrides struct {
+	Allergens map[string]bool `json:"allergens,omitempty"`
+	Diets     map[string]bool `json:"diets,omitempty"`
+	// Ingredients are the (normalized) ingredients the overrides were made
+	// for. Nobody has vouched for any others, so the overrides are dropped
+	// when the ingredients change.
+	Ingredients []string `json:"ingredients,omitempty"`
+}
+
+// RecordLabelOverrides notes which ingredients the recipe's overrides are
+// made for. Overrides sent back as they were on old are still only made for
+// old's ingredients.
+func (recipe *Recipe) RecordLabelOverrides(old Recipe) {
+	if recipe.LabelOverrides == nil {
+		return
+	}
+
+	overrides := *recipe.LabelOverrides
+	overrides.Ingredients = overriddenIngredients(*recipe)
+	if old.LabelOverrides != nil && reflect.DeepEqual(overrides.Allergens, old.LabelOverrides.Allergens) && reflect.DeepEqual(overrides.Diets, old.LabelOverrides.Diets) {
+		overrides.Ingredients = old.LabelOverrides.Ingredients
+		if overrides.Ingredients == nil {
+			overrides.Ingredients = overriddenIngredients(old)
+		}
+	}
+	recipe.LabelOverrides = &overrides
+}
+
+// DropStaleLabelOverrides drops overrides made for other ingredients than
+// the recipe has now
+func (recipe *Recipe) DropStaleLabelOverrides() {
+	overrides := recipe.LabelOverrides
+	if overrides != nil && overrides.Ingredients != nil && !reflect.DeepEqual(overrides.Ingredients, overriddenIngredients(*recipe)) {
+		recipe.LabelOverrides = nil
+	}
+}
+
+// ApplyLabels works out the recipe's allergens and dietary labels from it's
+// ingredients and overrides
+func (recipe *Recipe) ApplyLabels() {
+	names := []string{}
+	for name := range recipe.Ingredients {
+		names = append(names, name)
+	}
+
+	allergens, diets, unknown := ingredient.Labels(names)
+	recipe.UnknownIngredients = unknown
+	if len(unknown) == 0 {
+		recipe.UnknownIngredients = nil
+	}
+
+	if recipe.LabelOverrides == nil {
+		recipe.Allergens, recipe.Diets = allergens, diets
+		return
+	}
+	recipe.Allergens = applyOverrides(ingredient.Allergens, allergens, recipe.LabelOverrides.Allergens, false)
+	recipe.Diets = applyOverrides(ingredient.Diets, diets, recipe.LabelOverrides.Diets, true)
+}
+
+// FreeOf reports whether the recipe can be trusted not to contain allergen.
+// Recipes with unknown ingredients can't be, unless someone has said so.
+func (recipe Recipe) FreeOf(allergen string) bool {
+	for _, a := range recipe.Allergens {
+		if a == allergen {
+			return false
+		}
+	}
+
+	if len(recipe.UnknownIngredients) == 0 {
+		return true
+	}
+	if recipe.LabelOverrides != nil {
+		free, ok := recipe.LabelOverrides.Allergens[allergen]
+		return ok && !free
+	}
+	return false
+}
+
+// HasDiet reports whether the recipe can be trusted to suit diet. Like
+// FreeOf, recipes with unknown ingredients can't be unless someone has said
+// so.
+func (recipe Recipe) HasDiet(diet string) bool {
+	labelled := false
+	for _, d := range recipe.Diets {
+		if d == diet {
+			labelled = true
+		}
+	}
+
+	if !labelled || len(recipe.UnknownIngredients) == 0 {
+		return labelled
+	}
+	if recipe.LabelOverrides != nil {
+		return recipe.LabelOverrides.Diets[diet]
+	}
+	return false
+}
+
+// keeps all's order, with labels added by overrides and, if remove is set,
+// inferred labels removed by them
+func applyOverrides(all []string, inferred []string, overrides map[string]bool, remove bool) []string {
+	has := map[string]bool{}
+	for _, label := range inferred {
+		has[label] = true
+	}
+	for label, value := range overrides {
+		if value || remove {
+			has[label] = value
+		}
+	}
+
+	labels := []string{}
+	for _, label := range all {
+		if has[label] {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// the recipe's own ingredients, normalized and sorted
+func overriddenIngredients(recipe Recipe) []string {
+	names := []string{}
+	for name := range recipe.Ingredients {
+		names = append(names, ingredient.Normalize(name))
+	}
+	sort.Strings(names)
+	return names
+}
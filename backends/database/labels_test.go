@@ -0,0 +1,92 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyLabels(t *testing.T) {
+	recipe := Recipe{
+		Ingredients: map[string]string{
+			"flour":       "2 cups",
+			"butter":      "1/2 cup",
+			"sugar":       "1 cup",
+			"xanthan gum": "1 tsp",
+		},
+	}
+
+	recipe.ApplyLabels()
+	if !reflect.DeepEqual(recipe.Allergens, []string{"gluten", "dairy"}) {
+		t.Errorf("Wrong allergens: %v", recipe.Allergens)
+	}
+	if !reflect.DeepEqual(recipe.Diets, []string{"vegetarian"}) {
+		t.Errorf("Wrong diets: %v", recipe.Diets)
+	}
+	if recipe.FreeOf("nuts") {
+		t.Error("Expected an unknown ingredient to keep the recipe from being nut free")
+	}
+	if recipe.HasDiet("vegetarian") {
+		t.Error("Expected an unknown ingredient to keep the recipe from being vegetarian")
+	}
+
+	recipe.LabelOverrides = &LabelOverrides{
+		Allergens: map[string]bool{"nuts": false, "gluten": false},
+		Diets:     map[string]bool{"vegan": true},
+	}
+	recipe.ApplyLabels()
+	if !reflect.DeepEqual(recipe.Allergens, []string{"gluten", "dairy"}) {
+		t.Errorf("Expected the flour's gluten to stay, got %v", recipe.Allergens)
+	}
+	if !reflect.DeepEqual(recipe.Diets, []string{"vegetarian", "vegan"}) {
+		t.Errorf("Expected vegan to be added, got %v", recipe.Diets)
+	}
+	if !recipe.FreeOf("nuts") {
+		t.Error("Expected the override to vouch for the recipe being nut free")
+	}
+	if recipe.FreeOf("dairy") || recipe.FreeOf("gluten") {
+		t.Error("Expected the recipe to contain dairy and gluten")
+	}
+	if !recipe.HasDiet("vegan") || recipe.HasDiet("vegetarian") {
+		t.Error("Expected only the overridden diet to be vouched for")
+	}
+}
+
+func TestHasDietWithoutUnknownIngredients(t *testing.T) {
+	recipe := Recipe{Ingredients: map[string]string{"apples": "4", "pie crust": "1"}}
+	recipe.ApplyLabels()
+	if !recipe.HasDiet("vegan") || recipe.HasDiet("keto-friendly") {
+		t.Errorf("Expected apple pie to be vegan and not keto-friendly, got %v", recipe.Diets)
+	}
+}
+
+func TestLabelOverridesAreForTheirIngredients(t *testing.T) {
+	old := Recipe{
+		Ingredients:    map[string]string{"flour": "2 cups", "xanthan gum": "1 tsp"},
+		LabelOverrides: &LabelOverrides{Allergens: map[string]bool{"nuts": false}},
+	}
+	old.RecordLabelOverrides(Recipe{})
+	if !reflect.DeepEqual(old.LabelOverrides.Ingredients, []string{"flour", "xanthan gum"}) {
+		t.Fatalf("Expected the overrides to be made for the flour and xanthan gum, got %v", old.LabelOverrides.Ingredients)
+	}
+
+	// the same overrides sent back with an ingredient nobody vouched for
+	edited := Recipe{
+		Ingredients:    map[string]string{"flour": "2 cups", "xanthan gum": "1 tsp", "mystery crunch": "1 cup"},
+		LabelOverrides: &LabelOverrides{Allergens: map[string]bool{"nuts": false}},
+	}
+	edited.RecordLabelOverrides(old)
+	edited.DropStaleLabelOverrides()
+	edited.ApplyLabels()
+	if edited.LabelOverrides != nil || edited.FreeOf("nuts") {
+		t.Errorf("Expected the overrides to be dropped, got %+v", edited.LabelOverrides)
+	}
+
+	// overrides made again for the new ingredients are kept
+	edited.LabelOverrides = &LabelOverrides{Allergens: map[string]bool{"nuts": false, "dairy": true}}
+	edited.RecordLabelOverrides(old)
+	edited.DropStaleLabelOverrides()
+	edited.ApplyLabels()
+	if edited.LabelOverrides == nil || !edited.FreeOf("nuts") {
+		t.Errorf("Expected the new overrides to be kept, got %+v", edited.LabelOverrides)
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// DateFormat is how calendar dates are stored, so they sort as strings
+const DateFormat = "2006-01-02"
+
+// MealSlots are the meals a recipe can be planned for, in the order they
+// happen in a day
+var MealSlots = []string{"breakfast", "lunch", "dinner", "snack"}
+
+// ValidMealSlot reports whether slot is one of MealSlots
+func ValidMealSlot(slot string) bool {
+	for _, s := range MealSlots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// MealPlanEntry schedules a recipe for a meal on a date
+type MealPlanEntry struct {
+	ID       string `json:"id"`
+	UserID   string `json:"userId"`
+	Date     string `json:"date"`
+	Slot     string `json:"slot"`
+	RecipeID string `json:"recipeId"`
+	// RecipeName is copied from the recipe so the plan still makes sense if
+	// the recipe is deleted
+	RecipeName string `json:"recipeName"`
+	// Servings overrides the recipe's servings when set
+	Servings  int       `json:"servings,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// RecipeDeleted is filled in when listing, it is not stored
+	RecipeDeleted bool `json:"recipeDeleted" dynamodbav:"-"`
+}
+
+// SaveMealPlanEntry saves a new entry to the meal plan
+func (client *Client) SaveMealPlanEntry(entry MealPlanEntry) (*MealPlanEntry, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	entry.ID = id.String()
+	entry.CreatedAt = time.Now().UTC()
+
+	err = client.putMealPlanEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// UpdateMealPlanEntry replaces an existing entry
+func (client *Client) UpdateMealPlanEntry(entry MealPlanEntry, entryID string) error {
+	entry.ID = entryID
+	return client.putMealPlanEntry(entry)
+}
+
+func (client *Client) putMealPlanEntry(entry MealPlanEntry) error {
+	av, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return fmt.Errorf("error marshalling meal plan entry: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(MealPlanTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving meal plan entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetMealPlanEntry fetches a meal plan entry by it's ID
+func (client *Client) GetMealPlanEntry(id string) (*MealPlanEntry, error) {
+	var entry *MealPlanEntry
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(MealPlanTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find meal plan entry with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ListMealPlanEntries returns the user's entries between from and to
+// (inclusive, formatted with DateFormat), sorted by date and slot
+func (client *Client) ListMealPlanEntries(userID string, from string, to string) ([]MealPlanEntry, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(MealPlanTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []MealPlanEntry{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := []MealPlanEntry{}
+	for _, entry := range entries {
+		if entry.UserID == userID && entry.Date >= from && entry.Date <= to {
+			planned = append(planned, entry)
+		}
+	}
+
+	sort.SliceStable(planned, func(i, j int) bool {
+		if planned[i].Date != planned[j].Date {
+			return planned[i].Date < planned[j].Date
+		}
+		return slotOrder(planned[i].Slot) < slotOrder(planned[j].Slot)
+	})
+
+	return planned, nil
+}
+
+// DeleteMealPlanEntry deletes a meal plan entry given it's ID
+func (client *Client) DeleteMealPlanEntry(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(MealPlanTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
+
+func slotOrder(slot string) int {
+	for i, s := range MealSlots {
+		if s == slot {
+			return i
+		}
+	}
+	return len(MealSlots)
+}
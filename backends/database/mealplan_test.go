@@ -0,0 +1,36 @@
+package database
+
+import "testing"
+
+func TestListMealPlanEntries(t *testing.T) {
+	mockClient := newMockClient()
+
+	planned := []MealPlanEntry{
+		{UserID: "sam", Date: "2026-10-20", Slot: "dinner", RecipeID: "soup"},
+		{UserID: "sam", Date: "2026-10-20", Slot: "breakfast", RecipeID: "oats"},
+		{UserID: "sam", Date: "2026-10-19", Slot: "lunch", RecipeID: "salad"},
+		{UserID: "sam", Date: "2026-10-26", Slot: "dinner", RecipeID: "next-week"},
+		{UserID: "gran", Date: "2026-10-20", Slot: "dinner", RecipeID: "someone-else"},
+	}
+	for _, entry := range planned {
+		_, err := mockClient.SaveMealPlanEntry(entry)
+		if err != nil {
+			t.Fatalf("Error saving meal plan entry: %s", err.Error())
+		}
+	}
+
+	entries, err := mockClient.ListMealPlanEntries("sam", "2026-10-19", "2026-10-25")
+	if err != nil {
+		t.Fatalf("Error listing meal plan: %s", err.Error())
+	}
+
+	order := []string{"salad", "oats", "soup"}
+	if len(entries) != len(order) {
+		t.Fatalf("Listed wrong entries: %+v", entries)
+	}
+	for i, recipeID := range order {
+		if entries[i].RecipeID != recipeID {
+			t.Errorf("Entry %d is %s, expected %s", i, entries[i].RecipeID, recipeID)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// MergeConflict is a part of a recipe both sides changed in different ways
+type MergeConflict struct {
+	// Field is a field's JSON name, "ingredients.<name>" for an ingredient
+	// or "steps" for a run of steps
+	Field string `json:"field"`
+	// Index is where a run of conflicting steps starts in the base
+	Index *int `json:"index,omitempty"`
+	// Base, Ours and Theirs are the values in the common ancestor, in the
+	// edit being saved and in the stored recipe. Missing ingredients are
+	// null.
+	Base   interface{} `json:"base"`
+	Ours   interface{} `json:"ours"`
+	Theirs interface{} `json:"theirs"`
+}
+
+// MergeRecipes does a three-way merge of two edits of the base recipe, ours
+// being saved over theirs. Parts only one side changed take that side's
+// change, and parts both changed the same way are kept. Everything else is
+// returned as a conflict, in which case merged shouldn't be saved. Fields
+// the server manages come from theirs.
+func MergeRecipes(base Recipe, ours Recipe, theirs Recipe) (merged Recipe, conflicts []MergeConflict) {
+	merged = theirs
+	conflicts = []MergeConflict{}
+
+	for _, field := range recipeFields {
+		b, o, t := field.get(base), field.get(ours), field.get(theirs)
+		switch {
+		case reflect.DeepEqual(o, b) || reflect.DeepEqual(o, t):
+		case reflect.DeepEqual(t, b):
+			field.set(&merged, ours)
+		default:
+			conflicts = append(conflicts, MergeConflict{Field: field.name, Base: b, Ours: o, Theirs: t})
+		}
+	}
+
+	var ingredientConflicts []MergeConflict
+	merged.Ingredients, ingredientConflicts = mergeIngredients(base.Ingredients, ours.Ingredients, theirs.Ingredients)
+	conflicts = append(conflicts, ingredientConflicts...)
+
+	steps, stepConflicts := mergeSteps(recipeSteps(base), recipeSteps(ours), recipeSteps(theirs))
+	setRecipeSteps(&merged, steps)
+	conflicts = append(conflicts, stepConflicts...)
+
+	return merged, conflicts
+}
+
+// - MARK: Helper Functions
+
+func mergeIngredients(base map[string]string, ours map[string]string, theirs map[string]string) (map[string]string, []MergeConflict) {
+	type side struct {
+		name   string
+		amount *string
+	}
+	index := func(ingredients map[string]string) map[string]side {
+		sides := map[string]side{}
+		for name, amount := range ingredients {
+			amount := strings.TrimSpace(amount)
+			sides[ingredient.Normalize(name)] = side{name: name, amount: &amount}
+		}
+		return sides
+	}
+	same := func(a side, b side) bool {
+		return (a.amount == nil && b.amount == nil) || (a.amount != nil && b.amount != nil && *a.amount == *b.amount)
+	}
+	value := func(s side) interface{} {
+		if s.amount == nil {
+			return nil
+		}
+		return *s.amount
+	}
+
+	b, o, t := index(base), index(ours), index(theirs)
+	keys := []string{}
+	for _, sides := range []map[string]side{b, o, t} {
+		for key := range sides {
+			keys = AppendUnique(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	merged := map[string]string{}
+	conflicts := []MergeConflict{}
+	for _, key := range keys {
+		var chosen side
+		switch {
+		case same(o[key], b[key]) || same(o[key], t[key]):
+			chosen = t[key]
+		case same(t[key], b[key]):
+			chosen = o[key]
+		default:
+			name := o[key].name
+			if name == "" {
+				name = t[key].name
+			}
+			conflicts = append(conflicts, MergeConflict{
+				Field:  "ingredients." + name,
+				Base:   value(b[key]),
+				Ours:   value(o[key]),
+				Theirs: value(t[key]),
+			})
+			chosen = t[key]
+		}
+		if chosen.amount != nil {
+			merged[chosen.name] = *chosen.amount
+		}
+	}
+	return merged, conflicts
+}
+
+// merges the steps diff3 style: steps of the base both sides kept split the
+// steps into runs, and each run is merged as a whole
+func mergeSteps(base []recipeStep, ours []recipeStep, theirs []recipeStep) ([]recipeStep, []MergeConflict) {
+	oursMatches, theirsMatches := matchSteps(base, ours), matchSteps(base, theirs)
+
+	merged := []recipeStep{}
+	conflicts := []MergeConflict{}
+	i, o, t := 0, 0, 0
+	for k := 0; k <= len(base); k++ {
+		// runs end at steps both sides kept, or the end
+		if k < len(base) && (oursMatches[k] < 0 || theirsMatches[k] < 0) {
+			continue
+		}
+		oursEnd, theirsEnd := len(ours), len(theirs)
+		if k < len(base) {
+			oursEnd, theirsEnd = oursMatches[k], theirsMatches[k]
+		}
+
+		b, os, ts := base[i:k], ours[o:oursEnd], theirs[t:theirsEnd]
+		switch {
+		case sameSteps(os, b) || sameSteps(os, ts):
+			merged = append(merged, ts...)
+		case sameSteps(ts, b):
+			merged = append(merged, os...)
+		default:
+			start := i
+			conflicts = append(conflicts, MergeConflict{
+				Field:  "steps",
+				Index:  &start,
+				Base:   stepTexts(b),
+				Ours:   stepTexts(os),
+				Theirs: stepTexts(ts),
+			})
+			merged = append(merged, ts...)
+		}
+
+		if k < len(base) {
+			merged = append(merged, theirs[theirsEnd])
+		}
+		i, o, t = k+1, oursEnd+1, theirsEnd+1
+	}
+	return merged, conflicts
+}
+
+func stepTexts(steps []recipeStep) []string {
+	texts := []string{}
+	for _, step := range steps {
+		texts = append(texts, step.Text)
+	}
+	return texts
+}
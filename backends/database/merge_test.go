@@ -0,0 +1,117 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRecipeFields(t *testing.T) {
+	original := Recipe{Name: "Chili", Servings: 4, Tags: []string{"spicy"}}
+	variant := Recipe{Name: "Chili", Servings: 6, Tags: []string{"Spicy"}}
+
+	diff := DiffRecipes(original, variant)
+	expected := map[string]FieldChange{"servings": {From: 4, To: 6}}
+	if !reflect.DeepEqual(diff.Fields, expected) {
+		t.Errorf("Expected only servings to change, got %+v", diff.Fields)
+	}
+	if !DiffRecipes(original, original).Empty() {
+		t.Error("Expected a recipe to be the same as itself")
+	}
+}
+
+func TestMergeRecipes(t *testing.T) {
+	base := Recipe{
+		Name:        "Pancakes",
+		Servings:    4,
+		Ingredients: map[string]string{"flour": "1 cup", "milk": "1 cup", "egg": "1"},
+		Steps:       []string{"Whisk", "Rest", "Fry"},
+	}
+
+	// the app renamed it and changed the milk offline, while the web added
+	// sugar, a step and more servings
+	ours := base
+	ours.Name = "Fluffy Pancakes"
+	ours.Ingredients = map[string]string{"flour": "1 cup", "milk": "3/4 cup", "egg": "1"}
+	ours.Steps = []string{"Whisk", "Rest for 10 minutes", "Fry"}
+
+	theirs := base
+	theirs.Servings = 6
+	theirs.RatingCount = 3
+	theirs.Ingredients = map[string]string{"flour": "1 cup", "milk": "1 cup", "egg": "1", "sugar": "1 tbsp"}
+	theirs.Steps = []string{"Whisk", "Rest", "Fry", "Serve with syrup"}
+
+	merged, conflicts := MergeRecipes(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected a clean merge, got %+v", conflicts)
+	}
+
+	if merged.Name != "Fluffy Pancakes" || merged.Servings != 6 || merged.RatingCount != 3 {
+		t.Errorf("Wrong fields merged: %+v", merged)
+	}
+	expectedIngredients := map[string]string{"flour": "1 cup", "milk": "3/4 cup", "egg": "1", "sugar": "1 tbsp"}
+	if !reflect.DeepEqual(merged.Ingredients, expectedIngredients) {
+		t.Errorf("Wrong ingredients merged: %v", merged.Ingredients)
+	}
+	expectedSteps := []string{"Whisk", "Rest for 10 minutes", "Fry", "Serve with syrup"}
+	if !reflect.DeepEqual(merged.Steps, expectedSteps) {
+		t.Errorf("Wrong steps merged: %v", merged.Steps)
+	}
+}
+
+func TestMergeRecipeConflicts(t *testing.T) {
+	base := Recipe{
+		Name:        "Pancakes",
+		Ingredients: map[string]string{"milk": "1 cup", "egg": "1"},
+		Steps:       []string{"Whisk", "Fry"},
+	}
+
+	ours := base
+	ours.Name = "Fluffy Pancakes"
+	ours.Ingredients = map[string]string{"milk": "3/4 cup"}
+	ours.Steps = []string{"Whisk well", "Fry"}
+
+	theirs := base
+	theirs.Name = "Best Pancakes"
+	theirs.Ingredients = map[string]string{"milk": "1 1/4 cups"}
+	theirs.Steps = []string{"Whisk gently", "Fry"}
+
+	_, conflicts := MergeRecipes(base, ours, theirs)
+	if len(conflicts) != 3 {
+		t.Fatalf("Expected 3 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].Field != "name" || conflicts[0].Ours != "Fluffy Pancakes" || conflicts[0].Theirs != "Best Pancakes" {
+		t.Errorf("Wrong name conflict: %+v", conflicts[0])
+	}
+	// both removed the egg, so that merges cleanly
+	if conflicts[1].Field != "ingredients.milk" || conflicts[1].Base != "1 cup" {
+		t.Errorf("Wrong ingredient conflict: %+v", conflicts[1])
+	}
+	if conflicts[2].Field != "steps" || *conflicts[2].Index != 0 || !reflect.DeepEqual(conflicts[2].Ours, []string{"Whisk well"}) {
+		t.Errorf("Wrong step conflict: %+v", conflicts[2])
+	}
+}
+
+func TestReplaceRecipe(t *testing.T) {
+	mockClient := newMockClient()
+	recipe, _ := mockClient.SaveRecipe(Recipe{Name: "Soup"})
+	if recipe.Revision != 1 {
+		t.Fatalf("Expected new recipes to be at revision 1, got %d", recipe.Revision)
+	}
+
+	recipe.Name = "Tomato Soup"
+	err := mockClient.ReplaceRecipe(*recipe, recipe.ID, 1)
+	if err != nil {
+		t.Fatalf("Error replacing recipe: %s", err.Error())
+	}
+
+	recipe.Name = "Pea Soup"
+	if err := mockClient.ReplaceRecipe(*recipe, recipe.ID, 1); err != ErrRecipeChanged {
+		t.Errorf("Expected a stale revision to be refused, got %v", err)
+	}
+
+	stored, _ := mockClient.GetRecipe(recipe.ID)
+	old, err := mockClient.GetRecipeRevision(recipe.ID, 1)
+	if err != nil || stored.Name != "Tomato Soup" || stored.Revision != 2 || old.Name != "Soup" {
+		t.Errorf("Expected revision 2 to be stored and 1 kept, got %+v, %+v, %v", stored, old, err)
+	}
+}
@@ -0,0 +1,463 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// PantryItem is something a user has at home. A Quantity of zero means they
+// have some but aren't keeping track of how much, like salt.
+type PantryItem struct {
+	ID       string  `json:"id"`
+	UserID   string  `json:"userId"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+	// ExpiresOn is formatted with DateFormat
+	ExpiresOn string    `json:"expiresOn,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Tracked reports whether the item's quantity is being kept track of
+func (item PantryItem) Tracked() bool {
+	return item.Quantity > 0 || item.Unit != ""
+}
+
+// Amount is the item's quantity
+func (item PantryItem) Amount() ingredient.Quantity {
+	return ingredient.NewQuantity(item.Quantity, item.Unit)
+}
+
+// SetAmount changes the item's quantity
+func (item *PantryItem) SetAmount(quantity ingredient.Quantity) {
+	item.Quantity = quantity.Amount
+	item.Unit = quantity.Unit
+}
+
+// PantryChange is an amount of an ingredient to put in, or take out of, a
+// user's pantry
+type PantryChange struct {
+	Name     string
+	Quantity ingredient.Quantity
+	// Stock puts the quantity in the pantry instead of using it
+	Stock bool
+}
+
+// errPantryChanged is returned when the pantry kept changing while it was
+// being updated
+var errPantryChanged = errors.New("pantry changed while it was being updated")
+
+// UsePantryItems takes quantity of the named ingredient out of the pantry,
+// using whatever expires first. It returns the items that changed, the
+// items that ran out, and how much was still needed after the pantry ran
+// out.
+func UsePantryItems(pantry []PantryItem, name string, quantity ingredient.Quantity) ([]PantryItem, []PantryItem, ingredient.Quantity) {
+	matches := matchingPantryItems(pantry, name)
+
+	changed, emptied := []PantryItem{}, []PantryItem{}
+	for _, item := range matches {
+		if quantity.Amount <= 0 {
+			break
+		}
+		if !item.Tracked() {
+			continue
+		}
+
+		have := item.Amount()
+		left, ok := ingredient.Subtract(have, quantity)
+		if !ok {
+			continue
+		}
+		stillNeeded, _ := ingredient.Subtract(quantity, have)
+		quantity = stillNeeded
+
+		if left.Amount <= 0 {
+			emptied = append(emptied, item)
+			continue
+		}
+		item.SetAmount(left)
+		changed = append(changed, item)
+	}
+
+	return changed, emptied, quantity
+}
+
+// StockPantry adds quantity of the named ingredient to the pantry item it
+// can be added to, returning the item to save. The item is new, with no ID,
+// when nothing in the pantry could be added to.
+func StockPantry(pantry []PantryItem, name string, quantity ingredient.Quantity) PantryItem {
+	for _, item := range matchingPantryItems(pantry, name) {
+		if !item.Tracked() {
+			continue
+		}
+		sum, ok := ingredient.Add(item.Amount(), quantity)
+		if ok {
+			item.SetAmount(sum)
+			return item
+		}
+	}
+
+	item := PantryItem{Name: strings.TrimSpace(name)}
+	item.SetAmount(quantity)
+	return item
+}
+
+// ExcludePantryItems takes what's already in the pantry off generated
+// shopping list items, dropping the ones that are fully covered. Manual
+// items are left alone.
+func ExcludePantryItems(items []ShoppingItem, pantry []PantryItem) []ShoppingItem {
+	needed := []ShoppingItem{}
+	for _, item := range items {
+		matches := matchingPantryItems(pantry, item.Name)
+		if item.Manual || len(matches) == 0 {
+			needed = append(needed, item)
+			continue
+		}
+
+		untracked := false
+		for _, match := range matches {
+			untracked = untracked || !match.Tracked()
+		}
+		if untracked {
+			// they have some and aren't counting, so assume it's enough
+			continue
+		}
+
+		// work through a copy so each amount can draw on the whole pantry
+		available := append([]PantryItem{}, matches...)
+		amounts := []string{}
+		for _, part := range strings.Split(item.Amount, " + ") {
+			quantity, ok := ingredient.Parse(part)
+			if !ok {
+				// "to taste" is covered by having any at all
+				continue
+			}
+
+			changed, emptied, stillNeeded := UsePantryItems(available, item.Name, quantity)
+			available = updatePantryItems(available, changed, emptied)
+			if stillNeeded.Amount > 0 {
+				amounts = append(amounts, stillNeeded.String())
+			}
+		}
+
+		if len(amounts) > 0 {
+			item.Amount = strings.Join(amounts, " + ")
+			needed = append(needed, item)
+		}
+	}
+	return needed
+}
+
+// SavePantryItem saves a new pantry item
+func (client *Client) SavePantryItem(item PantryItem) (*PantryItem, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	item.ID = id.String()
+	item.CreatedAt = time.Now().UTC()
+	item.UpdatedAt = item.CreatedAt
+
+	err = client.putPantryItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// UpdatePantryItem replaces an existing pantry item
+func (client *Client) UpdatePantryItem(item PantryItem, itemID string) error {
+	item.ID = itemID
+	item.UpdatedAt = time.Now().UTC()
+	return client.putPantryItem(item)
+}
+
+func (client *Client) putPantryItem(item PantryItem) error {
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return fmt.Errorf("error marshalling pantry item: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(PantryTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving pantry item: %w", err)
+	}
+
+	return nil
+}
+
+// GetPantryItem fetches a pantry item by it's ID
+func (client *Client) GetPantryItem(id string) (*PantryItem, error) {
+	var item *PantryItem
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(PantryTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find pantry item with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ListPantryItems returns the user's pantry, soonest to expire first
+func (client *Client) ListPantryItems(userID string) ([]PantryItem, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(PantryTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := []PantryItem{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	pantry := []PantryItem{}
+	for _, item := range items {
+		if item.UserID == userID {
+			pantry = append(pantry, item)
+		}
+	}
+
+	sortPantryItems(pantry)
+	return pantry, nil
+}
+
+// DeletePantryItem deletes a pantry item given it's ID
+func (client *Client) DeletePantryItem(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(PantryTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
+
+// ChangePantry makes all the changes to the user's pantry at once, in order.
+// Nothing is written if an item the changes touch was edited in the meantime
+// (the pantry is re-read and the changes tried again).
+func (client *Client) ChangePantry(userID string, changes []PantryChange) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		pantry, err := client.ListPantryItems(userID)
+		if err != nil {
+			return err
+		}
+
+		changed, err := changePantryItems(userID, pantry, changes)
+		if err != nil {
+			return err
+		}
+		writes, err := pantryWrites(pantry, changed)
+		if err != nil {
+			return err
+		}
+		if len(writes) == 0 {
+			return nil
+		}
+		if len(writes) > 100 {
+			return fmt.Errorf("error updating pantry: %d items changed, at most 100 can be", len(writes))
+		}
+
+		_, err = client.dbService.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: writes,
+		})
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error updating pantry: %w", err)
+		}
+		return nil
+	}
+
+	return errPantryChanged
+}
+
+// - MARK: Helper Functions
+
+// applies the changes to a copy of the pantry. New items get their ID here,
+// so later changes can use them like any other item.
+func changePantryItems(userID string, pantry []PantryItem, changes []PantryChange) ([]PantryItem, error) {
+	now := time.Now().UTC()
+	changed := append([]PantryItem{}, pantry...)
+	for _, change := range changes {
+		if !change.Stock {
+			used, emptied, _ := UsePantryItems(changed, change.Name, change.Quantity)
+			changed = updatePantryItems(changed, used, emptied)
+			continue
+		}
+
+		item := StockPantry(changed, change.Name, change.Quantity)
+		if item.ID != "" {
+			changed = updatePantryItems(changed, []PantryItem{item}, nil)
+			continue
+		}
+
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("error generating UUID: %w", err)
+		}
+		item.ID = id.String()
+		item.UserID = userID
+		item.CreatedAt = now
+		item.UpdatedAt = now
+		changed = append(changed, item)
+	}
+	return changed, nil
+}
+
+// the transaction turning pantry into changed. Every write is conditional on
+// the item being as it was read, by it's updatedAt.
+func pantryWrites(pantry []PantryItem, changed []PantryItem) ([]*dynamodb.TransactWriteItem, error) {
+	now := time.Now().UTC()
+	before := map[string]PantryItem{}
+	for _, item := range pantry {
+		before[item.ID] = item
+	}
+
+	writes := []*dynamodb.TransactWriteItem{}
+	for _, item := range changed {
+		old, ok := before[item.ID]
+		delete(before, item.ID)
+		if ok && old.Quantity == item.Quantity && old.Unit == item.Unit {
+			continue
+		}
+
+		put := &dynamodb.Put{
+			TableName:           aws.String(PantryTable),
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		}
+		if ok {
+			item.UpdatedAt = now
+			values, err := unchangedSince(old)
+			if err != nil {
+				return nil, err
+			}
+			put.ConditionExpression = aws.String("#updatedAt = :updatedAt")
+			put.ExpressionAttributeNames = map[string]*string{"#updatedAt": aws.String("updatedAt")}
+			put.ExpressionAttributeValues = values
+		}
+
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling pantry item: %w", err)
+		}
+		put.Item = av
+		writes = append(writes, &dynamodb.TransactWriteItem{Put: put})
+	}
+
+	// whatever's left ran out
+	for _, old := range pantry {
+		if _, ok := before[old.ID]; !ok {
+			continue
+		}
+		values, err := unchangedSince(old)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName: aws.String(PantryTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {S: aws.String(old.ID)},
+			},
+			ConditionExpression:       aws.String("#updatedAt = :updatedAt"),
+			ExpressionAttributeNames:  map[string]*string{"#updatedAt": aws.String("updatedAt")},
+			ExpressionAttributeValues: values,
+		}})
+	}
+
+	return writes, nil
+}
+
+// the values for a "#updatedAt = :updatedAt" condition, that the item
+// hasn't been updated since it was read
+func unchangedSince(item PantryItem) (map[string]*dynamodb.AttributeValue, error) {
+	updatedAt, err := dynamodbattribute.Marshal(item.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling pantry item: %w", err)
+	}
+	return map[string]*dynamodb.AttributeValue{":updatedAt": updatedAt}, nil
+}
+
+// the pantry items for an ingredient, soonest to expire first
+func matchingPantryItems(pantry []PantryItem, name string) []PantryItem {
+	key := ingredient.Normalize(name)
+	matches := []PantryItem{}
+	for _, item := range pantry {
+		if ingredient.Normalize(item.Name) == key {
+			matches = append(matches, item)
+		}
+	}
+	sortPantryItems(matches)
+	return matches
+}
+
+// orders items by expiry, items that don't expire last
+func sortPantryItems(items []PantryItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ExpiresOn == "" || items[j].ExpiresOn == "" {
+			return items[j].ExpiresOn == "" && items[i].ExpiresOn != ""
+		}
+		if items[i].ExpiresOn != items[j].ExpiresOn {
+			return items[i].ExpiresOn < items[j].ExpiresOn
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+}
+
+func updatePantryItems(pantry []PantryItem, changed []PantryItem, emptied []PantryItem) []PantryItem {
+	updated := []PantryItem{}
+	for _, item := range pantry {
+		keep := true
+		for _, empty := range emptied {
+			keep = keep && empty.ID != item.ID
+		}
+		for _, change := range changed {
+			if change.ID == item.ID {
+				item = change
+			}
+		}
+		if keep {
+			updated = append(updated, item)
+		}
+	}
+	return updated
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/slichlyter12/thyme-apiserver/backends/database/dynamotest"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// interruptedPantry runs interrupt before the first pantry transaction, like
+// someone else editing the pantry after it was read
+type interruptedPantry struct {
+	*dynamotest.Client
+	interrupt func()
+}
+
+func (service *interruptedPantry) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	if service.interrupt != nil {
+		interrupt := service.interrupt
+		service.interrupt = nil
+		interrupt()
+	}
+	return service.Client.TransactWriteItems(input)
+}
+
+func TestUsePantryItems(t *testing.T) {
+	pantry := []PantryItem{
+		{ID: "later", Name: "Milk", Quantity: 2, Unit: "cup", ExpiresOn: "2026-10-30"},
+		{ID: "sooner", Name: "milk", Quantity: 1, Unit: "cup", ExpiresOn: "2026-10-21"},
+		{ID: "other", Name: "flour", Quantity: 1, Unit: "kg"},
+	}
+
+	needed, _ := ingredient.Parse("1 1/2 cups")
+	changed, emptied, stillNeeded := UsePantryItems(pantry, "milk", needed)
+
+	if len(emptied) != 1 || emptied[0].ID != "sooner" {
+		t.Errorf("Expected the milk expiring sooner to be used up, got %+v", emptied)
+	}
+	if len(changed) != 1 || changed[0].ID != "later" || changed[0].Amount().String() != "1 1/2 cups" {
+		t.Errorf("Expected 1 1/2 cups of the later milk left, got %+v", changed)
+	}
+	if stillNeeded.Amount != 0 {
+		t.Errorf("Expected nothing still needed, got %+v", stillNeeded)
+	}
+}
+
+func TestStockPantry(t *testing.T) {
+	pantry := []PantryItem{
+		{ID: "butter", Name: "butter", Quantity: 2, Unit: "tbsp"},
+	}
+
+	quarter, _ := ingredient.Parse("1/4 cup")
+	item := StockPantry(pantry, "Butter", quarter)
+	if item.ID != "butter" || item.Amount().String() != "6 tbsp" {
+		t.Errorf("Expected to add to the existing butter, got %+v", item)
+	}
+
+	item = StockPantry(pantry, "eggs", ingredient.NewQuantity(12, ""))
+	if item.ID != "" || item.Quantity != 12 {
+		t.Errorf("Expected a new pantry item, got %+v", item)
+	}
+}
+
+func TestExcludePantryItems(t *testing.T) {
+	items := []ShoppingItem{
+		{Name: "butter", Amount: "6 tbsp"},
+		{Name: "flour", Amount: "2 cups"},
+		{Name: "salt", Amount: "to taste"},
+		{Name: "eggs", Amount: "4"},
+		{Name: "flour", Amount: "1 cup", Manual: true},
+	}
+	pantry := []PantryItem{
+		{ID: "1", Name: "butter", Quantity: 2, Unit: "tbsp"},
+		{ID: "2", Name: "flour", Quantity: 5, Unit: "cups"},
+		{ID: "3", Name: "salt"},
+	}
+
+	needed := ExcludePantryItems(items, pantry)
+
+	if len(needed) != 3 {
+		t.Fatalf("Expected butter, eggs and the manual flour, got %+v", needed)
+	}
+	if needed[0].Name != "butter" || needed[0].Amount != "1/4 cup" {
+		t.Errorf("Expected 1/4 cup butter still needed, got %+v", needed[0])
+	}
+	if needed[1].Name != "eggs" || needed[1].Amount != "4" {
+		t.Errorf("Expected eggs untouched, got %+v", needed[1])
+	}
+	if !needed[2].Manual {
+		t.Errorf("Expected manual items to be kept, got %+v", needed[2])
+	}
+}
+
+func TestChangePantry(t *testing.T) {
+	// one item per page, so listing has to follow LastEvaluatedKey
+	mockClient := NewWithService(&dynamotest.Client{ScanPageSize: 1})
+	mockClient.SavePantryItem(PantryItem{UserID: "sam", Name: "butter", Quantity: 4, Unit: "tbsp"})
+	mockClient.SavePantryItem(PantryItem{UserID: "sam", Name: "eggs", Quantity: 2})
+	mockClient.SavePantryItem(PantryItem{UserID: "gran", Name: "eggs", Quantity: 6})
+
+	tbsp, _ := ingredient.Parse("1 tbsp")
+	err := mockClient.ChangePantry("sam", []PantryChange{
+		{Name: "butter", Quantity: tbsp},
+		{Name: "eggs", Quantity: ingredient.NewQuantity(2, "")},
+		{Name: "flour", Quantity: ingredient.NewQuantity(1, "kg"), Stock: true},
+		{Name: "flour", Quantity: ingredient.NewQuantity(500, "g")},
+	})
+	if err != nil {
+		t.Fatalf("Error changing pantry: %s", err.Error())
+	}
+
+	pantry, _ := mockClient.ListPantryItems("sam")
+	amounts := map[string]string{}
+	for _, item := range pantry {
+		amounts[item.Name] = item.Amount().String()
+	}
+	if len(amounts) != 2 || amounts["butter"] != "3 tbsp" || amounts["flour"] != "500 g" {
+		t.Errorf("Expected 3 tbsp of butter and 500 g of flour, got %+v", pantry)
+	}
+	other, _ := mockClient.ListPantryItems("gran")
+	if len(other) != 1 || other[0].Quantity != 6 {
+		t.Errorf("Expected someone else's eggs left alone, got %+v", other)
+	}
+}
+
+func TestChangePantryFromStaleReads(t *testing.T) {
+	service := &interruptedPantry{Client: &dynamotest.Client{}}
+	mockClient := NewWithService(service)
+	butter, _ := mockClient.SavePantryItem(PantryItem{UserID: "sam", Name: "butter", Quantity: 4, Unit: "tbsp"})
+
+	// someone else uses a tablespoon after the pantry is read
+	service.interrupt = func() {
+		used := *butter
+		used.Quantity = 3
+		err := mockClient.UpdatePantryItem(used, used.ID)
+		if err != nil {
+			t.Fatalf("Error updating pantry item: %s", err.Error())
+		}
+	}
+
+	tbsp, _ := ingredient.Parse("1 tbsp")
+	err := mockClient.ChangePantry("sam", []PantryChange{{Name: "butter", Quantity: tbsp}})
+	if err != nil {
+		t.Fatalf("Error changing pantry: %s", err.Error())
+	}
+
+	pantry, _ := mockClient.ListPantryItems("sam")
+	if len(pantry) != 1 || pantry[0].Quantity != 2 {
+		t.Errorf("Expected both tablespoons to be taken out, got %+v", pantry)
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// Price is what an ingredient costs at a store, in the household's catalog.
+// Per is the amount the price buys, like "1 lb" or "12" for a dozen eggs.
+type Price struct {
+	ID string `json:"id"`
+	// HouseholdID is the user's ID when they aren't in a household
+	HouseholdID string    `json:"householdId"`
+	Ingredient  string    `json:"ingredient"`
+	Price       float64   `json:"price"`
+	Per         string    `json:"per"`
+	Store       string    `json:"store,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CostOf is what quantity of the ingredient costs at this price. It reports
+// false when they can't be compared, like a price per pound and a cup.
+func (price Price) CostOf(quantity ingredient.Quantity) (float64, bool) {
+	per, ok := ingredient.Parse(price.Per)
+	if !ok || per.Base() <= 0 || per.Dimension != quantity.Dimension {
+		return 0, false
+	}
+	if per.Dimension == ingredient.Count && per.Unit != quantity.Unit {
+		return 0, false
+	}
+	return price.Price * quantity.Base() / per.Base(), true
+}
+
+// SavePrice adds a price to a household's catalog
+func (client *Client) SavePrice(price Price) (*Price, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	price.ID = id.String()
+	price.CreatedAt = time.Now().UTC()
+	price.UpdatedAt = price.CreatedAt
+
+	err = client.putPrice(price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// UpdatePrice replaces an existing price
+func (client *Client) UpdatePrice(price Price, priceID string) error {
+	price.ID = priceID
+	price.UpdatedAt = time.Now().UTC()
+	return client.putPrice(price)
+}
+
+func (client *Client) putPrice(price Price) error {
+	av, err := dynamodbattribute.MarshalMap(price)
+	if err != nil {
+		return fmt.Errorf("error marshalling price: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(PriceTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving price: %w", err)
+	}
+
+	return nil
+}
+
+// GetPrice fetches a price by it's ID
+func (client *Client) GetPrice(id string) (*Price, error) {
+	var price *Price
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(PriceTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find price with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &price)
+	if err != nil {
+		return nil, err
+	}
+
+	return price, nil
+}
+
+// ListPrices returns a household's price catalog, sorted by ingredient
+func (client *Client) ListPrices(householdID string) ([]Price, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(PriceTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := []Price{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &prices)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := []Price{}
+	for _, price := range prices {
+		if price.HouseholdID == householdID {
+			catalog = append(catalog, price)
+		}
+	}
+
+	sort.Slice(catalog, func(i, j int) bool {
+		if !strings.EqualFold(catalog[i].Ingredient, catalog[j].Ingredient) {
+			return strings.ToLower(catalog[i].Ingredient) < strings.ToLower(catalog[j].Ingredient)
+		}
+		return catalog[i].Store < catalog[j].Store
+	})
+	return catalog, nil
+}
+
+// DeletePrice deletes a price given it's ID
+func (client *Client) DeletePrice(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(PriceTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
@@ -1,5 +1,12 @@
 package database
 
+import (
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+	"github.com/slichlyter12/thyme-apiserver/timing"
+)
+
 // Recipe that users can create
 type Recipe struct {
 	ID          string            `json:"id"`
@@ -10,4 +17,207 @@ type Recipe struct {
 	ImageName   string            `json:"imageName"`
 	Ingredients map[string]string `json:"ingredients"`
 	Steps       []string          `json:"steps"`
+	Servings    int               `json:"servings,omitempty"`
+	// Components makes some of the Ingredients other recipes, keyed by the
+	// ingredient's name
+	Components map[string]Component `json:"components,omitempty"`
+	// Tags are normalized with NormalizeTags when the recipe is saved
+	Tags []string `json:"tags,omitempty"`
+	// CategoryIDs place the recipe in the category taxonomy
+	CategoryIDs []string `json:"categoryIds,omitempty"`
+	// OwnerID is the user who created the recipe, and ParentID the recipe
+	// it was forked from, as it was at ParentRevision
+	OwnerID        string `json:"ownerId,omitempty"`
+	ParentID       string `json:"parentId,omitempty"`
+	ParentRevision int    `json:"parentRevision,omitempty"`
+	// Revision goes up by one every time the recipe is updated
+	Revision int `json:"revision"`
+	// PrepTime and CookTime are in minutes
+	PrepTime int `json:"prepTime,omitempty"`
+	CookTime int `json:"cookTime,omitempty"`
+	// StepDetails optionally annotates Steps with rich text and media
+	StepDetails []StepDetail `json:"stepDetails,omitempty"`
+	// StepTimings are read from Steps whenever the recipe is saved, with
+	// StepTimings[i] for Steps[i]
+	StepTimings []timing.Step `json:"stepTimings,omitempty"`
+	// ActiveTime and PassiveTime add up the step timings, and TotalTime is
+	// PrepTime and CookTime, or the step timings when those aren't set. All
+	// are in minutes.
+	ActiveTime  int `json:"activeTime,omitempty"`
+	PassiveTime int `json:"passiveTime,omitempty"`
+	TotalTime   int `json:"totalTime,omitempty"`
+	// StepIngredients are the ingredients each step uses, with
+	// StepIngredients[i] for Steps[i]. They're linked from the steps'
+	// text whenever the recipe is saved, unless set by hand in StepDetails.
+	StepIngredients [][]ingredient.Mention `json:"stepIngredients,omitempty"`
+	// Image is managed by the image upload endpoints
+	Image *RecipeImage `json:"image,omitempty"`
+	// Allergens and Diets are worked out from the ingredients whenever the
+	// recipe is saved, with LabelOverrides applied on top
+	Allergens []string `json:"allergens"`
+	Diets     []string `json:"diets"`
+	// UnknownIngredients aren't in the ingredient taxonomy, so the labels
+	// can't vouch for them
+	UnknownIngredients []string        `json:"unknownIngredients,omitempty"`
+	LabelOverrides     *LabelOverrides `json:"labelOverrides,omitempty"`
+	// RatingCount and RatingTotal are kept up to date by reviews, and
+	// AverageRating is worked out from them when the recipe is read
+	RatingCount   int     `json:"ratingCount"`
+	RatingTotal   int     `json:"-" dynamodbav:"ratingTotal"`
+	AverageRating float64 `json:"averageRating" dynamodbav:"-"`
+}
+
+const (
+	// ImageProcessing images have been uploaded but can't be served yet
+	ImageProcessing = "processing"
+	// ImageReady images have been cleaned and have thumbnails
+	ImageReady = "ready"
+	// ImageFailed images could not be processed
+	ImageFailed = "failed"
+)
+
+// RecipeImage describes an uploaded recipe image and where it's blobs live
+type RecipeImage struct {
+	// Version changes with every upload so caches can tell images apart
+	Version     string    `json:"version"`
+	Status      string    `json:"status"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+	// BlurHash and DominantColor let clients draw a placeholder before the
+	// image loads
+	BlurHash      string `json:"blurHash,omitempty"`
+	DominantColor string `json:"dominantColor,omitempty"`
+	// IncomingKey holds the upload as received until it has been processed
+	IncomingKey string `json:"-" dynamodbav:"incomingKey"`
+	// OriginalKey and Thumbnails are blob store keys, thumbnails are keyed
+	// by size name
+	OriginalKey string            `json:"-" dynamodbav:"originalKey"`
+	Thumbnails  map[string]string `json:"-" dynamodbav:"thumbnails"`
+	// Sizes lists the thumbnail sizes that can be requested
+	Sizes []string `json:"sizes"`
+}
+
+// Keys returns every blob key used by the image
+func (image RecipeImage) Keys() []string {
+	keys := []string{}
+	for _, key := range []string{image.IncomingKey, image.OriginalKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range image.Thumbnails {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StepDetail carries the optional extras for a step. Recipe.StepDetails[i]
+// describes Recipe.Steps[i], so Steps stays plain text for older clients.
+type StepDetail struct {
+	// Body is an optional Markdown version of the step's text
+	Body  string      `json:"body,omitempty"`
+	Media []StepMedia `json:"media,omitempty"`
+	// Ingredients replace the ones linked from the step's text once
+	// IngredientsSet, even when there are none
+	Ingredients    []ingredient.Mention `json:"ingredients,omitempty"`
+	IngredientsSet bool                 `json:"ingredientsSet,omitempty"`
+}
+
+const (
+	// MediaImage is an image uploaded to the blob store
+	MediaImage = "image"
+	// MediaVideo is a reference to a video hosted elsewhere
+	MediaVideo = "video"
+)
+
+// StepMedia is a photo or video showing what a step looks like
+type StepMedia struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Caption string `json:"caption,omitempty"`
+	// URL is only set for videos
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// Key is the blob store key for images
+	Key string `json:"-" dynamodbav:"key"`
+}
+
+// BlobKeys returns every blob key the recipe references
+func (recipe Recipe) BlobKeys() []string {
+	keys := []string{}
+	if recipe.Image != nil {
+		keys = append(keys, recipe.Image.Keys()...)
+	}
+	for _, detail := range recipe.StepDetails {
+		for _, media := range detail.Media {
+			if media.Key != "" {
+				keys = append(keys, media.Key)
+			}
+		}
+	}
+	return keys
+}
+
+// PreserveManagedFields copies the fields clients can't set from the stored
+// version of the recipe. Uploaded step media is matched up by ID so it can be
+// reordered between steps, media that can't be matched is dropped.
+func (recipe *Recipe) PreserveManagedFields(old Recipe) {
+	recipe.Image = old.Image
+	recipe.RatingCount, recipe.RatingTotal = old.RatingCount, old.RatingTotal
+	recipe.OwnerID, recipe.ParentID, recipe.ParentRevision = old.OwnerID, old.ParentID, old.ParentRevision
+	recipe.Revision = old.Revision
+
+	uploaded := map[string]StepMedia{}
+	for _, detail := range old.StepDetails {
+		for _, media := range detail.Media {
+			if media.Type == MediaImage {
+				uploaded[media.ID] = media
+			}
+		}
+	}
+
+	if len(recipe.StepDetails) > len(recipe.Steps) {
+		recipe.StepDetails = recipe.StepDetails[:len(recipe.Steps)]
+	}
+	for i, detail := range recipe.StepDetails {
+		media := []StepMedia{}
+		for _, m := range detail.Media {
+			if m.Type == MediaVideo {
+				media = append(media, m)
+				continue
+			}
+
+			stored, ok := uploaded[m.ID]
+			if !ok {
+				continue
+			}
+			stored.Caption = m.Caption
+			media = append(media, stored)
+			// each upload can only be used once
+			delete(uploaded, m.ID)
+		}
+		recipe.StepDetails[i].Media = media
+	}
+}
+
+// OrphanedBlobKeys returns the keys referenced by old that recipe no longer
+// references
+func (recipe Recipe) OrphanedBlobKeys(old Recipe) []string {
+	current := map[string]bool{}
+	for _, key := range recipe.BlobKeys() {
+		current[key] = true
+	}
+
+	orphaned := []string{}
+	for _, key := range old.BlobKeys() {
+		if !current[key] {
+			orphaned = append(orphaned, key)
+		}
+	}
+	return orphaned
 }
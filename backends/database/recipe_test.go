@@ -0,0 +1,40 @@
+package database
+
+import "testing"
+
+func TestPreserveManagedFields(t *testing.T) {
+	old := Recipe{
+		Steps: []string{"Cream the butter", "Fold in the flour"},
+		StepDetails: []StepDetail{
+			{Media: []StepMedia{{ID: "creamed", Type: MediaImage, Key: "recipes/1/steps/creamed.jpg"}}},
+			{Media: []StepMedia{{ID: "folded", Type: MediaImage, Key: "recipes/1/steps/folded.jpg"}}},
+		},
+	}
+
+	// the client moves one photo to the other step, drops the other and tries
+	// to sneak in an upload that doesn't exist
+	updated := Recipe{
+		Steps: []string{"Cream the butter", "Fold in the flour"},
+		StepDetails: []StepDetail{
+			{Media: []StepMedia{{ID: "made-up", Type: MediaImage}}},
+			{Media: []StepMedia{
+				{ID: "creamed", Type: MediaImage, Caption: "like this"},
+				{ID: "video", Type: MediaVideo, URL: "https://example.com/fold.mp4"},
+			}},
+		},
+	}
+	updated.PreserveManagedFields(old)
+
+	if len(updated.StepDetails[0].Media) != 0 {
+		t.Errorf("Kept media that was never uploaded: %+v", updated.StepDetails[0].Media)
+	}
+	moved := updated.StepDetails[1].Media
+	if len(moved) != 2 || moved[0].Key != "recipes/1/steps/creamed.jpg" || moved[0].Caption != "like this" {
+		t.Errorf("Media was not moved between steps: %+v", moved)
+	}
+
+	orphaned := updated.OrphanedBlobKeys(old)
+	if len(orphaned) != 1 || orphaned[0] != "recipes/1/steps/folded.jpg" {
+		t.Errorf("Wrong orphaned blobs: %v", orphaned)
+	}
+}
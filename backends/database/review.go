@@ -0,0 +1,237 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// MaxRating is the most stars a review can give, the least is one
+const MaxRating = 5
+
+// reviewNamespace seeds the name based UUIDs used for review IDs
+var reviewNamespace = uuid.MustParse("0e6b2a4c-8f3d-4d1e-a7c2-3b9f51d6e8a4")
+
+// Review is a user's star rating of a recipe, and optionally what they
+// thought of it. Each user has one review per recipe.
+type Review struct {
+	ID        string    `json:"id"`
+	RecipeID  string    `json:"recipeId"`
+	UserID    string    `json:"userId"`
+	Rating    int       `json:"rating"`
+	Text      string    `json:"text,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ReviewID derives the ID of a user's review of a recipe, so saving a review
+// twice edits it rather than adding another
+func ReviewID(recipeID string, userID string) string {
+	return uuid.NewSHA1(reviewNamespace, []byte(recipeID+"\x00"+userID)).String()
+}
+
+// SaveReview saves the user's review of a recipe, replacing their earlier
+// one if there is one, and updates the recipe's ratings. Created is false
+// when an earlier review was replaced.
+func (client *Client) SaveReview(review Review) (saved *Review, created bool, err error) {
+	review.ID = ReviewID(review.RecipeID, review.UserID)
+	review.UpdatedAt = time.Now().UTC()
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = review.UpdatedAt
+	}
+
+	av, err := dynamodbattribute.MarshalMap(review)
+	if err != nil {
+		return nil, false, fmt.Errorf("error marshalling review: %w", err)
+	}
+
+	result, err := client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:         av,
+		TableName:    aws.String(ReviewTable),
+		ReturnValues: aws.String("ALL_OLD"),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("error saving review: %w", err)
+	}
+
+	// the replaced review comes back with the put, so concurrent edits can't
+	// count the same rating twice
+	count, total := 1, review.Rating
+	if len(result.Attributes) > 0 {
+		var old Review
+		err = dynamodbattribute.UnmarshalMap(result.Attributes, &old)
+		if err != nil {
+			return nil, false, err
+		}
+		count, total = 0, review.Rating-old.Rating
+	}
+
+	err = client.addRecipeRating(review.RecipeID, count, total)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &review, count == 1, nil
+}
+
+// GetReview fetches a review by it's ID
+func (client *Client) GetReview(id string) (*Review, error) {
+	var review *Review
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(ReviewTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find review with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &review)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// ListReviews returns a recipe's reviews, newest first
+func (client *Client) ListReviews(recipeID string) ([]Review, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(ReviewTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := []Review{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &reviews)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeReviews := []Review{}
+	for _, review := range reviews {
+		if review.RecipeID == recipeID {
+			recipeReviews = append(recipeReviews, review)
+		}
+	}
+
+	sort.Slice(recipeReviews, func(i, j int) bool {
+		return recipeReviews[i].CreatedAt.After(recipeReviews[j].CreatedAt)
+	})
+	return recipeReviews, nil
+}
+
+// SortRecipesByRating orders recipes best rated first. Ties go to the recipe
+// with more ratings, and unrated recipes come last.
+func SortRecipesByRating(recipes []Recipe) {
+	sort.SliceStable(recipes, func(i, j int) bool {
+		if recipes[i].AverageRating != recipes[j].AverageRating {
+			return recipes[i].AverageRating > recipes[j].AverageRating
+		}
+		return recipes[i].RatingCount > recipes[j].RatingCount
+	})
+}
+
+// DeleteReview deletes a review given it's ID and takes it out of the
+// recipe's ratings
+func (client *Client) DeleteReview(id string) error {
+	result, err := client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(ReviewTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ReturnValues: aws.String("ALL_OLD"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// already deleted
+	if len(result.Attributes) == 0 {
+		return nil
+	}
+
+	var old Review
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &old)
+	if err != nil {
+		return err
+	}
+	return client.addRecipeRating(old.RecipeID, -1, -old.Rating)
+}
+
+// DeleteRecipeReviews deletes every review of a recipe, for when the recipe
+// itself is deleted
+func (client *Client) DeleteRecipeReviews(recipeID string) error {
+	reviews, err := client.ListReviews(recipeID)
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		_, err = client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(ReviewTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {
+					S: aws.String(review.ID),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting review: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// - MARK: Helper Functions
+
+// atomically adds to a recipe's rating count and total
+func (client *Client) addRecipeRating(recipeID string, count int, total int) error {
+	_, err := client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(RecipeTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeID),
+			},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    aws.String("ADD ratingCount :count, ratingTotal :total"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":count": {N: aws.String(strconv.Itoa(count))},
+			":total": {N: aws.String(strconv.Itoa(total))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating recipe rating: %w", err)
+	}
+
+	return nil
+}
+
+// works out the average rating, to one decimal place
+func (recipe *Recipe) averageRating() {
+	recipe.AverageRating = 0
+	if recipe.RatingCount > 0 {
+		recipe.AverageRating = math.Round(float64(recipe.RatingTotal)/float64(recipe.RatingCount)*10) / 10
+	}
+}
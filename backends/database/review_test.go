@@ -0,0 +1,78 @@
+package database
+
+import "testing"
+
+func TestReviewRatings(t *testing.T) {
+	mockClient := newMockClient()
+	recipe, _ := mockClient.SaveRecipe(Recipe{Name: "Snickerdoodle Cookies", RatingCount: 100})
+
+	_, created, err := mockClient.SaveReview(Review{RecipeID: recipe.ID, UserID: "gran", Rating: 5})
+	if err != nil || !created {
+		t.Fatalf("Expected a new review, got %v", err)
+	}
+	mockClient.SaveReview(Review{RecipeID: recipe.ID, UserID: "kid", Rating: 2, Text: "too sweet"})
+
+	// changing your mind edits your review rather than adding another
+	_, created, _ = mockClient.SaveReview(Review{RecipeID: recipe.ID, UserID: "kid", Rating: 4})
+	if created {
+		t.Error("Expected the second review by the same user to replace the first")
+	}
+
+	rated, _ := mockClient.GetRecipe(recipe.ID)
+	if rated.RatingCount != 2 || rated.AverageRating != 4.5 {
+		t.Errorf("Expected 2 ratings averaging 4.5, got %d averaging %v", rated.RatingCount, rated.AverageRating)
+	}
+
+	err = mockClient.DeleteReview(ReviewID(recipe.ID, "gran"))
+	if err != nil {
+		t.Fatalf("Error deleting review: %s", err.Error())
+	}
+	rated, _ = mockClient.GetRecipe(recipe.ID)
+	if rated.RatingCount != 1 || rated.AverageRating != 4 {
+		t.Errorf("Expected 1 rating of 4 after deleting, got %d averaging %v", rated.RatingCount, rated.AverageRating)
+	}
+
+	// editing the recipe keeps it's ratings
+	edited := Recipe{Name: "Snickerdoodles"}
+	edited.PreserveManagedFields(*rated)
+	mockClient.UpdateRecipe(edited, recipe.ID)
+	rated, _ = mockClient.GetRecipe(recipe.ID)
+	if rated.RatingCount != 1 || rated.AverageRating != 4 {
+		t.Errorf("Expected editing the recipe to keep it's ratings, got %d averaging %v", rated.RatingCount, rated.AverageRating)
+	}
+}
+
+func TestEditingKeepsReviewsLeftSinceItWasRead(t *testing.T) {
+	mockClient := newMockClient()
+	recipe, _ := mockClient.SaveRecipe(Recipe{Name: "Shortbread", Steps: []string{"Bake"}})
+
+	// a review comes in while the recipe is being edited
+	read, _ := mockClient.GetRecipe(recipe.ID)
+	mockClient.SaveReview(Review{RecipeID: recipe.ID, UserID: "gran", Rating: 5})
+	read.Steps = append(read.Steps, "Cool")
+	err := mockClient.ReplaceRecipe(*read, read.ID, read.Revision)
+	if err != nil {
+		t.Fatalf("Error replacing recipe: %s", err.Error())
+	}
+
+	rated, _ := mockClient.GetRecipe(recipe.ID)
+	if rated.RatingCount != 1 || rated.AverageRating != 5 || len(rated.Steps) != 2 {
+		t.Errorf("Expected the edit and the review, got %d ratings averaging %v and steps %v", rated.RatingCount, rated.AverageRating, rated.Steps)
+	}
+}
+
+func TestSortRecipesByRating(t *testing.T) {
+	recipes := []Recipe{
+		{Name: "Unrated"},
+		{Name: "Good", AverageRating: 4, RatingCount: 1},
+		{Name: "Popular", AverageRating: 4, RatingCount: 10},
+		{Name: "Best", AverageRating: 5, RatingCount: 1},
+	}
+
+	SortRecipesByRating(recipes)
+	for i, name := range []string{"Best", "Popular", "Good", "Unrated"} {
+		if recipes[i].Name != name {
+			t.Errorf("Expected %s at %d, got %s", name, i, recipes[i].Name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// MaxRecipeRevisions is how many past revisions of each recipe are kept to
+// merge stale edits against
+const MaxRecipeRevisions = 20
+
+// ErrRecipeChanged is returned when a recipe was saved by someone else
+// since the revision an edit was based on
+var ErrRecipeChanged = errors.New("recipe has changed since that revision")
+
+// RecipeRevision is a recipe as it was at one revision
+type RecipeRevision struct {
+	// ID is the recipe ID and revision together
+	ID        string    `json:"id"`
+	RecipeID  string    `json:"recipeId"`
+	Revision  int       `json:"revision"`
+	Recipe    Recipe    `json:"recipe"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ReplaceRecipe updates a recipe, but only if it's still at the revision the
+// edit was based on. ErrRecipeChanged is returned if it isn't.
+func (client *Client) ReplaceRecipe(recipe Recipe, recipeID string, revision int) error {
+	recipe.Revision = revision
+	return client.putRecipeRevision(recipe, recipeID)
+}
+
+// GetRecipeRevision fetches a recipe as it was at a revision
+func (client *Client) GetRecipeRevision(recipeID string, revision int) (*Recipe, error) {
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(RecipeRevisionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeRevisionID(recipeID, revision)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find revision " + strconv.Itoa(revision) + " of recipe with id: " + recipeID)
+	}
+
+	var stored RecipeRevision
+	err = dynamodbattribute.UnmarshalMap(result.Item, &stored)
+	if err != nil {
+		return nil, err
+	}
+
+	stored.Recipe.averageRating()
+	return &stored.Recipe, nil
+}
+
+// DeleteRecipeRevisions deletes every stored revision of a recipe, for when
+// the recipe itself is deleted
+func (client *Client) DeleteRecipeRevisions(recipe Recipe) error {
+	for revision := recipe.Revision; revision > 0 && revision > recipe.Revision-MaxRecipeRevisions; revision-- {
+		err := client.deleteRecipeRevision(recipe.ID, revision)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// - MARK: Helper Functions
+
+// saves the next revision of a recipe, and keeps a copy of it. The recipe
+// must still be at the revision it was read at.
+func (client *Client) putRecipeRevision(recipe Recipe, recipeID string) error {
+	expected := recipe.Revision
+	recipe.ID = recipeID
+	recipe.Revision++
+	recipe.Tags = NormalizeTags(recipe.Tags)
+	recipe.ApplyTimings()
+	recipe.ApplyStepIngredients()
+	av, err := dynamodbattribute.MarshalMap(recipe)
+	if err != nil {
+		return fmt.Errorf("error marshalling recipe item: %w", err)
+	}
+
+	// only the recipe's content is written, so an image uploaded or a review
+	// left since the recipe was read isn't overwritten
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(RecipeTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeID),
+			},
+		},
+		ConditionExpression: aws.String("attribute_exists(id) AND #revision = :revision"),
+		ExpressionAttributeNames: map[string]*string{
+			"#revision": aws.String("revision"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":revision": {N: aws.String(strconv.Itoa(expected))},
+		},
+		ReturnValues: aws.String("ALL_OLD"),
+	}
+	// recipes saved before revisions were counted don't have one
+	if expected == 0 {
+		input.ConditionExpression = aws.String("attribute_exists(id) AND (attribute_not_exists(#revision) OR #revision = :revision)")
+	}
+	set, remove := []string{}, []string{}
+	for i, attribute := range recipeContentAttributes() {
+		name := "#a" + strconv.Itoa(i)
+		input.ExpressionAttributeNames[name] = aws.String(attribute)
+		if value, ok := av[attribute]; ok {
+			input.ExpressionAttributeValues[":a"+strconv.Itoa(i)] = value
+			set = append(set, name+" = :a"+strconv.Itoa(i))
+		} else {
+			remove = append(remove, name)
+		}
+	}
+	update := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+	input.UpdateExpression = aws.String(update)
+
+	result, err := client.dbService.UpdateItem(input)
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrRecipeChanged
+	}
+	if err != nil {
+		return fmt.Errorf("error updating recipe: %w", err)
+	}
+
+	// the tags being replaced come back with the update
+	var old Recipe
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &old)
+	if err != nil {
+		return err
+	}
+	err = client.indexRecipeTags(recipe.ID, old.Tags, recipe.Tags)
+	if err != nil {
+		return err
+	}
+
+	return client.saveRecipeRevision(recipe)
+}
+
+// the attributes a recipe's content is stored in, which is all of them but
+// it's ID, the image the upload endpoints manage and the ratings reviews keep
+// up to date
+func recipeContentAttributes() []string {
+	recipeAttributesOnce.Do(func() {
+		recipeType := reflect.TypeOf(Recipe{})
+		for i := 0; i < recipeType.NumField(); i++ {
+			field := recipeType.Field(i)
+			name, ok := field.Tag.Lookup("dynamodbav")
+			if !ok {
+				name = field.Tag.Get("json")
+			}
+			name = strings.Split(name, ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			if name == "-" || managedRecipeAttributes[name] {
+				continue
+			}
+			recipeAttributes = append(recipeAttributes, name)
+		}
+	})
+	return recipeAttributes
+}
+
+var (
+	recipeAttributes     []string
+	recipeAttributesOnce sync.Once
+)
+
+// attributes content writes leave alone, because they're changed on their
+// own and a recipe that was read before they changed would undo it
+var managedRecipeAttributes = map[string]bool{
+	"id": true, "image": true, "ratingCount": true, "ratingTotal": true,
+}
+
+func (client *Client) saveRecipeRevision(recipe Recipe) error {
+	av, err := dynamodbattribute.MarshalMap(RecipeRevision{
+		ID:        recipeRevisionID(recipe.ID, recipe.Revision),
+		RecipeID:  recipe.ID,
+		Revision:  recipe.Revision,
+		Recipe:    recipe,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling recipe revision: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(RecipeRevisionTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving recipe revision: %w", err)
+	}
+
+	// only the latest revisions are kept
+	if recipe.Revision > MaxRecipeRevisions {
+		return client.deleteRecipeRevision(recipe.ID, recipe.Revision-MaxRecipeRevisions)
+	}
+	return nil
+}
+
+func (client *Client) deleteRecipeRevision(recipeID string, revision int) error {
+	_, err := client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(RecipeRevisionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(recipeRevisionID(recipeID, revision)),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting recipe revision: %w", err)
+	}
+	return nil
+}
+
+func recipeRevisionID(recipeID string, revision int) string {
+	return recipeID + "@" + strconv.Itoa(revision)
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionPrefix starts every interactive session token
+	SessionPrefix = "thymesession_"
+	// SessionLifetime is how long a login lasts
+	SessionLifetime = 30 * 24 * time.Hour
+	// LoginLifetime is how long a user has to finish logging in at the
+	// identity provider
+	LoginLifetime = 10 * time.Minute
+)
+
+// ErrInvalidSession is returned when a session token is malformed, unknown or
+// expired
+var ErrInvalidSession = errors.New("invalid session")
+
+// Session is an interactive login. Like API keys, only a hash of the secret is
+// stored.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	SecretHash string    `json:"-" dynamodbav:"secretHash"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Login tracks an in-progress login between redirecting to the identity
+// provider and it redirecting back, keyed by the OAuth state parameter
+type Login struct {
+	ID           string    `json:"id"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"codeVerifier"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// CreateSession logs the user in, returning the plaintext session token
+func (client *Client) CreateSession(userID string) (*Session, string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating UUID: %w", err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	session := Session{
+		ID:         id.String(),
+		UserID:     userID,
+		SecretHash: hashSecret(secret),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(SessionLifetime),
+	}
+
+	av, err := dynamodbattribute.MarshalMap(session)
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshalling session: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(SessionTable),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error saving session: %w", err)
+	}
+
+	return &session, SessionPrefix + session.ID + "_" + secret, nil
+}
+
+// AuthenticateSession resolves a plaintext session token to it's session
+func (client *Client) AuthenticateSession(token string) (*Session, error) {
+	id, secret, ok := parseToken(SessionPrefix, token)
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+
+	var session *Session
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(SessionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil || result.Item == nil {
+		return nil, ErrInvalidSession
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidSession
+	}
+	if subtle.ConstantTimeCompare([]byte(session.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidSession
+	}
+
+	return session, nil
+}
+
+// DeleteSession logs a session out
+func (client *Client) DeleteSession(id string) error {
+	_, err := client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(SessionTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	return err
+}
+
+// - MARK: Login methods
+
+// SaveLogin records an in-progress login
+func (client *Client) SaveLogin(login Login) error {
+	av, err := dynamodbattribute.MarshalMap(login)
+	if err != nil {
+		return fmt.Errorf("error marshalling login: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(LoginTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving login: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeLogin fetches and deletes an in-progress login so each state value
+// can only be used once
+func (client *Client) ConsumeLogin(state string) (*Login, error) {
+	result, err := client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(LoginTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(state),
+			},
+		},
+		ReturnValues: aws.String("ALL_OLD"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Attributes == nil {
+		return nil, errors.New("Could not find login with state: " + state)
+	}
+
+	var login *Login
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &login)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(login.ExpiresAt) {
+		return nil, errors.New("login has expired")
+	}
+
+	return login, nil
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Share is a public, read-only link to a recipe. It's ID is the hash of the
+// token in the link, so the token itself is never stored.
+type Share struct {
+	ID        string     `json:"id"`
+	RecipeID  string     `json:"recipeId"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Expired reports whether the share link can no longer be used
+func (share Share) Expired() bool {
+	return share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt)
+}
+
+// CreateShare mints a share link for the recipe, returning the share and the
+// token to put in the link
+func (client *Client) CreateShare(recipeID string, createdBy string, expiresAt *time.Time) (*Share, string, error) {
+	token, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	share := Share{
+		ID:        hashSecret(token),
+		RecipeID:  recipeID,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+
+	av, err := dynamodbattribute.MarshalMap(share)
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshalling share: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(ShareTable),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error saving share: %w", err)
+	}
+
+	return &share, token, nil
+}
+
+// GetShareByToken resolves the token from a share link, failing if the link
+// has been revoked or has expired
+func (client *Client) GetShareByToken(token string) (*Share, error) {
+	var share *Share
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(ShareTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(hashSecret(token)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find share")
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &share)
+	if err != nil {
+		return nil, err
+	}
+
+	if share.Expired() {
+		return nil, errors.New("share has expired")
+	}
+
+	return share, nil
+}
+
+// ListShares returns the share links for a recipe
+func (client *Client) ListShares(recipeID string) ([]Share, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(ShareTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shares := []Share{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &shares)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeShares := []Share{}
+	for _, share := range shares {
+		if share.RecipeID == recipeID {
+			recipeShares = append(recipeShares, share)
+		}
+	}
+
+	return recipeShares, nil
+}
+
+// DeleteRecipeShares revokes every share link for a recipe, for when the
+// recipe itself is deleted
+func (client *Client) DeleteRecipeShares(recipeID string) error {
+	shares, err := client.ListShares(recipeID)
+	if err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		err = client.DeleteShare(share.ID)
+		if err != nil {
+			return fmt.Errorf("error deleting share link: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteShare revokes a share link given it's ID
+func (client *Client) DeleteShare(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(ShareTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetShareByToken(t *testing.T) {
+	mockClient := newMockClient()
+
+	share, token, err := mockClient.CreateShare("recipe-id", "sam", nil)
+	if err != nil {
+		t.Fatalf("Error creating share: %s", err.Error())
+	}
+	if share.ID == token {
+		t.Errorf("Share token stored in plaintext")
+	}
+
+	found, err := mockClient.GetShareByToken(token)
+	if err != nil {
+		t.Fatalf("Error getting share: %s", err.Error())
+	}
+	if found.RecipeID != "recipe-id" {
+		t.Errorf("Found wrong share: %+v", found)
+	}
+
+	err = mockClient.DeleteShare(share.ID)
+	if err != nil {
+		t.Fatalf("Error deleting share: %s", err.Error())
+	}
+	_, err = mockClient.GetShareByToken(token)
+	if err == nil {
+		t.Error("Found revoked share")
+	}
+}
+
+func TestGetExpiredShare(t *testing.T) {
+	mockClient := newMockClient()
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, token, err := mockClient.CreateShare("recipe-id", "sam", &expiresAt)
+	if err != nil {
+		t.Fatalf("Error creating share: %s", err.Error())
+	}
+
+	_, err = mockClient.GetShareByToken(token)
+	if err == nil {
+		t.Error("Found expired share")
+	}
+}
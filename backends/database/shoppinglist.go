@@ -0,0 +1,249 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// ShoppingList is a list of things to buy, generated from recipes or a
+// stretch of the meal plan and then added to by hand
+type ShoppingList struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	Name   string `json:"name"`
+	// From and To are the meal plan dates the list was generated from
+	From      string         `json:"from,omitempty"`
+	To        string         `json:"to,omitempty"`
+	RecipeIDs []string       `json:"recipeIds,omitempty"`
+	Items     []ShoppingItem `json:"items"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// ShoppingItem is one thing to buy. Amount adds up every recipe that needs
+// the ingredient, like "6 tbsp", joining amounts that can't be added with
+// " + ".
+type ShoppingItem struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Amount  string `json:"amount,omitempty"`
+	Aisle   string `json:"aisle"`
+	Checked bool   `json:"checked"`
+	// Manual items were added by hand rather than generated from a recipe
+	Manual    bool     `json:"manual,omitempty"`
+	RecipeIDs []string `json:"recipeIds,omitempty"`
+}
+
+// RecipePortion is a recipe going into a shopping list, Scale is how many
+// times the recipe is being made
+type RecipePortion struct {
+	Recipe Recipe
+	Scale  float64
+}
+
+// GenerateShoppingItems adds up the ingredients of the recipes, combining the
+// same ingredient across recipes and sorting them by aisle
+func GenerateShoppingItems(portions []RecipePortion) ([]ShoppingItem, error) {
+	type tally struct {
+		item       ShoppingItem
+		quantities []ingredient.Quantity
+		unparsed   []string
+	}
+
+	tallies := map[string]*tally{}
+	keys := []string{}
+	for _, portion := range portions {
+		for name, amount := range portion.Recipe.Ingredients {
+			key := ingredient.Normalize(name)
+			if key == "" {
+				continue
+			}
+
+			t, ok := tallies[key]
+			if !ok {
+				t = &tally{item: ShoppingItem{
+					Name:  strings.TrimSpace(name),
+					Aisle: ingredient.Aisle(name),
+				}}
+				tallies[key] = t
+				keys = append(keys, key)
+			}
+			t.item.RecipeIDs = AppendUnique(t.item.RecipeIDs, portion.Recipe.ID)
+
+			quantity, ok := ingredient.Parse(amount)
+			if !ok {
+				if strings.TrimSpace(amount) != "" {
+					t.unparsed = AppendUnique(t.unparsed, strings.TrimSpace(amount))
+				}
+				continue
+			}
+			if portion.Scale > 0 && portion.Scale != 1 {
+				quantity = quantity.Scale(portion.Scale)
+			}
+
+			added := false
+			for i, existing := range t.quantities {
+				if sum, ok := ingredient.Add(existing, quantity); ok {
+					t.quantities[i] = sum
+					added = true
+					break
+				}
+			}
+			if !added {
+				t.quantities = append(t.quantities, quantity)
+			}
+		}
+	}
+
+	items := []ShoppingItem{}
+	for _, key := range keys {
+		t := tallies[key]
+
+		amounts := []string{}
+		for _, quantity := range t.quantities {
+			amounts = append(amounts, quantity.String())
+		}
+		t.item.Amount = strings.Join(append(amounts, t.unparsed...), " + ")
+
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("error generating UUID: %w", err)
+		}
+		t.item.ID = id.String()
+
+		items = append(items, t.item)
+	}
+
+	SortShoppingItems(items)
+	return items, nil
+}
+
+// SortShoppingItems orders items by aisle and then name
+func SortShoppingItems(items []ShoppingItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Aisle != items[j].Aisle {
+			return ingredient.AisleOrder(items[i].Aisle) < ingredient.AisleOrder(items[j].Aisle)
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+}
+
+// SaveShoppingList saves a new shopping list
+func (client *Client) SaveShoppingList(list ShoppingList) (*ShoppingList, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+	list.ID = id.String()
+	list.CreatedAt = time.Now().UTC()
+
+	err = client.putShoppingList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// UpdateShoppingList replaces an existing shopping list
+func (client *Client) UpdateShoppingList(list ShoppingList, listID string) error {
+	list.ID = listID
+	return client.putShoppingList(list)
+}
+
+func (client *Client) putShoppingList(list ShoppingList) error {
+	av, err := dynamodbattribute.MarshalMap(list)
+	if err != nil {
+		return fmt.Errorf("error marshalling shopping list: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(ShoppingListTable),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving shopping list: %w", err)
+	}
+
+	return nil
+}
+
+// GetShoppingList fetches a shopping list by it's ID
+func (client *Client) GetShoppingList(id string) (*ShoppingList, error) {
+	var list *ShoppingList
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(ShoppingListTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find shopping list with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListShoppingLists returns the user's shopping lists, newest first
+func (client *Client) ListShoppingLists(userID string) ([]ShoppingList, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(ShoppingListTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []ShoppingList{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &lists)
+	if err != nil {
+		return nil, err
+	}
+
+	userLists := []ShoppingList{}
+	for _, list := range lists {
+		if list.UserID == userID {
+			userLists = append(userLists, list)
+		}
+	}
+
+	sort.SliceStable(userLists, func(i, j int) bool {
+		return userLists[i].CreatedAt.After(userLists[j].CreatedAt)
+	})
+
+	return userLists, nil
+}
+
+// DeleteShoppingList deletes a shopping list given it's ID
+func (client *Client) DeleteShoppingList(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(ShoppingListTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
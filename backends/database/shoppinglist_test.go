@@ -0,0 +1,81 @@
+package database
+
+import "testing"
+
+func TestGenerateShoppingItems(t *testing.T) {
+	cookies := Recipe{
+		ID: "cookies",
+		Ingredients: map[string]string{
+			"butter": "2 tbsp",
+			"flour":  "1 cup",
+			"salt":   "to taste",
+		},
+	}
+	cake := Recipe{
+		ID: "cake",
+		Ingredients: map[string]string{
+			"Butter": "1/4 cup",
+			"flour":  "200 g",
+			"eggs":   "2",
+		},
+	}
+
+	items, err := GenerateShoppingItems([]RecipePortion{
+		{Recipe: cookies, Scale: 1},
+		{Recipe: cake, Scale: 2},
+	})
+	if err != nil {
+		t.Fatalf("Error generating shopping items: %s", err.Error())
+	}
+
+	amounts := map[string]string{}
+	aisles := map[string]string{}
+	for _, item := range items {
+		amounts[item.Name] = item.Amount
+		aisles[item.Name] = item.Aisle
+		if item.ID == "" {
+			t.Errorf("Expected %s to have an ID", item.Name)
+		}
+	}
+
+	butter := amounts["butter"] + amounts["Butter"]
+	if butter != "10 tbsp" {
+		t.Errorf("Expected 2 tbsp + 2 x 1/4 cup butter to be 10 tbsp, got %q", butter)
+	}
+	if amounts["flour"] != "1 cup + 400 g" {
+		t.Errorf("Expected flour amounts that can't be added to be joined, got %q", amounts["flour"])
+	}
+	if amounts["eggs"] != "4" {
+		t.Errorf("Expected 4 eggs, got %q", amounts["eggs"])
+	}
+	if amounts["salt"] != "to taste" {
+		t.Errorf("Expected salt to taste, got %q", amounts["salt"])
+	}
+	if len(items) != 4 {
+		t.Errorf("Expected butter to be one item, got %+v", items)
+	}
+
+	// sorted by aisle, dairy before pantry before spices
+	if aisles[items[0].Name] != "Dairy & Eggs" || items[len(items)-1].Name != "salt" {
+		t.Errorf("Items are not sorted by aisle: %+v", items)
+	}
+}
+
+func TestListShoppingLists(t *testing.T) {
+	mockClient := newMockClient()
+
+	for _, userID := range []string{"sam", "gran", "sam"} {
+		_, err := mockClient.SaveShoppingList(ShoppingList{UserID: userID, Name: "Groceries"})
+		if err != nil {
+			t.Fatalf("Error saving shopping list: %s", err.Error())
+		}
+	}
+
+	lists, err := mockClient.ListShoppingLists("sam")
+	if err != nil {
+		t.Fatalf("Error listing shopping lists: %s", err.Error())
+	}
+	if len(lists) != 2 {
+		t.Errorf("Expected sam's 2 lists, got %d", len(lists))
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import "github.com/slichlyter12/thyme-apiserver/ingredient"
+
+// ApplyStepIngredients links each step to the ingredients it uses, from the
+// step's text or as set by hand. Ingredients set by hand that the recipe no
+// longer has are left out.
+func (recipe *Recipe) ApplyStepIngredients() {
+	recipe.StepIngredients = nil
+	found := false
+	for i, text := range recipe.Steps {
+		var mentions []ingredient.Mention
+		if i < len(recipe.StepDetails) && recipe.StepDetails[i].IngredientsSet {
+			mentions = recipe.listedMentions(recipe.StepDetails[i].Ingredients)
+		} else {
+			mentions = ingredient.Mentions(text, recipe.Ingredients)
+		}
+		recipe.StepIngredients = append(recipe.StepIngredients, mentions)
+		found = found || len(mentions) > 0
+	}
+	// recipes without any aren't worth storing
+	if !found {
+		recipe.StepIngredients = nil
+	}
+}
+
+// UnlistedIngredient returns the first name that isn't one of the recipe's
+// ingredients, or false if they all are
+func (recipe Recipe) UnlistedIngredient(mentions []ingredient.Mention) (string, bool) {
+	listed := recipe.listedNames()
+	for _, mention := range mentions {
+		if _, ok := listed[ingredient.Normalize(mention.Name)]; !ok {
+			return mention.Name, true
+		}
+	}
+	return "", false
+}
+
+// - MARK: Helper Functions
+
+// the mentions of ingredients the recipe lists, named as it lists them
+func (recipe Recipe) listedMentions(mentions []ingredient.Mention) []ingredient.Mention {
+	listed := recipe.listedNames()
+	kept := []ingredient.Mention{}
+	for _, mention := range mentions {
+		if name, ok := listed[ingredient.Normalize(mention.Name)]; ok {
+			mention.Name = name
+			kept = append(kept, mention)
+		}
+	}
+	return kept
+}
+
+// the recipe's ingredient names keyed by ingredient.Normalize
+func (recipe Recipe) listedNames() map[string]string {
+	listed := map[string]string{}
+	for name := range recipe.Ingredients {
+		listed[ingredient.Normalize(name)] = name
+	}
+	return listed
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+func TestSaveRecipeStepIngredients(t *testing.T) {
+	mockClient := newMockClient()
+	saved, err := mockClient.SaveRecipe(Recipe{
+		Name:        "Pancakes",
+		Ingredients: map[string]string{"flour": "1 1/2 cups", "milk": "1 cup", "eggs": "1"},
+		Steps:       []string{"Whisk the flour and half the milk", "Heat a pan", "Beat in the egg and the rest of the milk"},
+	})
+	if err != nil {
+		t.Fatalf("Error saving recipe: %s", err.Error())
+	}
+
+	stored, _ := mockClient.GetRecipe(saved.ID)
+	expected := [][]ingredient.Mention{
+		{{Name: "flour", Amount: "1 1/2 cups"}, {Name: "milk", Amount: "1/2 cup"}},
+		{},
+		{{Name: "eggs", Amount: "1"}, {Name: "milk", Amount: "1 cup"}},
+	}
+	if len(stored.StepIngredients) != 3 || !reflect.DeepEqual(stored.StepIngredients[0], expected[0]) ||
+		len(stored.StepIngredients[1]) != 0 || !reflect.DeepEqual(stored.StepIngredients[2], expected[2]) {
+		t.Errorf("Expected %+v, got %+v", expected, stored.StepIngredients)
+	}
+
+	// links set by hand are kept, less ingredients the recipe no longer has
+	stored.StepDetails = []StepDetail{{}, {IngredientsSet: true, Ingredients: []ingredient.Mention{
+		{Name: "Flour", Amount: "2 tbsp"},
+		{Name: "butter", Amount: "1 tbsp"},
+	}}}
+	_, err = mockClient.UpdateRecipe(*stored, stored.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+	updated, _ := mockClient.GetRecipe(saved.ID)
+	if !reflect.DeepEqual(updated.StepIngredients[1], []ingredient.Mention{{Name: "flour", Amount: "2 tbsp"}}) {
+		t.Errorf("Expected the step's ingredients set by hand, got %+v", updated.StepIngredients[1])
+	}
+	if name, ok := updated.UnlistedIngredient(updated.StepDetails[1].Ingredients); !ok || name != "butter" {
+		t.Errorf("Expected butter not to be listed, got %q", name)
+	}
+}
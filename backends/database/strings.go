@@ -0,0 +1,30 @@
+package database
+
+// ContainsString reports whether values has value
+func ContainsString(values []string, value string) bool {
+	for _, other := range values {
+		if other == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendUnique appends value unless values already has it
+func AppendUnique(values []string, value string) []string {
+	if ContainsString(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// RemoveString returns values without any that equal value
+func RemoveString(values []string, value string) []string {
+	kept := []string{}
+	for _, other := range values {
+		if other != value {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// IngredientSubstitutes are the ways one of a recipe's ingredients can be
+// replaced
+type IngredientSubstitutes struct {
+	Ingredient  string                `json:"ingredient"`
+	Amount      string                `json:"amount"`
+	Substitutes []SuggestedSubstitute `json:"substitutes"`
+}
+
+// SuggestedSubstitute is a substitute worked out for the amount the recipe
+// uses. Avoids are the allergens and groups the original has and the
+// substitute doesn't.
+type SuggestedSubstitute struct {
+	Ingredients map[string]string `json:"ingredients"`
+	Notes       string            `json:"notes,omitempty"`
+	Avoids      []string          `json:"avoids"`
+}
+
+// Substitution is a substitute that was applied to a recipe
+type Substitution struct {
+	Ingredient string `json:"ingredient"`
+	Amount     string `json:"amount"`
+	SuggestedSubstitute
+}
+
+// SuggestSubstitutes returns the substitutes for each of the recipe's
+// ingredients that has any, sorted by ingredient
+func SuggestSubstitutes(recipe Recipe) []IngredientSubstitutes {
+	suggestions := []IngredientSubstitutes{}
+	for _, name := range ingredientNames(recipe) {
+		amount := recipe.Ingredients[name]
+
+		substitutes := []SuggestedSubstitute{}
+		for _, substitute := range ingredient.Substitutes(name) {
+			substitutes = append(substitutes, suggestSubstitute(name, amount, substitute))
+		}
+		if len(substitutes) > 0 {
+			suggestions = append(suggestions, IngredientSubstitutes{Ingredient: name, Amount: amount, Substitutes: substitutes})
+		}
+	}
+	return suggestions
+}
+
+// Substitute derives a recipe that keeps to constraints by replacing the
+// ingredients that don't with their first substitute that does. Unresolved
+// are the ingredients nothing could be found for, including any the
+// taxonomy doesn't know. The derived recipe isn't saved, so it has no ID.
+func (recipe Recipe) Substitute(constraints ingredient.Constraints) (derived Recipe, changes []Substitution, unresolved []string) {
+	derived = recipe
+	derived.ID = ""
+	derived.Image = nil
+	derived.LabelOverrides = nil
+	derived.Ingredients = map[string]string{}
+	derived.Steps = append([]string{}, recipe.Steps...)
+	changes, unresolved = []Substitution{}, []string{}
+
+	replaced := map[string]Substitution{}
+	for _, name := range ingredientNames(recipe) {
+		amount := recipe.Ingredients[name]
+		if constraints.Allows(name) {
+			addIngredient(derived.Ingredients, name, amount)
+			continue
+		}
+
+		var change *Substitution
+		for _, substitute := range ingredient.Substitutes(name) {
+			if allows(constraints, substitute) {
+				change = &Substitution{Ingredient: name, Amount: amount, SuggestedSubstitute: suggestSubstitute(name, amount, substitute)}
+				break
+			}
+		}
+		if change == nil {
+			unresolved = append(unresolved, name)
+			addIngredient(derived.Ingredients, name, amount)
+			continue
+		}
+
+		for replacement, replacementAmount := range change.Ingredients {
+			addIngredient(derived.Ingredients, replacement, replacementAmount)
+		}
+		changes = append(changes, *change)
+		replaced[name] = *change
+	}
+
+	for name, change := range replaced {
+		pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(name) + `\b`)
+		replacement := strings.Join(sortedKeys(change.Ingredients), " and ")
+		for i, step := range derived.Steps {
+			derived.Steps[i] = pattern.ReplaceAllLiteralString(step, replacement)
+		}
+	}
+
+	// the recipe can't be called dairy-free while anything might not be
+	if label := constraintsLabel(constraints); label != "" && len(changes) > 0 && len(unresolved) == 0 {
+		derived.Name = recipe.Name + " (" + label + ")"
+	}
+	derived.ApplyLabels()
+	return derived, changes, unresolved
+}
+
+// - MARK: Helper Functions
+
+func suggestSubstitute(name string, amount string, substitute ingredient.Substitute) SuggestedSubstitute {
+	quantity, parsed := ingredient.Parse(amount)
+
+	suggested := SuggestedSubstitute{Ingredients: map[string]string{}, Notes: substitute.Notes, Avoids: []string{}}
+	replacementGroups := map[string]bool{}
+	for _, replacement := range substitute.Ingredients {
+		// amounts like "to taste" carry over as they are
+		replacementAmount := amount
+		if parsed {
+			replacementAmount = replacement.Amount(quantity).String()
+		}
+		addIngredient(suggested.Ingredients, replacement.Name, replacementAmount)
+
+		for _, group := range ingredient.Classify(replacement.Name) {
+			replacementGroups[group] = true
+		}
+	}
+
+	for _, group := range ingredient.Classify(name) {
+		if !replacementGroups[group] {
+			suggested.Avoids = append(suggested.Avoids, group)
+		}
+	}
+	return suggested
+}
+
+func allows(constraints ingredient.Constraints, substitute ingredient.Substitute) bool {
+	for _, replacement := range substitute.Ingredients {
+		if !constraints.Allows(replacement.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// adds an ingredient, adding the amounts together when it's already there
+func addIngredient(ingredients map[string]string, name string, amount string) {
+	existing, ok := ingredients[name]
+	if !ok {
+		ingredients[name] = amount
+		return
+	}
+
+	a, aOK := ingredient.Parse(existing)
+	b, bOK := ingredient.Parse(amount)
+	if aOK && bOK {
+		if sum, ok := ingredient.Add(a, b); ok {
+			ingredients[name] = sum.String()
+			return
+		}
+	}
+	ingredients[name] = existing + " + " + amount
+}
+
+// a name for a recipe made to keep to constraints, like "dairy-free, vegan"
+func constraintsLabel(constraints ingredient.Constraints) string {
+	labels := []string{}
+	for _, allergen := range constraints.Without {
+		labels = append(labels, allergen+"-free")
+	}
+	labels = append(labels, constraints.Diets...)
+	return strings.Join(labels, ", ")
+}
+
+func ingredientNames(recipe Recipe) []string {
+	return sortedKeys(recipe.Ingredients)
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
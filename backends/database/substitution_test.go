@@ -0,0 +1,82 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+func TestSuggestSubstitutes(t *testing.T) {
+	recipe := Recipe{Ingredients: map[string]string{"buttermilk": "2 cups", "saffron": "1 pinch"}}
+
+	suggestions := SuggestSubstitutes(recipe)
+	if len(suggestions) != 1 || suggestions[0].Ingredient != "buttermilk" {
+		t.Fatalf("Expected substitutes for buttermilk only, got %+v", suggestions)
+	}
+
+	first := suggestions[0].Substitutes[0]
+	if !reflect.DeepEqual(first.Ingredients, map[string]string{"milk": "2 cups", "lemon juice": "2 tbsp"}) {
+		t.Errorf("Wrong amounts for buttermilk's substitute: %v", first.Ingredients)
+	}
+	if len(first.Avoids) != 0 {
+		t.Errorf("Milk doesn't avoid anything buttermilk has, got %v", first.Avoids)
+	}
+	if dairyFree := suggestions[0].Substitutes[2]; !reflect.DeepEqual(dairyFree.Avoids, []string{"dairy"}) {
+		t.Errorf("Expected soy milk to avoid dairy, got %v", dairyFree.Avoids)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	recipe := Recipe{
+		ID:   "pancakes",
+		Name: "Pancakes",
+		Ingredients: map[string]string{
+			"flour":      "2 cups",
+			"buttermilk": "2 cups",
+			"eggs":       "2",
+			"saffron":    "1 pinch",
+		},
+		Steps: []string{"Whisk the Buttermilk and eggs", "Fold in the flour"},
+	}
+
+	derived, changes, unresolved := recipe.Substitute(ingredient.Constraints{Without: []string{"dairy"}, Diets: []string{"vegan"}})
+
+	expected := map[string]string{
+		"flour":           "2 cups",
+		"soy milk":        "2 cups",
+		"lemon juice":     "2 tbsp",
+		"ground flaxseed": "2 tbsp",
+		"water":           "6 tbsp",
+		"saffron":         "1 pinch",
+	}
+	if !reflect.DeepEqual(derived.Ingredients, expected) {
+		t.Errorf("Wrong derived ingredients: %v", derived.Ingredients)
+	}
+	if len(changes) != 2 || !reflect.DeepEqual(unresolved, []string{"saffron"}) {
+		t.Errorf("Expected 2 changes and the unknown saffron unresolved, got %+v and %v", changes, unresolved)
+	}
+	if derived.Steps[0] != "Whisk the lemon juice and soy milk and ground flaxseed and water" {
+		t.Errorf("Wrong derived step: %q", derived.Steps[0])
+	}
+	if derived.ID != "" || derived.Name != "Pancakes" {
+		t.Errorf("Expected the derived recipe to keep it's name while saffron is unresolved, got %q %q", derived.ID, derived.Name)
+	}
+	if !reflect.DeepEqual(derived.Diets, []string{"vegetarian", "vegan"}) || recipe.Ingredients["buttermilk"] != "2 cups" {
+		t.Errorf("Expected a vegan recipe that leaves the original alone")
+	}
+	if derived.HasDiet("vegan") {
+		t.Errorf("Expected the saffron to keep the recipe out of vegan filters")
+	}
+
+	delete(recipe.Ingredients, "saffron")
+	derived, _, unresolved = recipe.Substitute(ingredient.Constraints{Without: []string{"dairy"}, Diets: []string{"vegan"}})
+	if len(unresolved) != 0 || derived.Name != "Pancakes (dairy-free, vegan)" || !derived.HasDiet("vegan") {
+		t.Errorf("Expected a vegan recipe named for it, got %q with %v unresolved", derived.Name, unresolved)
+	}
+
+	_, _, unresolved = recipe.Substitute(ingredient.Constraints{Without: []string{"gluten", "nuts"}})
+	if !reflect.DeepEqual(unresolved, []string{}) {
+		t.Errorf("Expected flour to be resolved with gluten-free flour, got %v", unresolved)
+	}
+}
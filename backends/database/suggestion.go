@@ -0,0 +1,88 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// RecipeSuggestion is a recipe that uses up pantry items that are about to
+// expire. Score is higher for recipes using more items, sooner to expire.
+type RecipeSuggestion struct {
+	RecipeID   string       `json:"recipeId"`
+	RecipeName string       `json:"recipeName"`
+	Score      float64      `json:"score"`
+	Uses       []PantryItem `json:"uses"`
+}
+
+// ExpiringPantryItems returns the items expiring between today and days from
+// now, soonest first. Items that have already expired are left out.
+func ExpiringPantryItems(pantry []PantryItem, today time.Time, days int) []PantryItem {
+	from := today.Format(DateFormat)
+	to := today.AddDate(0, 0, days).Format(DateFormat)
+
+	expiring := []PantryItem{}
+	for _, item := range pantry {
+		if item.ExpiresOn != "" && item.ExpiresOn >= from && item.ExpiresOn <= to {
+			expiring = append(expiring, item)
+		}
+	}
+
+	sortPantryItems(expiring)
+	return expiring
+}
+
+// SuggestRecipes ranks the recipes that use the expiring items. Each item a
+// recipe uses adds 1 / (1 + days until it expires) to it's score, so using
+// something that expires today counts as much as two things that expire
+// tomorrow.
+func SuggestRecipes(recipes []Recipe, expiring []PantryItem, today time.Time) []RecipeSuggestion {
+	suggestions := []RecipeSuggestion{}
+	for _, recipe := range recipes {
+		suggestion := RecipeSuggestion{
+			RecipeID:   recipe.ID,
+			RecipeName: recipe.Name,
+			Uses:       []PantryItem{},
+		}
+
+		for _, item := range expiring {
+			for name := range recipe.Ingredients {
+				if !ingredient.Matches(name, item.Name) {
+					continue
+				}
+
+				suggestion.Uses = append(suggestion.Uses, item)
+				suggestion.Score += 1 / (1 + float64(daysUntil(today, item.ExpiresOn)))
+				break
+			}
+		}
+
+		if len(suggestion.Uses) > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].RecipeName < suggestions[j].RecipeName
+	})
+
+	return suggestions
+}
+
+func daysUntil(today time.Time, date string) int {
+	day, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return 0
+	}
+	start, _ := time.Parse(DateFormat, today.Format(DateFormat))
+
+	days := int(day.Sub(start).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestRecipes(t *testing.T) {
+	today := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	pantry := []PantryItem{
+		{ID: "milk", Name: "milk", ExpiresOn: "2026-10-19"},
+		{ID: "spinach", Name: "baby spinach", ExpiresOn: "2026-10-20"},
+		{ID: "eggs", Name: "eggs", ExpiresOn: "2026-10-21"},
+		{ID: "old", Name: "cream", ExpiresOn: "2026-10-18"},
+		{ID: "later", Name: "cheese", ExpiresOn: "2026-11-30"},
+		{ID: "rice", Name: "rice"},
+	}
+
+	expiring := ExpiringPantryItems(pantry, today, 3)
+	if len(expiring) != 3 || expiring[0].ID != "milk" {
+		t.Fatalf("Expected milk, spinach and eggs to be expiring, got %+v", expiring)
+	}
+
+	recipes := []Recipe{
+		{ID: "omelette", Name: "Omelette", Ingredients: map[string]string{"egg": "3", "spinach": "1 cup"}},
+		{ID: "pudding", Name: "Rice Pudding", Ingredients: map[string]string{"milk": "2 cups", "rice": "1/2 cup"}},
+		{ID: "toast", Name: "Toast", Ingredients: map[string]string{"bread": "2 slices"}},
+	}
+
+	suggestions := SuggestRecipes(recipes, expiring, today)
+	if len(suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions, got %+v", suggestions)
+	}
+
+	// milk expiring today (1) beats spinach tomorrow (1/2) and eggs in two
+	// days (1/3)
+	if suggestions[0].RecipeID != "pudding" || suggestions[1].RecipeID != "omelette" {
+		t.Errorf("Suggestions in the wrong order: %+v", suggestions)
+	}
+	if len(suggestions[1].Uses) != 2 {
+		t.Errorf("Expected the omelette to use spinach and eggs, got %+v", suggestions[1].Uses)
+	}
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// TagIndex is the global secondary index on RecipeTagTable for looking up
+// recipes by tag
+const TagIndex = "tag-index"
+
+// recipeTag records a recipe having a tag, so recipes can be looked up by
+// tag through TagIndex
+type recipeTag struct {
+	// ID is the tag and recipe ID together, so each pair is stored once
+	ID       string `json:"id"`
+	Tag      string `json:"tag"`
+	RecipeID string `json:"recipeId"`
+}
+
+// TagCount is a tag and how many recipes have it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag lower cases a tag and tidies up it's spacing, so "Weeknight "
+// and "weeknight" are the same tag
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// NormalizeTags normalizes the tags, dropping empty and repeated ones, in
+// alphabetical order
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" {
+			normalized = AppendUnique(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// ListRecipeIDsByTag returns the IDs of the recipes with a tag
+func (client *Client) ListRecipeIDsByTag(tag string) ([]string, error) {
+	result, err := client.dbService.Query(&dynamodb.QueryInput{
+		TableName:                aws.String(RecipeTagTable),
+		IndexName:                aws.String(TagIndex),
+		KeyConditionExpression:   aws.String("#tag = :tag"),
+		ExpressionAttributeNames: map[string]*string{"#tag": aws.String("tag")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":tag": {S: aws.String(NormalizeTag(tag))},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up tag: %w", err)
+	}
+
+	tags := []recipeTag{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	recipeIDs := []string{}
+	for _, tag := range tags {
+		recipeIDs = append(recipeIDs, tag.RecipeID)
+	}
+	sort.Strings(recipeIDs)
+	return recipeIDs, nil
+}
+
+// ListTags returns every tag in use, most used first
+func (client *Client) ListTags() ([]TagCount, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(RecipeTagTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []recipeTag{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, tag := range tags {
+		counts[tag.Tag]++
+	}
+
+	tagCounts := []TagCount{}
+	for tag, count := range counts {
+		tagCounts = append(tagCounts, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tagCounts, func(i, j int) bool {
+		if tagCounts[i].Count != tagCounts[j].Count {
+			return tagCounts[i].Count > tagCounts[j].Count
+		}
+		return tagCounts[i].Tag < tagCounts[j].Tag
+	})
+	return tagCounts, nil
+}
+
+// RenameTags replaces the from tags with the to tag on every recipe that has
+// them. Renaming to a tag that's already in use merges them. It returns how
+// many recipes were changed.
+func (client *Client) RenameTags(from []string, to string) (int, error) {
+	to = NormalizeTag(to)
+
+	recipeIDs := []string{}
+	for _, tag := range from {
+		tagged, err := client.ListRecipeIDsByTag(tag)
+		if err != nil {
+			return 0, err
+		}
+		for _, recipeID := range tagged {
+			recipeIDs = AppendUnique(recipeIDs, recipeID)
+		}
+	}
+
+	renamed := map[string]bool{}
+	for _, tag := range from {
+		renamed[NormalizeTag(tag)] = true
+	}
+
+	for _, recipeID := range recipeIDs {
+		tags := []string{}
+		old, err := client.updateRecipeList(recipeID, "tags", func(recipe Recipe) []string {
+			tags = []string{to}
+			for _, tag := range recipe.Tags {
+				if !renamed[tag] {
+					tags = append(tags, tag)
+				}
+			}
+			tags = NormalizeTags(tags)
+			return tags
+		})
+		if errors.Is(err, ErrRecipeNotFound) {
+			// deleted since it was indexed
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		err = client.indexRecipeTags(recipeID, old.Tags, tags)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(recipeIDs), nil
+}
+
+// - MARK: Helper Functions
+
+// brings the tag index up to date with a recipe's tags changing from old to
+// tags
+func (client *Client) indexRecipeTags(recipeID string, old []string, tags []string) error {
+	for _, tag := range tags {
+		if ContainsString(old, tag) {
+			continue
+		}
+
+		av, err := dynamodbattribute.MarshalMap(recipeTag{ID: tag + "\x00" + recipeID, Tag: tag, RecipeID: recipeID})
+		if err != nil {
+			return fmt.Errorf("error marshalling recipe tag: %w", err)
+		}
+		_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+			Item:      av,
+			TableName: aws.String(RecipeTagTable),
+		})
+		if err != nil {
+			return fmt.Errorf("error saving recipe tag: %w", err)
+		}
+	}
+
+	for _, tag := range old {
+		if ContainsString(tags, tag) {
+			continue
+		}
+
+		_, err := client.dbService.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(RecipeTagTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {
+					S: aws.String(tag + "\x00" + recipeID),
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting recipe tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// how many times a change is tried against the latest recipe
+const recipeAttempts = 5
+
+// sets a list attribute of a recipe without touching the rest of it, as the
+// recipe's next revision. Change gets the latest recipe and returns the new
+// list, and is called again if the recipe is saved in the meantime. The
+// recipe as it was before the change is returned.
+func (client *Client) updateRecipeList(recipeID string, attribute string, change func(Recipe) []string) (*Recipe, error) {
+	for attempt := 0; attempt < recipeAttempts; attempt++ {
+		recipe, err := client.GetRecipe(recipeID)
+		if err != nil {
+			return nil, err
+		}
+
+		av, err := dynamodbattribute.Marshal(change(*recipe))
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling recipe %s: %w", attribute, err)
+		}
+
+		input := &dynamodb.UpdateItemInput{
+			TableName: aws.String(RecipeTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {
+					S: aws.String(recipeID),
+				},
+			},
+			ConditionExpression: aws.String("attribute_exists(id) AND #revision = :revision"),
+			UpdateExpression:    aws.String("SET #list = :list, #revision = :next"),
+			ExpressionAttributeNames: map[string]*string{
+				"#list":     aws.String(attribute),
+				"#revision": aws.String("revision"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":list":     av,
+				":revision": {N: aws.String(strconv.Itoa(recipe.Revision))},
+				":next":     {N: aws.String(strconv.Itoa(recipe.Revision + 1))},
+			},
+			ReturnValues: aws.String("ALL_NEW"),
+		}
+		// recipes saved before revisions were counted don't have one
+		if recipe.Revision == 0 {
+			input.ConditionExpression = aws.String("attribute_exists(id) AND (attribute_not_exists(#revision) OR #revision = :revision)")
+		}
+
+		result, err := client.dbService.UpdateItem(input)
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error updating recipe %s: %w", attribute, err)
+		}
+
+		// kept like any other edit, so stale edits merge against it
+		var updated Recipe
+		err = dynamodbattribute.UnmarshalMap(result.Attributes, &updated)
+		if err != nil {
+			return nil, err
+		}
+		err = client.saveRecipeRevision(updated)
+		if err != nil {
+			return nil, err
+		}
+		return recipe, nil
+	}
+	return nil, fmt.Errorf("error updating recipe %s: %w", attribute, ErrRecipeChanged)
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{" Weeknight ", "weeknight", "", "One  Pot", "easy"})
+	expected := []string{"easy", "one pot", "weeknight"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+}
+
+func TestRecipeTagIndex(t *testing.T) {
+	mockClient := newMockClient()
+	tacos, _ := mockClient.SaveRecipe(Recipe{Name: "Tacos", Tags: []string{"Weeknight", "mexican"}})
+	soup, _ := mockClient.SaveRecipe(Recipe{Name: "Soup", Tags: []string{"quick"}})
+
+	found, err := mockClient.ListRecipeIDsByTag("weeknight")
+	if err != nil || !reflect.DeepEqual(found, []string{tacos.ID}) {
+		t.Fatalf("Expected tacos to be weeknight, got %v, %v", found, err)
+	}
+
+	// updating the recipe moves it between tags
+	tacos.Tags = []string{"mexican", "quick"}
+	_, err = mockClient.UpdateRecipe(*tacos, tacos.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+	if found, _ := mockClient.ListRecipeIDsByTag("weeknight"); len(found) != 0 {
+		t.Errorf("Expected no weeknight recipes, got %v", found)
+	}
+
+	// merging quick into weeknight
+	changed, err := mockClient.RenameTags([]string{"quick"}, "Weeknight")
+	if err != nil || changed != 2 {
+		t.Fatalf("Expected 2 recipes renamed, got %d, %v", changed, err)
+	}
+	recipe, _ := mockClient.GetRecipe(tacos.ID)
+	if !reflect.DeepEqual(recipe.Tags, []string{"mexican", "weeknight"}) {
+		t.Errorf("Expected tacos to be renamed, got %v", recipe.Tags)
+	}
+	if stored, err := mockClient.GetRecipeRevision(tacos.ID, recipe.Revision); err != nil || recipe.Revision != tacos.Revision+2 || !reflect.DeepEqual(stored.Tags, recipe.Tags) {
+		t.Errorf("Expected renaming to save a revision, got revision %d and %v", recipe.Revision, err)
+	}
+
+	tags, _ := mockClient.ListTags()
+	expected := []TagCount{{Tag: "weeknight", Count: 2}, {Tag: "mexican", Count: 1}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+
+	mockClient.DeleteRecipe(soup.ID)
+	if found, _ := mockClient.ListRecipeIDsByTag("weeknight"); !reflect.DeepEqual(found, []string{tacos.ID}) {
+		t.Errorf("Expected deleted recipes to be untagged, got %v", found)
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import "github.com/slichlyter12/thyme-apiserver/timing"
+
+// ApplyTimings reads the durations and temperatures out of the recipe's
+// steps and adds up how long it takes. Steps are taken to happen one after
+// another.
+func (recipe *Recipe) ApplyTimings() {
+	recipe.StepTimings = nil
+	active, passive, found := 0, 0, false
+	for _, text := range recipe.Steps {
+		step := timing.Parse(text)
+		recipe.StepTimings = append(recipe.StepTimings, step)
+		active += step.ActiveSeconds()
+		passive += step.PassiveSeconds()
+		found = found || !step.Empty()
+	}
+	// steps that don't mention either aren't worth storing
+	if !found {
+		recipe.StepTimings = nil
+	}
+
+	recipe.ActiveTime, recipe.PassiveTime = minutes(active), minutes(passive)
+	recipe.TotalTime = recipe.PrepTime + recipe.CookTime
+	if recipe.TotalTime == 0 {
+		recipe.TotalTime = recipe.ActiveTime + recipe.PassiveTime
+	}
+}
+
+// - MARK: Helper Functions
+
+// rounds seconds up to whole minutes
+func minutes(seconds int) int {
+	return (seconds + 59) / 60
+}
@@ -0,0 +1,32 @@
+package database
+
+import "testing"
+
+func TestSaveRecipeTimings(t *testing.T) {
+	mockClient := newMockClient()
+	saved, err := mockClient.SaveRecipe(Recipe{
+		Name:  "Brownies",
+		Steps: []string{"Preheat the oven to 350°F", "Mix for 2 minutes", "Bake 25-30 minutes", "Cut into squares"},
+	})
+	if err != nil {
+		t.Fatalf("Error saving recipe: %s", err.Error())
+	}
+
+	if len(saved.StepTimings) != 4 || saved.StepTimings[0].Temperatures[0].Celsius != 177 {
+		t.Errorf("Wrong step timings: %+v", saved.StepTimings)
+	}
+	if saved.ActiveTime != 2 || saved.PassiveTime != 30 || saved.TotalTime != 32 {
+		t.Errorf("Expected 2 active and 30 passive minutes, got %d, %d and %d total", saved.ActiveTime, saved.PassiveTime, saved.TotalTime)
+	}
+
+	saved.Steps = []string{"Mix", "Bake"}
+	saved.PrepTime, saved.CookTime = 15, 25
+	_, err = mockClient.UpdateRecipe(*saved, saved.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+	updated, _ := mockClient.GetRecipe(saved.ID)
+	if updated.StepTimings != nil || updated.ActiveTime != 0 || updated.TotalTime != 40 {
+		t.Errorf("Expected the timings to be worked out again, got %+v", updated)
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// identityNamespace seeds the name based UUIDs used for user IDs
+var identityNamespace = uuid.MustParse("5b7f1d0e-3c1a-4f7e-9d55-6f0c2b8e4a11")
+
+// User is someone who has logged in through the identity provider
+type User struct {
+	ID        string    `json:"id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// HouseholdID is managed by the household methods
+	HouseholdID string `json:"householdId,omitempty"`
+}
+
+// UserIDForIdentity derives a stable user ID from the provider's issuer and
+// subject, so logging in never needs to scan for an existing user
+func UserIDForIdentity(issuer string, subject string) string {
+	return uuid.NewSHA1(identityNamespace, []byte(issuer+"\x00"+subject)).String()
+}
+
+// EnsureUser returns the user for the identity, creating them on first login
+// and refreshing their profile on later ones
+func (client *Client) EnsureUser(issuer string, subject string, email string, name string) (*User, error) {
+	id := UserIDForIdentity(issuer, subject)
+
+	user := User{
+		ID:        id,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	av, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling user: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:                av,
+		TableName:           aws.String(UserTable),
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err == nil {
+		return &user, nil
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil, fmt.Errorf("error saving user: %w", err)
+	}
+
+	// only the profile is refreshed, so the user's household isn't lost if
+	// it changes while they log in
+	result, err := client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(UserTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:         aws.String("SET email = :email, #name = :name"),
+		ExpressionAttributeNames: map[string]*string{"#name": aws.String("name")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":email": av["email"],
+			":name":  av["name"],
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUser fetches a user by their ID
+func (client *Client) GetUser(id string) (*User, error) {
+	var user *User
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(UserTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find user with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
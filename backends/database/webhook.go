@@ -0,0 +1,228 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// EventPantryExpiring is the daily digest of pantry items about to expire,
+// with recipes that use them
+const EventPantryExpiring = "pantry.expiring"
+
+// EventCommentMention is sent when someone mentions the user in a comment
+const EventCommentMention = "comment.mention"
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{EventPantryExpiring, EventCommentMention}
+
+// ValidWebhookEvent reports whether event is one of WebhookEvents
+func ValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is a URL a user wants events delivered to. Deliveries are signed
+// with the secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-" dynamodbav:"secret"`
+	CreatedAt time.Time `json:"createdAt"`
+	// DigestSentAt is when the pantry digest was last sent, so it's sent
+	// once a day however often the server restarts
+	DigestSentAt time.Time `json:"digestSentAt"`
+}
+
+// Subscribed reports whether the webhook wants event
+func (webhook Webhook) Subscribed(event string) bool {
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhook registers a webhook with a new signing secret
+func (client *Client) CreateWebhook(userID string, url string, events []string) (*Webhook, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("error generating UUID: %w", err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := Webhook{
+		ID:        id.String(),
+		UserID:    userID,
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	av, err := dynamodbattribute.MarshalMap(webhook)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling webhook: %w", err)
+	}
+
+	_, err = client.dbService.PutItem(&dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(WebhookTable),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error saving webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// GetWebhook fetches a webhook by it's ID
+func (client *Client) GetWebhook(id string) (*Webhook, error) {
+	var webhook *Webhook
+
+	result, err := client.dbService.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(WebhookTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, errors.New("Could not find webhook with id: " + id)
+	}
+
+	err = dynamodbattribute.UnmarshalMap(result.Item, &webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns the user's webhooks
+func (client *Client) ListWebhooks(userID string) ([]Webhook, error) {
+	webhooks, err := client.listAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	userWebhooks := []Webhook{}
+	for _, webhook := range webhooks {
+		if webhook.UserID == userID {
+			userWebhooks = append(userWebhooks, webhook)
+		}
+	}
+
+	return userWebhooks, nil
+}
+
+// ListWebhooksForEvent returns every user's webhooks subscribed to event
+func (client *Client) ListWebhooksForEvent(event string) ([]Webhook, error) {
+	webhooks, err := client.listAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := []Webhook{}
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+
+	return subscribed, nil
+}
+
+func (client *Client) listAllWebhooks() ([]Webhook, error) {
+	result, err := client.scan(&dynamodb.ScanInput{
+		TableName: aws.String(WebhookTable),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []Webhook{}
+	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// ClaimWebhookDigest records that the webhook's pantry digest is being sent
+// at sentAt. It returns false, without an error, if someone else sent it
+// since the webhook was read.
+func (client *Client) ClaimWebhookDigest(webhook Webhook, sentAt time.Time) (bool, error) {
+	previous, err := dynamodbattribute.Marshal(webhook.DigestSentAt)
+	if err != nil {
+		return false, fmt.Errorf("error marshalling digest time: %w", err)
+	}
+	sent, err := dynamodbattribute.Marshal(sentAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("error marshalling digest time: %w", err)
+	}
+
+	_, err = client.dbService.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(WebhookTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(webhook.ID),
+			},
+		},
+		// webhooks created before digests were recorded don't have one
+		ConditionExpression:      aws.String("attribute_exists(id) AND (attribute_not_exists(#sent) OR #sent = :previous)"),
+		UpdateExpression:         aws.String("SET #sent = :sent"),
+		ExpressionAttributeNames: map[string]*string{"#sent": aws.String("digestSentAt")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":previous": previous,
+			":sent":     sent,
+		},
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating webhook: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteWebhook deletes a webhook given it's ID
+func (client *Client) DeleteWebhook(id string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(WebhookTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	_, err := client.dbService.DeleteItem(input)
+	return err
+}
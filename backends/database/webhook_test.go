@@ -0,0 +1,34 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimWebhookDigest(t *testing.T) {
+	mockClient := newMockClient()
+	webhook, err := mockClient.CreateWebhook("user", "https://example.com/hook", []string{EventPantryExpiring})
+	if err != nil {
+		t.Fatalf("Error creating webhook: %s", err.Error())
+	}
+
+	// two servers read the webhook before either sends the digest
+	sentAt := time.Now()
+	claimed, err := mockClient.ClaimWebhookDigest(*webhook, sentAt)
+	if err != nil || !claimed {
+		t.Fatalf("Expected the first claim to succeed, got %t and %v", claimed, err)
+	}
+	claimed, err = mockClient.ClaimWebhookDigest(*webhook, sentAt)
+	if err != nil || claimed {
+		t.Errorf("Expected the second claim to fail, got %t and %v", claimed, err)
+	}
+
+	stored, _ := mockClient.GetWebhook(webhook.ID)
+	if !stored.DigestSentAt.Equal(sentAt) {
+		t.Errorf("Expected the digest to be recorded as sent at %s, got %s", sentAt, stored.DigestSentAt)
+	}
+	claimed, err = mockClient.ClaimWebhookDigest(*stored, sentAt.Add(24*time.Hour))
+	if err != nil || !claimed {
+		t.Errorf("Expected the next day's claim to succeed, got %t and %v", claimed, err)
+	}
+}
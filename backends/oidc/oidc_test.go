@@ -0,0 +1,110 @@
+package oidc
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/oidc/oidctest"
+)
+
+func newTestProvider(server *oidctest.Server) *Provider {
+	return New(Config{
+		Issuer:      server.Issuer(),
+		ClientID:    server.ClientID,
+		RedirectURL: "http://localhost:8080/api/auth/callback",
+	})
+}
+
+// follows the authorization redirect without following the one back to us
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	response, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Error authorizing: %s", err.Error())
+	}
+	defer response.Body.Close()
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Error parsing redirect: %s", err.Error())
+	}
+
+	return location.Query()
+}
+
+func TestLoginFlow(t *testing.T) {
+	server := oidctest.NewServer("thyme")
+	defer server.Close()
+	provider := newTestProvider(server)
+
+	authURL, verifier, err := provider.AuthCodeURL("some-state", "some-nonce")
+	if err != nil {
+		t.Fatalf("Error building auth URL: %s", err.Error())
+	}
+
+	callback := authorize(t, authURL)
+	if callback.Get("state") != "some-state" {
+		t.Errorf("State was not passed back: %s", callback.Get("state"))
+	}
+
+	claims, err := provider.Exchange(callback.Get("code"), verifier, "some-nonce")
+	if err != nil {
+		t.Fatalf("Error exchanging code: %s", err.Error())
+	}
+	if claims.Subject != server.Subject || claims.Email != server.Email {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeWithWrongVerifier(t *testing.T) {
+	server := oidctest.NewServer("thyme")
+	defer server.Close()
+	provider := newTestProvider(server)
+
+	authURL, _, err := provider.AuthCodeURL("some-state", "some-nonce")
+	if err != nil {
+		t.Fatalf("Error building auth URL: %s", err.Error())
+	}
+
+	callback := authorize(t, authURL)
+	_, err = provider.Exchange(callback.Get("code"), "not-the-verifier", "some-nonce")
+	if err == nil {
+		t.Error("Exchanged code with the wrong PKCE verifier")
+	}
+}
+
+func TestVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	server := oidctest.NewServer("thyme")
+	defer server.Close()
+	provider := newTestProvider(server)
+
+	cases := map[string]func(claims map[string]interface{}){
+		"wrong audience": func(claims map[string]interface{}) { claims["aud"] = "someone-else" },
+		"wrong issuer":   func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"expired":        func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"wrong nonce":    func(claims map[string]interface{}) { claims["nonce"] = "other-nonce" },
+	}
+
+	for name, mutate := range cases {
+		claims := server.Claims("some-nonce")
+		mutate(claims)
+		_, err := provider.VerifyIDToken(server.SignIDToken(claims), "some-nonce")
+		if err == nil {
+			t.Errorf("Accepted id token with %s", name)
+		}
+	}
+
+	token := server.SignIDToken(server.Claims("some-nonce"))
+	tampered := token[:len(token)-4] + "AAAA"
+	_, err := provider.VerifyIDToken(tampered, "some-nonce")
+	if err == nil {
+		t.Error("Accepted id token with a bad signature")
+	}
+}
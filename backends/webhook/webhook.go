@@ -0,0 +1,149 @@
+// Package webhook delivers notifications to URLs users have registered. Each
+// delivery is a JSON POST signed with the webhook's secret, so receivers can
+// check it came from us. Webhooks can only be delivered to public addresses,
+// so they can't be used to reach the server's own network.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// EventHeader names the event being delivered
+	EventHeader = "X-Thyme-Event"
+	// SignatureHeader is "sha256=" and the hex HMAC-SHA256 of the body
+	SignatureHeader = "X-Thyme-Signature"
+)
+
+// Payload is the body of every delivery
+type Payload struct {
+	Event  string      `json:"event"`
+	SentAt time.Time   `json:"sentAt"`
+	Data   interface{} `json:"data"`
+}
+
+// ErrPrivateAddress is returned when a webhook's URL resolves to a loopback,
+// private or link-local address, like the cloud metadata service
+var ErrPrivateAddress = errors.New("webhook url is not a public address")
+
+// addresses that aren't covered by net.IP's checks but aren't public either
+var privateNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+// Sender delivers webhooks
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender that gives up on slow receivers after timeout
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, publicIP)
+}
+
+// the sender checks every address it connects to, after DNS has been
+// resolved and on every redirect, with allowed
+func newSender(timeout time.Duration, allowed func(net.IP) bool) *Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowed(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &Sender{client: &http.Client{
+		Timeout: timeout,
+		// no proxy, so the addresses checked are the receivers'
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+	}}
+}
+
+// Deliver posts the event to url, failing unless the receiver responds with
+// a 2xx status
+func (sender *Sender) Deliver(url string, secret string, event string, data interface{}) error {
+	body, err := json.Marshal(Payload{
+		Event:  event,
+		SentAt: time.Now().UTC(),
+		Data:   data,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding webhook: %w", err)
+	}
+
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "thyme-webhook")
+	request.Header.Set(EventHeader, event)
+	request.Header.Set(SignatureHeader, Sign(secret, body))
+
+	response, err := sender.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error delivering webhook: %w", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook receiver responded %s", response.Status)
+	}
+	return nil
+}
+
+// Sign is the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value, for receivers written in Go
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// - MARK: Helper Functions
+
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer server.Close()
+
+	sender := newSender(time.Second, anyIP)
+	err := sender.Deliver(server.URL, "shh", "pantry.expiring", map[string]int{"items": 2})
+	if err != nil {
+		t.Fatalf("Error delivering webhook: %s", err.Error())
+	}
+
+	if header.Get(EventHeader) != "pantry.expiring" {
+		t.Errorf("Wrong event header: %q", header.Get(EventHeader))
+	}
+	if !Verify("shh", body, header.Get(SignatureHeader)) {
+		t.Error("Signature did not verify")
+	}
+	if Verify("wrong", body, header.Get(SignatureHeader)) {
+		t.Error("Signature verified with the wrong secret")
+	}
+}
+
+func TestDeliverFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	err := newSender(time.Second, anyIP).Deliver(server.URL, "shh", "test", nil)
+	if err == nil {
+		t.Error("Expected an error when the receiver fails")
+	}
+}
+
+func TestDeliverRefusesPrivateAddresses(t *testing.T) {
+	delivered := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer server.Close()
+
+	err := NewSender(time.Second).Deliver(server.URL, "shh", "test", nil)
+	if !errors.Is(err, ErrPrivateAddress) || delivered {
+		t.Errorf("Expected delivery to loopback to be refused, got %v", err)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.100.100.200": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00:ec2::254":   false,
+		"fe80::1":         false,
+	} {
+		if publicIP(net.ParseIP(address)) != public {
+			t.Errorf("Expected %s to be public: %t", address, public)
+		}
+	}
+}
+
+func anyIP(net.IP) bool {
+	return true
+}
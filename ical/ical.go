@@ -0,0 +1,142 @@
+// Package ical writes just enough of iCalendar (RFC 5545) to publish a
+// subscribable calendar feed
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// lines longer than this many octets must be folded
+const maxLineLength = 75
+
+const (
+	floatingFormat = "20060102T150405"
+	utcFormat      = "20060102T150405Z"
+)
+
+// Calendar is a VCALENDAR holding events
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. Start is written as a floating time, so it happens at
+// the same wall clock time in whatever time zone the subscriber is in.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	Duration    time.Duration
+	Summary     string
+	Description string
+	URL         string
+	Alarms      []Alarm
+}
+
+// Alarm is a VALARM that displays a reminder Before the event starts
+type Alarm struct {
+	Before      time.Duration
+	Description string
+}
+
+// Encode writes the calendar with CRLF line endings and folded lines
+func (calendar Calendar) Encode(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	line := func(name string, value string) {
+		writeFolded(writer, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", calendar.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if calendar.Name != "" {
+		line("X-WR-CALNAME", escape(calendar.Name))
+	}
+
+	for _, event := range calendar.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", event.Stamp.UTC().Format(utcFormat))
+		line("DTSTART", event.Start.Format(floatingFormat))
+		line("DURATION", Duration(event.Duration))
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.URL != "" {
+			line("URL", event.URL)
+		}
+		for _, alarm := range event.Alarms {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escape(alarm.Description))
+			line("TRIGGER", "-"+Duration(alarm.Before))
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return writer.Flush()
+}
+
+// Duration formats a duration like PT1H30M, rounded down to the minute
+func Duration(duration time.Duration) string {
+	minutes := int(duration / time.Minute)
+	if minutes <= 0 {
+		return "PT0M"
+	}
+
+	formatted := "PT"
+	if minutes >= 60 {
+		formatted += fmt.Sprintf("%dH", minutes/60)
+	}
+	if minutes%60 != 0 {
+		formatted += fmt.Sprintf("%dM", minutes%60)
+	}
+	return formatted
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escape makes text safe for a TEXT property value
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// writes a content line, folding it onto continuation lines (which start
+// with a space) without splitting UTF-8 characters
+func writeFolded(writer *bufio.Writer, contentLine string) {
+	limit := maxLineLength
+	for len(contentLine) > limit {
+		cut := limit
+		for cut > 0 && !startsCharacter(contentLine[cut]) {
+			cut--
+		}
+		writer.WriteString(contentLine[:cut])
+		writer.WriteString("\r\n ")
+		contentLine = contentLine[cut:]
+		// the leading space counts towards the next line's length
+		limit = maxLineLength - 1
+	}
+	writer.WriteString(contentLine)
+	writer.WriteString("\r\n")
+}
+
+// reports whether b is the first byte of a UTF-8 encoded character
+func startsCharacter(b byte) bool {
+	return b&0xc0 != 0x80
+}
@@ -0,0 +1,65 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncode(t *testing.T) {
+	calendar := Calendar{
+		ProdID: "-//thyme//test//EN",
+		Name:   "Dinners",
+		Events: []Event{
+			{
+				UID:      "1@thyme",
+				Stamp:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+				Start:    time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC),
+				Duration: time.Hour,
+				Summary:  "Soup, bread; salad",
+				Alarms:   []Alarm{{Before: 90 * time.Minute, Description: "Start cooking"}},
+			},
+		},
+	}
+
+	var buffer bytes.Buffer
+	err := calendar.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Error encoding calendar: %s", err.Error())
+	}
+	encoded := buffer.String()
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20261020T180000\r\n",
+		"DTSTAMP:20261001T120000Z\r\n",
+		"DURATION:PT1H\r\n",
+		`SUMMARY:Soup\, bread\; salad` + "\r\n",
+		"TRIGGER:-PT1H30M\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(encoded, expected) {
+			t.Errorf("Calendar is missing %q:\n%s", expected, encoded)
+		}
+	}
+}
+
+func TestFolding(t *testing.T) {
+	var buffer bytes.Buffer
+	calendar := Calendar{Events: []Event{{Description: strings.Repeat("é", 100)}}}
+	err := calendar.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Error encoding calendar: %s", err.Error())
+	}
+
+	for _, line := range strings.Split(buffer.String(), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("Line is %d octets long: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Folded in the middle of a character: %q", line)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// JPEG markers we care about
+const (
+	markerSOI  = 0xd8
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+	// APP13 holds Photoshop/IPTC metadata
+	markerAPP13 = 0xed
+	markerCOM   = 0xfe
+)
+
+// Sanitize strips metadata such as EXIF GPS locations from an image and
+// rotates it upright according to it's EXIF orientation. It returns the
+// cleaned bytes and the upright image. JPEGs are only re-encoded when they
+// need rotating, PNGs and GIFs always are.
+func Sanitize(data []byte, format string, img image.Image) ([]byte, image.Image, error) {
+	switch format {
+	case "jpeg":
+		orientation := Orientation(data)
+		if orientation <= 1 {
+			return StripJPEGMetadata(data), img, nil
+		}
+
+		img = Orient(img, orientation)
+		var buffer bytes.Buffer
+		err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 92})
+		if err != nil {
+			return nil, nil, err
+		}
+		return buffer.Bytes(), img, nil
+	case "png":
+		// the encoder only writes image data, dropping eXIf and text chunks
+		var buffer bytes.Buffer
+		err := png.Encode(&buffer, img)
+		if err != nil {
+			return nil, nil, err
+		}
+		return buffer.Bytes(), img, nil
+	case "gif":
+		// the encoder only writes frames and the loop count, dropping comment
+		// and application extensions
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		var buffer bytes.Buffer
+		err = gif.EncodeAll(&buffer, animation)
+		if err != nil {
+			return nil, nil, err
+		}
+		return buffer.Bytes(), img, nil
+	}
+
+	return data, img, nil
+}
+
+// calls fn with each marker and segment before the image data starts,
+// returning the offset of the start of scan segment
+func walkJPEGSegments(data []byte, fn func(marker byte, segment []byte)) int {
+	if len(data) < 2 || data[0] != 0xff || data[1] != markerSOI {
+		return -1
+	}
+
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xff {
+			return -1
+		}
+		marker := data[offset+1]
+		// markers may be padded with fill bytes
+		if marker == 0xff {
+			offset++
+			continue
+		}
+		if marker == markerSOS {
+			return offset
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return -1
+		}
+		fn(marker, data[offset:offset+2+length])
+		offset += 2 + length
+	}
+
+	return -1
+}
+
+// StripJPEGMetadata losslessly removes EXIF, XMP, IPTC and comment segments
+// from a JPEG. Color profiles are kept. Data that doesn't parse is returned
+// as is.
+func StripJPEGMetadata(data []byte) []byte {
+	stripped := []byte{0xff, markerSOI}
+	sos := walkJPEGSegments(data, func(marker byte, segment []byte) {
+		if marker == markerAPP1 || marker == markerAPP13 || marker == markerCOM {
+			return
+		}
+		stripped = append(stripped, segment...)
+	})
+	if sos < 0 {
+		return data
+	}
+
+	return append(stripped, data[sos:]...)
+}
+
+// Orientation reads the EXIF orientation (1-8) from a JPEG, returning 1 when
+// there isn't one
+func Orientation(data []byte) int {
+	orientation := 1
+	walkJPEGSegments(data, func(marker byte, segment []byte) {
+		if marker != markerAPP1 || len(segment) < 10 || string(segment[4:10]) != "Exif\x00\x00" {
+			return
+		}
+		if value, ok := tiffOrientation(segment[10:]); ok {
+			orientation = value
+		}
+	})
+	return orientation
+}
+
+// finds the orientation tag in the first IFD of a TIFF structure
+func tiffOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	// compared before converting, so huge offsets can't wrap negative on
+	// 32-bit builds
+	offset := order.Uint32(tiff[4:])
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 0, false
+	}
+	ifd := int(offset)
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 0, false
+			}
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+// Orient transforms the image so an image with the given EXIF orientation is
+// displayed upright
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+// builds a JPEG with an EXIF segment holding the orientation and a fake GPS
+// marker
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, img, nil)
+	if err != nil {
+		t.Fatalf("Error encoding jpeg: %s", err.Error())
+	}
+	encoded := buffer.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	entry := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(entry[0:], 1)
+	binary.BigEndian.PutUint16(entry[2:], 0x0112)
+	binary.BigEndian.PutUint16(entry[4:], 3)
+	binary.BigEndian.PutUint32(entry[6:], 1)
+	binary.BigEndian.PutUint16(entry[10:], orientation)
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), entry...)
+	payload = append(payload, []byte("GPS 45.52N 122.68W")...)
+
+	segment := []byte{0xff, markerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	withExif := append([]byte{0xff, markerSOI}, segment...)
+	return append(withExif, encoded[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	data := jpegWithOrientation(t, img, 6)
+
+	if orientation := Orientation(data); orientation != 6 {
+		t.Errorf("Read wrong orientation: %d", orientation)
+	}
+
+	stripped := StripJPEGMetadata(data)
+	if bytes.Contains(stripped, []byte("GPS")) || Orientation(stripped) != 1 {
+		t.Errorf("EXIF was not stripped")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Stripped jpeg does not decode: %s", err.Error())
+	}
+}
+
+func TestSanitizeRotates(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	data := jpegWithOrientation(t, img, 6)
+
+	cleaned, upright, err := Sanitize(data, "jpeg", img)
+	if err != nil {
+		t.Fatalf("Error sanitizing: %s", err.Error())
+	}
+	if upright.Bounds().Dx() != 4 || upright.Bounds().Dy() != 8 {
+		t.Errorf("Image was not rotated: %v", upright.Bounds())
+	}
+	if bytes.Contains(cleaned, []byte("GPS")) {
+		t.Errorf("EXIF was not stripped")
+	}
+}
+
+func TestTIFFOrientationRejectsHugeOffsets(t *testing.T) {
+	tiff := []byte("MM\x00\x2a\xff\xff\xff\xf0\x00\x01")
+	if _, ok := tiffOrientation(tiff); ok {
+		t.Error("Expected an IFD offset past the end to be rejected")
+	}
+}
+
+func TestSanitizeStripsGIFComments(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	var buffer bytes.Buffer
+	err := gif.Encode(&buffer, img, nil)
+	if err != nil {
+		t.Fatalf("Error encoding gif: %s", err.Error())
+	}
+	encoded := buffer.Bytes()
+
+	// a comment extension after the header and global color table
+	tableSize := 0
+	if flags := encoded[10]; flags&0x80 != 0 {
+		tableSize = 3 * (1 << ((flags & 7) + 1))
+	}
+	comment := append([]byte{0x21, 0xfe, 18}, []byte("GPS 45.52N 122.68W")...)
+	comment = append(comment, 0)
+	data := append(append(append([]byte{}, encoded[:13+tableSize]...), comment...), encoded[13+tableSize:]...)
+	if _, err := gif.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Commented gif does not decode: %s", err.Error())
+	}
+
+	cleaned, _, err := Sanitize(data, "gif", img)
+	if err != nil {
+		t.Fatalf("Error sanitizing: %s", err.Error())
+	}
+	if bytes.Contains(cleaned, []byte("GPS")) {
+		t.Errorf("GIF comment was not stripped")
+	}
+	if _, err := gif.Decode(bytes.NewReader(cleaned)); err != nil {
+		t.Errorf("Sanitized gif does not decode: %s", err.Error())
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	// rotating clockwise moves the left pixel to the top
+	rotated := Orient(img, 6).(*image.RGBA)
+	if rotated.Bounds().Dx() != 1 || rotated.RGBAAt(0, 0).R != 255 || rotated.RGBAAt(0, 1).R != 0 {
+		t.Errorf("Rotated wrong: %v", rotated.Pix)
+	}
+}
@@ -0,0 +1,152 @@
+// Package imaging decodes uploaded images and produces thumbnails using only
+// the standard library
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	// decoders for the formats we accept
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxPixels guards against decompression bombs, a tiny file can claim to be an
+// enormous image
+const MaxPixels = 50 * 1000 * 1000
+
+// JPEGQuality is used for everything we encode
+const JPEGQuality = 85
+
+// Size is a named thumbnail size, images are scaled so their longest edge is
+// at most MaxEdge pixels
+type Size struct {
+	Name    string
+	MaxEdge int
+}
+
+// ThumbnailSizes are generated for every uploaded image
+var ThumbnailSizes = []Size{
+	{Name: "small", MaxEdge: 160},
+	{Name: "medium", MaxEdge: 480},
+	{Name: "large", MaxEdge: 1200},
+}
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image is too large")
+
+// Decode checks the image's dimensions before decoding it, returning the
+// image and it's format name
+func Decode(data []byte) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading image: %w", err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding image: %w", err)
+	}
+
+	return img, format, nil
+}
+
+// EncodeJPEG encodes the image as a JPEG, flattening any transparency onto
+// white
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, flat, &jpeg.Options{Quality: JPEGQuality})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding jpeg: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Fit scales the image down so it's longest edge is at most maxEdge pixels.
+// Images that are already small enough are returned as is.
+func Fit(img image.Image, maxEdge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxEdge && height <= maxEdge {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxEdge/width)
+		width = maxEdge
+	} else {
+		width = max(1, width*maxEdge/height)
+		height = maxEdge
+	}
+
+	return Resize(img, width, height)
+}
+
+// Resize scales the image to exactly width by height pixels by averaging the
+// source pixels covered by each destination pixel, which gives good results
+// when shrinking
+func Resize(img image.Image, width int, height int) *image.RGBA {
+	src := toRGBA(img)
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += int(pixel[0])
+					g += int(pixel[1])
+					b += int(pixel[2])
+					a += int(pixel[3])
+					count++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+
+	return dst
+}
+
+// copies the image into an RGBA image whose bounds start at the origin
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
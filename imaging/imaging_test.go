@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+
+	fitted := Fit(img, 100)
+	if fitted.Bounds().Dx() != 100 || fitted.Bounds().Dy() != 50 {
+		t.Errorf("Fitted to wrong size: %v", fitted.Bounds())
+	}
+
+	small := Fit(img, 2000)
+	if small != image.Image(img) {
+		t.Errorf("Scaled up an image that already fit")
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 200, A: 255})
+	img.Set(1, 0, color.RGBA{R: 100, A: 255})
+
+	resized := Resize(img, 1, 1)
+	if got := resized.RGBAAt(0, 0); got.R != 150 || got.A != 255 {
+		t.Errorf("Pixels were not averaged: %+v", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 4, 3)))
+	if err != nil {
+		t.Fatalf("Error encoding png: %s", err.Error())
+	}
+
+	img, format, err := Decode(buffer.Bytes())
+	if err != nil {
+		t.Fatalf("Error decoding png: %s", err.Error())
+	}
+	if format != "png" || img.Bounds().Dx() != 4 {
+		t.Errorf("Decoded wrong image: %s %v", format, img.Bounds())
+	}
+
+	_, _, err = Decode([]byte("not an image"))
+	if err == nil {
+		t.Error("Decoded something that isn't an image")
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	uniform := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(uniform, uniform.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 100, B: 50, A: 255}), image.Point{}, draw.Src)
+
+	hash, err := BlurHash(uniform, 4, 3)
+	if err != nil {
+		t.Fatalf("Error computing blurhash: %s", err.Error())
+	}
+
+	// the size flag comes first and the DC component is the average color
+	if len(hash) != 28 || hash[0] != 'L' || hash[2:6] != encode83(200<<16|100<<8|50, 4) {
+		t.Errorf("Unexpected blurhash: %s", hash)
+	}
+
+	if color := DominantColor(uniform); color != "#c86432" {
+		t.Errorf("Unexpected dominant color: %s", color)
+	}
+}
@@ -0,0 +1,92 @@
+package ingredient
+
+import "strings"
+
+// Aisles are the store sections shopping lists are grouped by, in the order
+// people usually walk a store
+var Aisles = []string{
+	"Produce",
+	"Bakery",
+	"Meat & Seafood",
+	"Dairy & Eggs",
+	"Pantry",
+	"Spices & Seasonings",
+	"Frozen",
+	"Beverages",
+	"Other",
+}
+
+// keywords that place an ingredient in an aisle. The longest keyword found in
+// a name wins, so "peanut butter" is Pantry even though "butter" is Dairy.
+var aisleKeywords = map[string][]string{
+	"Produce": {
+		"apple", "avocado", "banana", "basil", "bean sprout", "beet", "berries",
+		"blueberr", "broccoli", "cabbage", "carrot", "cauliflower", "celery",
+		"chive", "cilantro", "corn", "cucumber", "dill", "eggplant", "garlic",
+		"ginger", "grape", "green onion", "herb", "jalapeño", "jalapeno", "kale",
+		"leek", "lemon", "lettuce", "lime", "mango", "mint", "mushroom", "onion",
+		"orange", "parsley", "peach", "pear", "pepper", "potato", "raspberr",
+		"rosemary", "sage", "scallion", "shallot", "spinach", "squash",
+		"strawberr", "thyme", "tomato", "zucchini",
+	},
+	"Bakery": {
+		"bagel", "baguette", "bread", "bun", "croissant", "pita", "roll",
+		"tortilla",
+	},
+	"Meat & Seafood": {
+		"bacon", "beef", "chicken", "chorizo", "cod", "crab", "fish", "ham",
+		"lamb", "lobster", "pork", "prawn", "salmon", "sausage", "scallop",
+		"shrimp", "steak", "tilapia", "tuna", "turkey",
+	},
+	"Dairy & Eggs": {
+		"butter", "buttermilk", "cheddar", "cheese", "cream", "egg", "feta",
+		"ghee", "half and half", "milk", "mozzarella", "parmesan", "pecorino",
+		"ricotta", "sour cream", "yogurt",
+	},
+	"Pantry": {
+		"agar", "arrowroot", "baking powder", "baking soda", "bean",
+		"breadcrumb", "broth", "brown sugar", "chickpea", "chocolate", "cocoa",
+		"coconut milk", "cornstarch", "erythritol", "flaxseed", "flour", "honey", "jam", "ketchup", "lentil", "maple syrup",
+		"mayonnaise", "molasses", "mustard", "noodle", "nut", "oat", "oil",
+		"olive", "pasta", "peanut butter", "quinoa", "rice", "rolled oats", "soy sauce",
+		"spaghetti", "stock", "sugar", "tomato paste", "tomato sauce", "vanilla",
+		"vinegar", "yeast",
+	},
+	"Spices & Seasonings": {
+		"bay leaf", "bay leaves", "cardamom", "cayenne", "chili powder",
+		"cinnamon", "clove", "cumin", "curry", "garlic powder", "nutmeg",
+		"onion powder", "oregano", "paprika", "pepper flakes", "black pepper",
+		"salt", "seasoning", "turmeric",
+	},
+	"Frozen": {
+		"frozen", "ice cream",
+	},
+	"Beverages": {
+		"beer", "coffee", "juice", "soda", "tea", "water", "wine",
+	},
+}
+
+// Aisle guesses which aisle an ingredient is found in, "Other" if it can't
+func Aisle(name string) string {
+	name = Normalize(name)
+
+	best, bestLength := "Other", 0
+	for _, aisle := range Aisles {
+		for _, keyword := range aisleKeywords[aisle] {
+			if len(keyword) > bestLength && strings.Contains(name, keyword) {
+				best, bestLength = aisle, len(keyword)
+			}
+		}
+	}
+	return best
+}
+
+// AisleOrder is where an aisle comes in Aisles, unknown aisles go last
+func AisleOrder(aisle string) int {
+	for i, a := range Aisles {
+		if a == aisle {
+			return i
+		}
+	}
+	return len(Aisles)
+}
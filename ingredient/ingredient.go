@@ -0,0 +1,358 @@
+// Package ingredient understands the free text amounts recipes are written
+// with ("1 1/2 cups", "2 tbsp", "3 cloves"), so amounts of the same
+// ingredient can be added up and shown in a sensible unit.
+package ingredient
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dimension is what a unit measures, amounts can only be added within one
+type Dimension string
+
+const (
+	Volume Dimension = "volume"
+	Mass   Dimension = "mass"
+	// Count is for amounts without a known unit, "3" or "2 cloves"
+	Count Dimension = "count"
+)
+
+// Unit is a unit of measure, Factor converts it to millilitres or grams
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	Factor    float64
+	Metric    bool
+}
+
+var (
+	teaspoon   = Unit{"tsp", Volume, 4.92892, false}
+	tablespoon = Unit{"tbsp", Volume, 14.7868, false}
+	fluidOunce = Unit{"fl oz", Volume, 29.5735, false}
+	cup        = Unit{"cup", Volume, 236.588, false}
+	pint       = Unit{"pint", Volume, 473.176, false}
+	quart      = Unit{"quart", Volume, 946.353, false}
+	gallon     = Unit{"gallon", Volume, 3785.41, false}
+	millilitre = Unit{"ml", Volume, 1, true}
+	litre      = Unit{"l", Volume, 1000, true}
+	ounce      = Unit{"oz", Mass, 28.3495, false}
+	pound      = Unit{"lb", Mass, 453.592, false}
+	gram       = Unit{"g", Mass, 1, true}
+	kilogram   = Unit{"kg", Mass, 1000, true}
+)
+
+// units by every way they are commonly written
+var units = map[string]Unit{
+	"t": teaspoon, "tsp": teaspoon, "teaspoon": teaspoon, "teaspoons": teaspoon,
+	"T": tablespoon, "tbsp": tablespoon, "tbs": tablespoon, "tablespoon": tablespoon, "tablespoons": tablespoon,
+	"fl oz": fluidOunce, "fluid ounce": fluidOunce, "fluid ounces": fluidOunce,
+	"c": cup, "cup": cup, "cups": cup,
+	"pt": pint, "pint": pint, "pints": pint,
+	"qt": quart, "quart": quart, "quarts": quart,
+	"gal": gallon, "gallon": gallon, "gallons": gallon,
+	"ml": millilitre, "millilitre": millilitre, "millilitres": millilitre, "milliliter": millilitre, "milliliters": millilitre,
+	"l": litre, "litre": litre, "litres": litre, "liter": litre, "liters": litre,
+	"oz": ounce, "ounce": ounce, "ounces": ounce,
+	"lb": pound, "lbs": pound, "pound": pound, "pounds": pound,
+	"g": gram, "gram": gram, "grams": gram,
+	"kg": kilogram, "kilogram": kilogram, "kilograms": kilogram,
+}
+
+// the units amounts are shown in, largest first
+var displayUnits = map[Dimension]map[bool][]Unit{
+	Volume: {
+		false: {cup, tablespoon, teaspoon},
+		true:  {litre, millilitre},
+	},
+	Mass: {
+		false: {pound, ounce},
+		true:  {kilogram, gram},
+	},
+}
+
+var unicodeFractions = map[rune]string{
+	'¼': "1/4", '½': "1/2", '¾': "3/4",
+	'⅓': "1/3", '⅔': "2/3", '⅛': "1/8",
+}
+
+// a number, "1", "1.5", "1/2" or "1 1/2", optionally a range like "2-3"
+var amountPattern = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d*\.?\d+)(?:\s*(?:-|to)\s*(\d+\s+\d+/\d+|\d+/\d+|\d*\.?\d+))?\s*(.*)$`)
+
+// Quantity is a parsed amount. Unit is the canonical unit name for volumes
+// and masses, or whatever followed the number for counts.
+type Quantity struct {
+	Amount    float64   `json:"amount"`
+	Unit      string    `json:"unit,omitempty"`
+	Dimension Dimension `json:"-"`
+}
+
+// Parse reads an amount like "2 tbsp" or "1 1/2 cups". It reports false for
+// amounts without a number, like "to taste". Ranges use their upper bound so
+// there is always enough.
+func Parse(text string) (Quantity, bool) {
+	text = strings.TrimSpace(text)
+	for r, fraction := range unicodeFractions {
+		text = strings.ReplaceAll(text, string(r), " "+fraction)
+	}
+	text = strings.TrimSpace(text)
+
+	match := amountPattern.FindStringSubmatch(text)
+	if match == nil {
+		return Quantity{}, false
+	}
+
+	amount, ok := parseNumber(match[1])
+	if !ok {
+		return Quantity{}, false
+	}
+	if match[2] != "" {
+		upper, ok := parseNumber(match[2])
+		if ok && upper > amount {
+			amount = upper
+		}
+	}
+
+	name := strings.TrimSuffix(strings.TrimSpace(match[3]), ".")
+	unit, ok := lookupUnit(name)
+	if !ok {
+		return Quantity{Amount: amount, Unit: singular(strings.ToLower(name)), Dimension: Count}, true
+	}
+
+	return Quantity{Amount: amount, Unit: unit.Name, Dimension: unit.Dimension}, true
+}
+
+// NewQuantity is a quantity of amount in unit, which can be any way of
+// writing a unit or a count like "clove"
+func NewQuantity(amount float64, unit string) Quantity {
+	if known, ok := lookupUnit(unit); ok {
+		return Quantity{Amount: amount, Unit: known.Name, Dimension: known.Dimension}
+	}
+	return Quantity{Amount: amount, Unit: singular(strings.ToLower(strings.TrimSpace(unit))), Dimension: Count}
+}
+
+// Add sums two quantities of the same dimension, keeping a's measuring
+// system. It reports false when they can't be added, like a cup and a gram.
+func Add(a Quantity, b Quantity) (Quantity, bool) {
+	if a.Dimension != b.Dimension {
+		return Quantity{}, false
+	}
+
+	if a.Dimension == Count {
+		if a.Unit != b.Unit {
+			return Quantity{}, false
+		}
+		return Quantity{Amount: a.Amount + b.Amount, Unit: a.Unit, Dimension: Count}, true
+	}
+
+	unitA, _ := lookupUnit(a.Unit)
+	unitB, _ := lookupUnit(b.Unit)
+	base := a.Amount*unitA.Factor + b.Amount*unitB.Factor
+	return fromBase(base, a.Dimension, unitA.Metric), true
+}
+
+// Subtract takes b away from a, in a's measuring system, stopping at zero.
+// It reports false when they can't be subtracted.
+func Subtract(a Quantity, b Quantity) (Quantity, bool) {
+	negative := b
+	negative.Amount = -b.Amount
+	difference, ok := Add(a, negative)
+	if !ok {
+		return Quantity{}, false
+	}
+	if difference.Amount < 0.001 {
+		difference.Amount = 0
+		difference.Unit = a.Unit
+	}
+	return difference, true
+}
+
+// Scale multiplies the amount, for making more or fewer servings
+func (quantity Quantity) Scale(factor float64) Quantity {
+	if quantity.Dimension == Count {
+		quantity.Amount *= factor
+		return quantity
+	}
+	unit, _ := lookupUnit(quantity.Unit)
+	return fromBase(quantity.Amount*factor*unit.Factor, quantity.Dimension, unit.Metric)
+}
+
+// Base is the amount in millilitres, grams, or itself for counts
+func (quantity Quantity) Base() float64 {
+	unit, ok := lookupUnit(quantity.Unit)
+	if quantity.Dimension == Count || !ok {
+		return quantity.Amount
+	}
+	return quantity.Amount * unit.Factor
+}
+
+// String formats the quantity the way a recipe would, "1 1/2 cups"
+func (quantity Quantity) String() string {
+	amount := formatAmount(quantity.Amount, quantity.Dimension != Count || quantity.Unit != "")
+	if quantity.Unit == "" {
+		return amount
+	}
+	if amount == "1" || strings.HasPrefix(amount, "1/") || strings.HasPrefix(amount, "2/") || strings.HasPrefix(amount, "3/") {
+		return amount + " " + quantity.Unit
+	}
+	return amount + " " + plural(quantity.Unit)
+}
+
+// Normalize is the key used to tell whether two ingredient names are the
+// same ingredient
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Matches reports whether two ingredient names are for the same thing,
+// allowing one to be more specific, so "spinach" matches "baby spinach" and
+// "egg" matches "eggs"
+func Matches(a string, b string) bool {
+	a, b = singularWords(a), singularWords(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ")
+}
+
+// - MARK: Helper Functions
+
+func lookupUnit(name string) (Unit, bool) {
+	// "T" and "t" are the only units where case matters
+	if unit, ok := units[name]; ok {
+		return unit, true
+	}
+	unit, ok := units[strings.ToLower(name)]
+	return unit, ok
+}
+
+// picks the largest display unit the amount comes out as a kitchen fraction
+// in, falling back to the smallest
+func fromBase(base float64, dimension Dimension, metric bool) Quantity {
+	candidates := displayUnits[dimension][metric]
+	for _, unit := range candidates {
+		amount := base / unit.Factor
+		if amount > 0.98 && isKitchenFraction(amount) {
+			return Quantity{Amount: roundFraction(amount), Unit: unit.Name, Dimension: dimension}
+		}
+		// quarter and third cups are fine too
+		if unit == cup && amount >= 0.25 && isKitchenFraction(amount) {
+			return Quantity{Amount: roundFraction(amount), Unit: unit.Name, Dimension: dimension}
+		}
+	}
+
+	smallest := candidates[len(candidates)-1]
+	amount := base / smallest.Factor
+	if metric {
+		amount = math.Round(amount)
+	} else {
+		amount = roundFraction(amount)
+	}
+	return Quantity{Amount: amount, Unit: smallest.Name, Dimension: dimension}
+}
+
+// whether the amount is close to a whole number of quarters or thirds
+func isKitchenFraction(amount float64) bool {
+	return math.Abs(amount-roundFraction(amount)) < 0.02
+}
+
+// rounds to the nearest quarter or third
+func roundFraction(amount float64) float64 {
+	quarters := math.Round(amount*4) / 4
+	thirds := math.Round(amount*3) / 3
+	if math.Abs(amount-thirds) < math.Abs(amount-quarters) {
+		return thirds
+	}
+	return quarters
+}
+
+var fractionNames = []struct {
+	value float64
+	name  string
+}{
+	{1.0 / 4, "1/4"}, {1.0 / 3, "1/3"}, {1.0 / 2, "1/2"}, {2.0 / 3, "2/3"}, {3.0 / 4, "3/4"},
+}
+
+func formatAmount(amount float64, fractions bool) string {
+	whole, rest := math.Modf(amount)
+	if rest < 0.01 {
+		return strconv.Itoa(int(whole))
+	}
+	if rest > 0.99 {
+		return strconv.Itoa(int(whole) + 1)
+	}
+
+	if fractions {
+		for _, fraction := range fractionNames {
+			if math.Abs(rest-fraction.value) < 0.01 {
+				if whole == 0 {
+					return fraction.name
+				}
+				return fmt.Sprintf("%d %s", int(whole), fraction.name)
+			}
+		}
+	}
+
+	return strconv.FormatFloat(math.Round(amount*100)/100, 'f', -1, 64)
+}
+
+func parseNumber(text string) (float64, bool) {
+	fields := strings.Fields(text)
+	total := 0.0
+	for _, field := range fields {
+		if parts := strings.SplitN(field, "/", 2); len(parts) == 2 {
+			numerator, err := strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return 0, false
+			}
+			denominator, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || denominator == 0 {
+				return 0, false
+			}
+			total += numerator / denominator
+			continue
+		}
+
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += value
+	}
+	return total, len(fields) > 0
+}
+
+// abbreviations stay the same, everything else gets an s
+func plural(unit string) string {
+	if _, ok := units[unit]; ok && unit != "cup" && unit != "pint" {
+		return unit
+	}
+	if strings.HasSuffix(unit, "ch") || strings.HasSuffix(unit, "sh") {
+		return unit + "es"
+	}
+	return unit + "s"
+}
+
+func singularWords(name string) string {
+	words := strings.Fields(Normalize(name))
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// "cloves" and "clove" should add up, "slices" and "slice" too
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
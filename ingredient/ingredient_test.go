@@ -0,0 +1,148 @@
+package ingredient
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Quantity
+	}{
+		{"2 tbsp", Quantity{2, "tbsp", Volume}},
+		{"1/4 cup", Quantity{0.25, "cup", Volume}},
+		{"1 1/2 Cups", Quantity{1.5, "cup", Volume}},
+		{"½ tsp", Quantity{0.5, "tsp", Volume}},
+		{"1½ T", Quantity{1.5, "tbsp", Volume}},
+		{"500g", Quantity{500, "g", Mass}},
+		{"2-3 lbs", Quantity{3, "lb", Mass}},
+		{"3", Quantity{3, "", Count}},
+		{"2 cloves", Quantity{2, "clove", Count}},
+		{"0.5 l", Quantity{0.5, "l", Volume}},
+	}
+
+	for _, test := range tests {
+		quantity, ok := Parse(test.text)
+		if !ok {
+			t.Errorf("Expected %q to parse", test.text)
+			continue
+		}
+		if quantity != test.expected {
+			t.Errorf("Parse(%q) = %+v, expected %+v", test.text, quantity, test.expected)
+		}
+	}
+
+	for _, text := range []string{"", "to taste", "a pinch"} {
+		if _, ok := Parse(text); ok {
+			t.Errorf("Expected %q not to parse", text)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"2 tbsp", "1/4 cup", "6 tbsp"},
+		{"1 cup", "1/2 cup", "1 1/2 cups"},
+		{"1/2 cup", "1/4 cup", "3/4 cup"},
+		{"1 tsp", "1 tsp", "2 tsp"},
+		{"3 cups", "1 cup", "4 cups"},
+		{"1 quart", "1 cup", "5 cups"},
+		{"500 ml", "1 l", "1 1/2 l"},
+		{"8 oz", "8 oz", "1 lb"},
+		{"2 cloves", "1 clove", "3 cloves"},
+		{"2", "1", "3"},
+	}
+
+	for _, test := range tests {
+		a, _ := Parse(test.a)
+		b, _ := Parse(test.b)
+		sum, ok := Add(a, b)
+		if !ok {
+			t.Errorf("Expected %q and %q to add", test.a, test.b)
+			continue
+		}
+		if sum.String() != test.expected {
+			t.Errorf("%q + %q = %q, expected %q", test.a, test.b, sum.String(), test.expected)
+		}
+	}
+
+	cup, _ := Parse("1 cup")
+	gram, _ := Parse("100 g")
+	if _, ok := Add(cup, gram); ok {
+		t.Error("Expected a volume and a mass not to add")
+	}
+
+	cloves, _ := Parse("2 cloves")
+	count, _ := Parse("2")
+	if _, ok := Add(cloves, count); ok {
+		t.Error("Expected different count units not to add")
+	}
+}
+
+func TestScale(t *testing.T) {
+	quantity, _ := Parse("1/2 cup")
+	if scaled := quantity.Scale(2).String(); scaled != "1 cup" {
+		t.Errorf("Expected 1 cup, got %q", scaled)
+	}
+	if scaled := quantity.Scale(0.25).String(); scaled != "2 tbsp" {
+		t.Errorf("Expected 2 tbsp, got %q", scaled)
+	}
+}
+
+func TestAisle(t *testing.T) {
+	tests := map[string]string{
+		"Butter":        "Dairy & Eggs",
+		"peanut butter": "Pantry",
+		"garlic cloves": "Produce",
+		"black pepper":  "Spices & Seasonings",
+		"chicken thigh": "Meat & Seafood",
+		"eggplant":      "Produce",
+		"rolled oats":   "Pantry",
+		"xanthan gum":   "Other",
+	}
+
+	for name, expected := range tests {
+		if aisle := Aisle(name); aisle != expected {
+			t.Errorf("Aisle(%q) = %q, expected %q", name, aisle, expected)
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	cup, _ := Parse("1 cup")
+	quarter, _ := Parse("1/4 cup")
+	difference, ok := Subtract(cup, quarter)
+	if !ok || difference.String() != "3/4 cup" {
+		t.Errorf("Expected 3/4 cup, got %q", difference.String())
+	}
+
+	difference, ok = Subtract(quarter, cup)
+	if !ok || difference.Amount != 0 {
+		t.Errorf("Expected subtracting too much to stop at zero, got %+v", difference)
+	}
+
+	if quantity := NewQuantity(2, "Cloves"); quantity != (Quantity{2, "clove", Count}) {
+		t.Errorf("Expected 2 cloves, got %+v", quantity)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"spinach", "baby spinach", true},
+		{"Eggs", "egg", true},
+		{"red onions", "onion", true},
+		{"butter", "buttermilk", false},
+		{"rice", "licorice", false},
+		{"", "rice", false},
+	}
+
+	for _, test := range tests {
+		if Matches(test.a, test.b) != test.expected {
+			t.Errorf("Matches(%q, %q) should be %v", test.a, test.b, test.expected)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package ingredient
+
+import (
+	"sort"
+	"strings"
+)
+
+// Allergens
+const (
+	Gluten    = "gluten"
+	Dairy     = "dairy"
+	Egg       = "egg"
+	TreeNuts  = "nuts"
+	Peanuts   = "peanuts"
+	Shellfish = "shellfish"
+	Fish      = "fish"
+	Soy       = "soy"
+	Sesame    = "sesame"
+)
+
+// Allergens are every allergen ingredients are checked for
+var Allergens = []string{Gluten, Dairy, Egg, TreeNuts, Peanuts, Shellfish, Fish, Soy, Sesame}
+
+// Dietary labels
+const (
+	Vegetarian   = "vegetarian"
+	Vegan        = "vegan"
+	KetoFriendly = "keto-friendly"
+)
+
+// Diets are every dietary label a recipe can have
+var Diets = []string{Vegetarian, Vegan, KetoFriendly}
+
+// groups of ingredients that decide dietary labels, alongside the allergens
+const (
+	meat     = "meat"
+	gelatin  = "gelatin"
+	honey    = "honey"
+	highCarb = "high-carb"
+)
+
+// a group of ingredients, the names that put an ingredient in it and the
+// names that look like they should but don't
+type category struct {
+	keywords []string
+	except   []string
+}
+
+// taxonomy is what ingredients are in each group. Keywords match whole
+// words, so "egg" doesn't match "eggplant". Keep the exceptions up to date
+// when a new false positive turns up, people rely on the allergens.
+var taxonomy = map[string]category{
+	Gluten: {
+		keywords: []string{
+			"flour", "wheat", "bread", "breadcrumb", "panko", "pasta",
+			"spaghetti", "macaroni", "penne", "fettuccine", "linguine", "lasagna",
+			"noodle", "barley", "rye", "couscous", "semolina", "bulgur", "farro",
+			"spelt", "seitan", "tortilla", "cracker", "pita", "bagel", "croissant",
+			"bun", "baguette", "beer", "soy sauce", "malt", "graham cracker",
+			"pie crust", "puff pastry", "phyllo", "cake", "cookie",
+		},
+		except: []string{
+			"gluten-free", "gluten free", "almond flour", "coconut flour",
+			"rice flour", "corn flour", "chickpea flour", "oat flour",
+			"tapioca flour", "cassava flour", "corn tortilla", "rice noodle",
+			"glass noodle", "rice paper", "buckwheat", "tamari", "zucchini noodle",
+		},
+	},
+	Dairy: {
+		keywords: []string{
+			"milk", "butter", "buttermilk", "cream", "cheese", "yogurt", "yoghurt",
+			"ghee", "whey", "casein", "parmesan", "pecorino", "mozzarella",
+			"ricotta", "feta", "cheddar", "mascarpone", "brie", "gouda", "gruyere",
+			"half and half", "creme fraiche", "kefir", "custard", "ice cream",
+		},
+		except: []string{
+			"almond milk", "oat milk", "soy milk", "coconut milk", "rice milk",
+			"cashew milk", "coconut cream", "peanut butter", "almond butter",
+			"cashew butter", "apple butter", "cocoa butter", "sunflower butter",
+			"cream of tartar", "dairy-free", "dairy free", "vegan",
+			"coconut yogurt", "nutritional yeast",
+		},
+	},
+	Egg: {
+		keywords: []string{"egg", "egg white", "egg yolk", "mayonnaise", "mayo", "meringue", "aioli"},
+		except:   []string{"egg-free", "egg free", "vegan mayonnaise", "vegan mayo", "egg replacer"},
+	},
+	TreeNuts: {
+		keywords: []string{
+			"nut", "almond", "cashew", "walnut", "pecan", "pistachio", "hazelnut",
+			"macadamia", "brazil nut", "pine nut", "chestnut", "praline",
+			"marzipan", "nutella", "frangipane",
+		},
+		except: []string{"nut-free", "nut free", "water chestnut"},
+	},
+	Peanuts: {
+		keywords: []string{"peanut", "groundnut", "peanut butter", "satay"},
+		except:   []string{"peanut-free", "peanut free"},
+	},
+	Shellfish: {
+		keywords: []string{
+			"shrimp", "prawn", "crab", "lobster", "scallop", "clam", "mussel",
+			"oyster", "crawfish", "crayfish", "langoustine", "oyster sauce",
+		},
+		except: []string{"oyster mushroom", "imitation crab"},
+	},
+	Fish: {
+		keywords: []string{
+			"fish", "salmon", "tuna", "cod", "tilapia", "anchovy", "anchovies",
+			"sardine", "trout", "halibut", "mackerel", "haddock", "snapper",
+			"bass", "fish sauce", "worcestershire", "imitation crab",
+		},
+	},
+	Soy: {
+		keywords: []string{
+			"soy", "soya", "soybean", "soy sauce", "tofu", "edamame", "tempeh",
+			"miso", "tamari", "soy milk",
+		},
+	},
+	Sesame: {
+		keywords: []string{"sesame", "tahini", "halva", "za'atar"},
+	},
+	meat: {
+		keywords: []string{
+			"beef", "pork", "bacon", "ham", "lamb", "mutton", "veal", "venison",
+			"sausage", "chorizo", "prosciutto", "pancetta", "salami", "pepperoni",
+			"chicken", "turkey", "duck", "goose", "steak", "mince", "ground beef",
+			"lard", "suet", "bone broth",
+		},
+		except: []string{
+			"vegan", "vegetarian", "plant-based", "meatless", "impossible",
+			"beyond",
+		},
+	},
+	gelatin: {
+		keywords: []string{"gelatin", "gelatine", "marshmallow"},
+		except:   []string{"vegan"},
+	},
+	honey: {
+		keywords: []string{"honey"},
+		except:   []string{"honeydew"},
+	},
+	highCarb: {
+		keywords: []string{
+			"sugar", "brown sugar", "flour", "rice", "pasta", "spaghetti",
+			"macaroni", "penne", "fettuccine", "linguine", "lasagna", "noodle",
+			"bread", "breadcrumb", "panko", "bun", "bagel", "baguette", "pita",
+			"croissant", "pie crust", "puff pastry", "phyllo", "cake", "cookie",
+			"graham cracker", "potato", "honey", "maple syrup", "syrup", "oat",
+			"oats", "corn", "cornmeal", "polenta", "barley", "bulgur", "farro",
+			"semolina", "millet", "bean", "lentil", "chickpea", "pea", "banana",
+			"apple", "pear", "mango", "pineapple", "juice", "quinoa", "couscous",
+			"cracker", "cereal", "tortilla", "molasses", "agave", "date",
+			"raisin", "jam", "marshmallow", "chocolate chip", "beer",
+		},
+		except: []string{
+			"sugar-free", "sugar free", "almond flour", "coconut flour",
+			"cauliflower rice", "green bean", "rice vinegar", "corn starch",
+			"cornstarch", "shirataki", "zucchini noodle", "apple cider vinegar",
+			"cider vinegar", "lemon juice", "lime juice", "snow pea",
+			"sugar snap pea",
+		},
+	},
+}
+
+// Classify returns the allergens and groups an ingredient is in
+func Classify(name string) []string {
+	words := " " + singularWords(name) + " "
+
+	groups := []string{}
+	for group, category := range taxonomy {
+		if matchesAny(words, category.keywords) && !matchesAny(words, category.except) {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Strings(groups)
+	return groups
+}
+
+// Labels works out the allergens and dietary labels of a recipe from it's
+// ingredient names. Unknown are the ingredients the taxonomy knows nothing
+// about, so the labels can't vouch for them.
+func Labels(names []string) (allergens []string, diets []string, unknown []string) {
+	found := map[string]bool{}
+	unknown = []string{}
+	for _, name := range names {
+		groups := Classify(name)
+		for _, group := range groups {
+			found[group] = true
+		}
+		if len(groups) == 0 && !Known(name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	allergens = []string{}
+	for _, allergen := range Allergens {
+		if found[allergen] {
+			allergens = append(allergens, allergen)
+		}
+	}
+
+	diets = []string{}
+	vegetarian := !found[meat] && !found[Fish] && !found[Shellfish] && !found[gelatin]
+	if vegetarian {
+		diets = append(diets, Vegetarian)
+	}
+	if vegetarian && !found[Dairy] && !found[Egg] && !found[honey] {
+		diets = append(diets, Vegan)
+	}
+	if !found[highCarb] {
+		diets = append(diets, KetoFriendly)
+	}
+
+	sort.Strings(unknown)
+	return allergens, diets, unknown
+}
+
+// Known reports whether the taxonomy knows anything about an ingredient,
+// either the groups it's in or where it's found in the store
+func Known(name string) bool {
+	return len(Classify(name)) > 0 || Aisle(name) != "Other"
+}
+
+// ValidAllergen reports whether allergen is one of Allergens
+func ValidAllergen(allergen string) bool {
+	return contains(Allergens, allergen)
+}
+
+// ValidDiet reports whether diet is one of Diets
+func ValidDiet(diet string) bool {
+	return contains(Diets, diet)
+}
+
+func matchesAny(words string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(words, " "+singularWords(phrase)+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
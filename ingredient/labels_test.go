@@ -0,0 +1,91 @@
+package ingredient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string][]string{
+		"all-purpose flour":     {Gluten, highCarb},
+		"almond flour":          {TreeNuts},
+		"unsalted butter":       {Dairy},
+		"peanut butter":         {Peanuts},
+		"butternut squash":      {},
+		"eggs":                  {Egg},
+		"eggplant":              {},
+		"nutmeg":                {},
+		"coconut milk":          {},
+		"soy sauce":             {Gluten, Soy},
+		"anchovies":             {Fish},
+		"shrimp":                {Shellfish},
+		"oyster mushrooms":      {},
+		"chicken stock":         {meat},
+		"vegan sausage":         {},
+		"cream of tartar":       {},
+		"toasted sesame oil":    {Sesame},
+		"cauliflower rice":      {},
+		"chopped walnuts":       {TreeNuts},
+		"water chestnuts":       {},
+		"green beans":           {},
+		"dried kidney beans":    {highCarb},
+		"gluten-free spaghetti": {highCarb},
+		"granny smith apples":   {highCarb},
+		"pie crust":             {Gluten, highCarb},
+		"puff pastry":           {Gluten, highCarb},
+		"frozen peas":           {highCarb},
+		"apple cider vinegar":   {},
+		"zucchini noodles":      {},
+		"lemon juice":           {},
+	}
+
+	for name, expected := range tests {
+		groups := Classify(name)
+		if len(groups) == 0 && len(expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(groups, expected) {
+			t.Errorf("Classify(%q) = %v, expected %v", name, groups, expected)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	allergens, diets, unknown := Labels([]string{"spaghetti", "olive oil", "garlic", "parmesan", "xanthan gum"})
+
+	if !reflect.DeepEqual(allergens, []string{Gluten, Dairy}) {
+		t.Errorf("Wrong allergens: %v", allergens)
+	}
+	if !reflect.DeepEqual(diets, []string{Vegetarian}) {
+		t.Errorf("Wrong diets: %v", diets)
+	}
+	if !reflect.DeepEqual(unknown, []string{"xanthan gum"}) {
+		t.Errorf("Wrong unknown ingredients: %v", unknown)
+	}
+
+	_, diets, _ = Labels([]string{"salmon", "butter", "lemon", "salt"})
+	if !reflect.DeepEqual(diets, []string{KetoFriendly}) {
+		t.Errorf("Expected salmon in butter to only be keto-friendly, got %v", diets)
+	}
+
+	_, diets, _ = Labels([]string{"tofu", "broccoli", "sesame seeds"})
+	if !reflect.DeepEqual(diets, []string{Vegetarian, Vegan, KetoFriendly}) {
+		t.Errorf("Expected tofu and broccoli to be vegan, got %v", diets)
+	}
+}
+
+func TestConstraintsAllows(t *testing.T) {
+	dairyFree := Constraints{Without: []string{Dairy}}
+	if dairyFree.Allows("buttermilk") || !dairyFree.Allows("oat milk") {
+		t.Error("Dairy free should rule out buttermilk and allow oat milk")
+	}
+
+	vegan := Constraints{Diets: []string{Vegan}}
+	if vegan.Allows("eggs") || vegan.Allows("honey") || !vegan.Allows("maple syrup") {
+		t.Error("Vegan should rule out eggs and honey and allow maple syrup")
+	}
+
+	if dairyFree.Allows("xanthan gum") || !(Constraints{}).Allows("xanthan gum") {
+		t.Error("Unknown ingredients should only be allowed without constraints")
+	}
+}
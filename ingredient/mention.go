@@ -0,0 +1,293 @@
+package ingredient
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mention is an ingredient a recipe step uses
+type Mention struct {
+	// Name is the ingredient as it's listed in the recipe
+	Name string `json:"name"`
+	// Amount is how much the step uses, when the step says, or the whole
+	// amount the recipe lists otherwise
+	Amount string `json:"amount,omitempty"`
+}
+
+var wordPattern = regexp.MustCompile(`\p{L}+`)
+
+// descriptors describe an ingredient without naming it, so a step saying
+// "fresh" or "brown" doesn't mention every fresh or brown ingredient
+var descriptors = map[string]bool{
+	"a": true, "all": true, "and": true, "black": true, "boneless": true,
+	"brown": true, "canned": true, "chopped": true, "cold": true,
+	"cooked": true, "dark": true, "diced": true, "dried": true, "extra": true,
+	"finely": true, "for": true, "fresh": true, "frozen": true, "grated": true,
+	"green": true, "ground": true, "hot": true, "large": true, "light": true,
+	"medium": true, "melted": true, "minced": true, "of": true,
+	"optional": true, "or": true, "packed": true, "plus": true,
+	"purpose": true, "raw": true, "red": true, "room": true, "roughly": true,
+	"salted": true, "shredded": true, "skinless": true, "sliced": true,
+	"small": true, "softened": true, "taste": true, "temperature": true,
+	"the": true, "to": true, "unsalted": true, "virgin": true, "warm": true,
+	"white": true, "whole": true, "yellow": true,
+}
+
+// pieces an ingredient comes in, which name it less than the word before
+// them, so "garlic cloves" is garlic
+var pieceWords = map[string]bool{
+	"bunch": true, "clove": true, "piece": true, "sprig": true, "stalk": true,
+}
+
+// words that start what a verb acts on, so "cream the butter" creams the
+// butter rather than using cream
+var determiners = map[string]bool{
+	"a": true, "an": true, "all": true, "both": true, "each": true,
+	"it": true, "some": true, "the": true, "them": true, "your": true,
+}
+
+// fractions a step can use of an ingredient instead of an amount, "half
+// the butter"
+var fractionWords = map[string]float64{"half": 0.5, "third": 1.0 / 3, "quarter": 0.25}
+
+// Mentions finds the ingredients a step uses, in the order it mentions
+// them. Ingredients is the recipe's ingredients and their amounts.
+//
+// An ingredient is mentioned when part of it's name is, so "flour" and
+// "all-purpose flour" both mention "all-purpose flour", as long as the part
+// includes the word the name is about. That's the last word that isn't a
+// descriptor like "fresh", so "cream" doesn't mention cream cheese and
+// "baking sheet" doesn't mention baking powder. When more than one ingredient
+// could be meant, the one whose name the step matches best wins, so "olive
+// oil" means olive oil and not vegetable oil. A name starting a clause and
+// followed by "the" or the like is a verb, so "cream the butter" doesn't
+// mention cream. Amounts right before the ingredient, "add 1 cup of the
+// sugar" or "2 cloves garlic", are what the step uses.
+func Mentions(text string, ingredients map[string]string) []Mention {
+	text = strings.ToLower(text)
+	words := wordPattern.FindAllStringIndex(text, -1)
+	stepWords := make([]string, len(words))
+	for i, word := range words {
+		stepWords[i] = singular(text[word[0]:word[1]])
+	}
+
+	candidates := []mentionCandidate{}
+	for name := range ingredients {
+		nameWords := ingredientWords(name)
+		head := headWord(nameWords)
+		for start := range stepWords {
+			for end := start + 1; end <= len(stepWords) && end-start <= len(nameWords); end++ {
+				if !containsRun(nameWords, stepWords[start:end], head) || onlyDescriptors(stepWords[start:end]) {
+					continue
+				}
+				if usedAsVerb(text, words, stepWords, start, end) {
+					continue
+				}
+				candidates = append(candidates, mentionCandidate{
+					name:  name,
+					start: start,
+					end:   end,
+					score: float64(end-start) + float64(end-start)/float64(len(nameWords)),
+				})
+			}
+		}
+	}
+
+	// the best matches claim their words first
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].start != candidates[j].start {
+			return candidates[i].start < candidates[j].start
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	claimed := make([]*mentionCandidate, len(stepWords))
+	accepted := []mentionCandidate{}
+	for i := range candidates {
+		candidate := &candidates[i]
+		if !candidate.claim(claimed) {
+			continue
+		}
+		accepted = append(accepted, *candidate)
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].start != accepted[j].start {
+			return accepted[i].start < accepted[j].start
+		}
+		return accepted[i].name < accepted[j].name
+	})
+	mentions := []Mention{}
+	seen := map[string]bool{}
+	for _, candidate := range accepted {
+		if seen[candidate.name] {
+			continue
+		}
+		seen[candidate.name] = true
+		amount := stepAmount(text[:words[candidate.start][0]], ingredients[candidate.name])
+		mentions = append(mentions, Mention{Name: candidate.name, Amount: amount})
+	}
+	return mentions
+}
+
+// - MARK: Helper Functions
+
+// mentionCandidate is a run of a step's words that could mention an
+// ingredient, scored by how many words match and how much of the name
+type mentionCandidate struct {
+	name       string
+	start, end int
+	score      float64
+}
+
+// claims the candidate's words, unless a better candidate has. Candidates
+// matching the same words equally well share them.
+func (candidate *mentionCandidate) claim(claimed []*mentionCandidate) bool {
+	for i := candidate.start; i < candidate.end; i++ {
+		other := claimed[i]
+		if other == nil {
+			continue
+		}
+		if other.score != candidate.score || other.start != candidate.start || other.end != candidate.end {
+			return false
+		}
+	}
+	for i := candidate.start; i < candidate.end; i++ {
+		if claimed[i] == nil {
+			claimed[i] = candidate
+		}
+	}
+	return true
+}
+
+// the words of an ingredient's name, leaving out notes after a comma or in
+// brackets, "butter, softened"
+func ingredientWords(name string) []string {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, ",("); i >= 0 {
+		name = name[:i]
+	}
+	words := wordPattern.FindAllString(name, -1)
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	return words
+}
+
+// the index of the word an ingredient's name is about, the last one that
+// isn't a descriptor or a piece it comes in
+func headWord(words []string) int {
+	for i := len(words) - 1; i >= 0; i-- {
+		if !descriptors[words[i]] && !pieceWords[words[i]] {
+			return i
+		}
+	}
+	return len(words) - 1
+}
+
+// reports whether run is in words somewhere covering the head word
+func containsRun(words []string, run []string, head int) bool {
+	for i := 0; i+len(run) <= len(words); i++ {
+		if head < i || head >= i+len(run) {
+			continue
+		}
+		matched := true
+		for j := range run {
+			if words[i+j] != run[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// reports whether the words from start to end begin a clause and are
+// followed by a determiner, like "cream" in "cream the butter"
+func usedAsVerb(text string, words [][]int, stepWords []string, start int, end int) bool {
+	if end >= len(stepWords) || !determiners[stepWords[end]] {
+		return false
+	}
+	if start == 0 {
+		return true
+	}
+	between := text[words[start-1][1]:words[start][0]]
+	return strings.ContainsAny(between, ".,;:!?") || stepWords[start-1] == "then" || stepWords[start-1] == "and"
+}
+
+func onlyDescriptors(words []string) bool {
+	for _, word := range words {
+		if !descriptors[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// how much of an ingredient a step uses, going by the words before it's
+// mentioned, or the whole amount when they don't say
+func stepAmount(before string, whole string) string {
+	words := strings.Fields(before)
+	for len(words) > 0 && (words[len(words)-1] == "the" || words[len(words)-1] == "of") {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return whole
+	}
+
+	wholeQuantity, wholeParsed := Parse(whole)
+	if fraction, ok := fractionWords[words[len(words)-1]]; ok {
+		if !wholeParsed {
+			return whole
+		}
+		return wholeQuantity.Scale(fraction).String()
+	}
+
+	// the longest run of words that reads as an amount, "1 1/2 cups"
+	for k := 4; k > 0; k-- {
+		if k > len(words) {
+			continue
+		}
+		text := strings.Join(words[len(words)-k:], " ")
+		if !startsWithNumber(text) {
+			continue
+		}
+		quantity, ok := Parse(text)
+		if !ok {
+			continue
+		}
+		// "2 cloves garlic" is counted in the pieces the recipe lists, or
+		// in cloves if it lists something else, like a head
+		if quantity.Dimension == Count && pieceWords[singular(quantity.Unit)] {
+			if wholeParsed && wholeQuantity.Dimension == Count && wholeQuantity.Unit == "" {
+				quantity.Unit = ""
+			}
+			return quantity.String()
+		}
+		// counts only when they're counted the same way, so "bake for 20
+		// minutes" isn't an amount
+		if quantity.Dimension == Count && quantity.Unit != "" && (!wholeParsed || wholeQuantity.Unit != quantity.Unit) {
+			continue
+		}
+		// "2 garlic cloves" is counted in the cloves the recipe lists
+		if quantity.Dimension == Count && quantity.Unit == "" && wholeParsed && wholeQuantity.Dimension == Count {
+			quantity.Unit = wholeQuantity.Unit
+		}
+		return quantity.String()
+	}
+	return whole
+}
+
+func startsWithNumber(text string) bool {
+	for _, r := range text {
+		_, fraction := unicodeFractions[r]
+		return (r >= '0' && r <= '9') || fraction
+	}
+	return false
+}
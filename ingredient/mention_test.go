@@ -0,0 +1,95 @@
+package ingredient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	ingredients := map[string]string{
+		"all-purpose flour":         "2 cups",
+		"sugar":                     "1 cup",
+		"brown sugar":               "1/2 cup",
+		"large eggs":                "3",
+		"unsalted butter, softened": "8 tbsp",
+		"olive oil":                 "2 tbsp",
+		"vegetable oil":             "1 cup",
+		"garlic cloves":             "4 cloves",
+		"salt":                      "to taste",
+		"heavy cream":               "1/2 cup",
+	}
+
+	tests := []struct {
+		text     string
+		expected []Mention
+	}{
+		{"Whisk the flour and salt together.", []Mention{{"all-purpose flour", "2 cups"}, {"salt", "to taste"}}},
+		{"Beat half the butter with 3/4 cup of the sugar", []Mention{{"unsalted butter, softened", "1/4 cup"}, {"sugar", "3/4 cup"}}},
+		{"Add the brown sugar, then 2 eggs", []Mention{{"brown sugar", "1/2 cup"}, {"large eggs", "2"}}},
+		{"Heat the olive oil and fry 2 garlic cloves", []Mention{{"olive oil", "2 tbsp"}, {"garlic cloves", "2 cloves"}}},
+		{"Heat the oil", []Mention{{"olive oil", "2 tbsp"}, {"vegetable oil", "1 cup"}}},
+		{"Bake for 20 minutes until brown", []Mention{}},
+		{"Cream the butter and sugar", []Mention{{"unsalted butter, softened", "8 tbsp"}, {"sugar", "1 cup"}}},
+		{"Let it cool, then cream the butter", []Mention{{"unsalted butter, softened", "8 tbsp"}}},
+		{"Whisk in the cream", []Mention{{"heavy cream", "1/2 cup"}}},
+	}
+
+	for _, test := range tests {
+		mentions := Mentions(test.text, ingredients)
+		if !reflect.DeepEqual(mentions, test.expected) {
+			t.Errorf("Mentions(%q) = %+v, expected %+v", test.text, mentions, test.expected)
+		}
+	}
+}
+
+func TestMentionsNeedTheHeadWord(t *testing.T) {
+	ingredients := map[string]string{
+		"baking powder": "1 tsp",
+		"baking soda":   "1/2 tsp",
+		"chicken":       "1 lb",
+		"chicken stock": "2 cups",
+		"cream cheese":  "8 oz",
+		"heavy cream":   "1 cup",
+		"garlic cloves": "4 cloves",
+	}
+
+	tests := []struct {
+		text     string
+		expected []Mention
+	}{
+		{"Line a baking sheet with parchment", []Mention{}},
+		{"Add the chicken", []Mention{{"chicken", "1 lb"}}},
+		{"Whip the cream", []Mention{{"heavy cream", "1 cup"}}},
+		{"Pour in the stock", []Mention{{"chicken stock", "2 cups"}}},
+		{"Mince the garlic", []Mention{{"garlic cloves", "4 cloves"}}},
+		{"Sift in the baking soda", []Mention{{"baking soda", "1/2 tsp"}}},
+		{"Fold in the cream", []Mention{{"heavy cream", "1 cup"}}},
+	}
+
+	for _, test := range tests {
+		mentions := Mentions(test.text, ingredients)
+		if !reflect.DeepEqual(mentions, test.expected) {
+			t.Errorf("Mentions(%q) = %+v, expected %+v", test.text, mentions, test.expected)
+		}
+	}
+}
+
+func TestMentionsCountedInPieces(t *testing.T) {
+	tests := []struct {
+		text        string
+		ingredients map[string]string
+		expected    []Mention
+	}{
+		{"Fry 2 cloves garlic", map[string]string{"garlic": "4"}, []Mention{{"garlic", "2"}}},
+		{"Fry 2 cloves garlic", map[string]string{"garlic cloves": "4 cloves"}, []Mention{{"garlic cloves", "2 cloves"}}},
+		{"Fry 2 cloves of garlic", map[string]string{"garlic": "1 head"}, []Mention{{"garlic", "2 cloves"}}},
+		{"Add 3 sprigs thyme", map[string]string{"fresh thyme": "1 bunch"}, []Mention{{"fresh thyme", "3 sprigs"}}},
+	}
+
+	for _, test := range tests {
+		mentions := Mentions(test.text, test.ingredients)
+		if !reflect.DeepEqual(mentions, test.expected) {
+			t.Errorf("Mentions(%q) = %+v, expected %+v", test.text, mentions, test.expected)
+		}
+	}
+}
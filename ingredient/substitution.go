@@ -0,0 +1,218 @@
+package ingredient
+
+import "strings"
+
+// Substitute is a way to replace an ingredient
+type Substitute struct {
+	Ingredients []Replacement `json:"ingredients"`
+	Notes       string        `json:"notes,omitempty"`
+}
+
+// Replacement is one ingredient of a substitute. Without a Unit, Ratio is
+// how much to use for each of the original's units, so 0.75 of 1 cup is 3/4
+// cup. With a Unit, Ratio is how many of that unit to use for each of the
+// original's units, so 3 tbsp of water for each egg.
+type Replacement struct {
+	Name  string  `json:"name"`
+	Ratio float64 `json:"ratio"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// Amount is how much of the replacement to use in place of quantity of the
+// original
+func (replacement Replacement) Amount(quantity Quantity) Quantity {
+	if replacement.Unit == "" {
+		return quantity.Scale(replacement.Ratio)
+	}
+	return NewQuantity(quantity.Amount*replacement.Ratio, replacement.Unit).Scale(1)
+}
+
+// shorthand for substitutes that swap one ingredient for another
+func swap(name string, ratio float64, notes string) Substitute {
+	return Substitute{Ingredients: []Replacement{{Name: name, Ratio: ratio}}, Notes: notes}
+}
+
+// substitutions is the curated substitution graph, from an ingredient to the
+// ways it can be replaced, best first
+var substitutions = map[string][]Substitute{
+	"buttermilk": {
+		{Ingredients: []Replacement{{"milk", 1, ""}, {"lemon juice", 0.0625, ""}}, Notes: "stir and let stand 5 minutes"},
+		{Ingredients: []Replacement{{"plain yogurt", 0.75, ""}, {"milk", 0.25, ""}}},
+		{Ingredients: []Replacement{{"soy milk", 1, ""}, {"lemon juice", 0.0625, ""}}, Notes: "stir and let stand 5 minutes"},
+	},
+	"butter": {
+		swap("vegan butter", 1, ""),
+		swap("coconut oil", 1, "best for baking"),
+		swap("olive oil", 0.75, "best for cooking rather than baking"),
+	},
+	"milk": {
+		swap("oat milk", 1, ""),
+		swap("soy milk", 1, ""),
+		swap("almond milk", 1, ""),
+	},
+	"heavy cream": {
+		swap("coconut cream", 1, ""),
+		{Ingredients: []Replacement{{"milk", 0.75, ""}, {"butter", 0.25, ""}}, Notes: "won't whip"},
+	},
+	"sour cream": {
+		swap("plain greek yogurt", 1, ""),
+		swap("coconut yogurt", 1, ""),
+	},
+	"yogurt": {
+		swap("coconut yogurt", 1, ""),
+		swap("sour cream", 1, ""),
+	},
+	"cream cheese": {
+		swap("cashew cream cheese", 1, ""),
+		swap("mascarpone", 1, ""),
+	},
+	"parmesan": {
+		swap("nutritional yeast", 0.5, ""),
+		swap("pecorino romano", 1, ""),
+	},
+	"egg": {
+		{Ingredients: []Replacement{{"ground flaxseed", 1, "tbsp"}, {"water", 3, "tbsp"}}, Notes: "mix and let thicken for 5 minutes"},
+		{Ingredients: []Replacement{{"unsweetened applesauce", 0.25, "cup"}}, Notes: "best in sweet baking"},
+		{Ingredients: []Replacement{{"mashed banana", 0.25, "cup"}}, Notes: "best in sweet baking"},
+	},
+	"mayonnaise": {
+		swap("vegan mayonnaise", 1, ""),
+		swap("plain greek yogurt", 1, ""),
+	},
+	"flour": {
+		swap("gluten-free flour blend", 1, "use a blend with xanthan gum for baking"),
+		swap("almond flour", 1, "makes denser baked goods"),
+	},
+	"sugar": {
+		swap("maple syrup", 0.75, "use 3 tbsp less liquid for each cup"),
+		swap("honey", 0.75, "use 1/4 cup less liquid for each cup"),
+		swap("erythritol", 1, ""),
+	},
+	"brown sugar": {
+		{Ingredients: []Replacement{{"sugar", 1, ""}, {"molasses", 0.0625, ""}}},
+		swap("coconut sugar", 1, ""),
+	},
+	"honey": {
+		swap("maple syrup", 1, ""),
+		swap("agave nectar", 1, ""),
+	},
+	"soy sauce": {
+		swap("tamari", 1, "check the label, most tamari is gluten-free"),
+		swap("coconut aminos", 1, "slightly sweeter"),
+	},
+	"fish sauce": {
+		{Ingredients: []Replacement{{"coconut aminos", 1, ""}, {"salt", 0.125, "tsp"}}},
+	},
+	"breadcrumb": {
+		swap("almond flour", 1, ""),
+		swap("rolled oats", 1, ""),
+		swap("crushed pork rinds", 1, ""),
+	},
+	"pasta": {
+		swap("gluten-free pasta", 1, ""),
+		swap("zucchini noodles", 2, "by weight, don't boil"),
+	},
+	"spaghetti": {
+		swap("gluten-free spaghetti", 1, ""),
+		swap("zucchini noodles", 2, "by weight, don't boil"),
+	},
+	"rice": {
+		swap("cauliflower rice", 1, ""),
+		swap("quinoa", 1, ""),
+	},
+	"peanut butter": {
+		swap("sunflower seed butter", 1, ""),
+		swap("almond butter", 1, ""),
+	},
+	"chicken broth": {
+		swap("vegetable broth", 1, ""),
+	},
+	"chicken stock": {
+		swap("vegetable stock", 1, ""),
+	},
+	"beef broth": {
+		swap("mushroom broth", 1, ""),
+	},
+	"ground beef": {
+		swap("cooked lentils", 1, ""),
+		swap("crumbled tempeh", 1, ""),
+	},
+	"chicken": {
+		swap("extra-firm tofu", 1, "press before cooking"),
+		swap("chickpeas", 1, ""),
+	},
+	"gelatin": {
+		swap("agar agar", 1, "use the same weight, it sets firmer"),
+	},
+	"cornstarch": {
+		swap("arrowroot", 1, ""),
+		swap("flour", 2, ""),
+	},
+	"lemon juice": {
+		swap("lime juice", 1, ""),
+		swap("white vinegar", 0.5, ""),
+	},
+	"sesame oil": {
+		swap("olive oil", 1, ""),
+	},
+}
+
+// Substitutes returns the ways an ingredient can be replaced. The most
+// specific entry in the substitution graph found in the name is used, so
+// "unsalted butter" uses butter's and "peanut butter" uses it's own.
+func Substitutes(name string) []Substitute {
+	words := " " + singularWords(name) + " "
+
+	best, bestLength := "", 0
+	for key := range substitutions {
+		phrase := singularWords(key)
+		if len(phrase) > bestLength && strings.Contains(words, " "+phrase+" ") {
+			best, bestLength = key, len(phrase)
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return substitutions[best]
+}
+
+// Constraints are what a recipe needs to be safe for someone
+type Constraints struct {
+	// Without are allergens to avoid
+	Without []string
+	// Diets are dietary labels to keep to
+	Diets []string
+}
+
+// groups each diet can't have
+var dietForbids = map[string][]string{
+	Vegetarian:   {meat, Fish, Shellfish, gelatin},
+	Vegan:        {meat, Fish, Shellfish, gelatin, Dairy, Egg, honey},
+	KetoFriendly: {highCarb},
+}
+
+// Allows reports whether an ingredient can be trusted to keep to the
+// constraints. Unknown ingredients can't be, unless there are none.
+func (constraints Constraints) Allows(name string) bool {
+	forbidden := map[string]bool{}
+	for _, allergen := range constraints.Without {
+		forbidden[allergen] = true
+	}
+	for _, diet := range constraints.Diets {
+		for _, group := range dietForbids[diet] {
+			forbidden[group] = true
+		}
+	}
+
+	for _, group := range Classify(name) {
+		if forbidden[group] {
+			return false
+		}
+	}
+	return constraints.Empty() || Known(name)
+}
+
+// Empty reports whether there are no constraints
+func (constraints Constraints) Empty() bool {
+	return len(constraints.Without) == 0 && len(constraints.Diets) == 0
+}
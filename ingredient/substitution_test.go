@@ -0,0 +1,33 @@
+package ingredient
+
+import "testing"
+
+func TestSubstitutes(t *testing.T) {
+	substitutes := Substitutes("unsalted butter")
+	if len(substitutes) == 0 || substitutes[0].Ingredients[0].Name != "vegan butter" {
+		t.Errorf("Expected butter's substitutes, got %+v", substitutes)
+	}
+
+	substitutes = Substitutes("creamy peanut butter")
+	if len(substitutes) == 0 || substitutes[0].Ingredients[0].Name != "sunflower seed butter" {
+		t.Errorf("Expected peanut butter's substitutes, got %+v", substitutes)
+	}
+
+	if substitutes := Substitutes("saffron"); substitutes != nil {
+		t.Errorf("Expected no substitutes for saffron, got %+v", substitutes)
+	}
+}
+
+func TestReplacementAmount(t *testing.T) {
+	buttermilk := Substitutes("buttermilk")[0]
+	cup, _ := Parse("1 cup")
+	if amount := buttermilk.Ingredients[1].Amount(cup).String(); amount != "1 tbsp" {
+		t.Errorf("Expected 1 tbsp of lemon juice for a cup of buttermilk, got %q", amount)
+	}
+
+	flax := Substitutes("eggs")[0]
+	eggs, _ := Parse("2")
+	if amount := flax.Ingredients[1].Amount(eggs).String(); amount != "6 tbsp" {
+		t.Errorf("Expected 6 tbsp of water for 2 eggs, got %q", amount)
+	}
+}
@@ -0,0 +1,120 @@
+// Package jobs runs work in the background so requests can return quickly.
+// Jobs live in memory, so anything queued when the server stops is lost and
+// jobs must be safe to abandon.
+package jobs
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// MaxAttempts is how many times a failing job is tried
+const MaxAttempts = 3
+
+// ErrQueueFull is returned when there is no room for another job
+var ErrQueueFull = errors.New("job queue is full")
+
+type job struct {
+	name string
+	run  func() error
+	// failed is called with the last error once every attempt has failed
+	failed func(error)
+}
+
+// Queue runs jobs on a fixed number of workers
+type Queue struct {
+	jobs chan job
+	wg   sync.WaitGroup
+	// backoff is how long to wait before retrying, doubling each attempt
+	backoff time.Duration
+}
+
+// NewQueue starts the workers, size is how many jobs can be waiting
+func NewQueue(workers int, size int) *Queue {
+	queue := &Queue{
+		jobs:    make(chan job, size),
+		backoff: time.Second,
+	}
+
+	for i := 0; i < workers; i++ {
+		queue.wg.Add(1)
+		go queue.work()
+	}
+
+	return queue
+}
+
+// Enqueue adds a job without waiting for it to run
+func (queue *Queue) Enqueue(name string, run func() error) error {
+	return queue.EnqueueWithFailure(name, run, nil)
+}
+
+// EnqueueWithFailure adds a job like Enqueue, calling failed with the last
+// error if every attempt fails so the job can record that it gave up
+func (queue *Queue) EnqueueWithFailure(name string, run func() error, failed func(error)) error {
+	select {
+	case queue.jobs <- job{name: name, run: run, failed: failed}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Every enqueues the job once each interval until stop is called, which must
+// happen before the queue is closed. A run is skipped when the queue is full.
+func (queue *Queue) Every(name string, interval time.Duration, run func() error) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := queue.Enqueue(name, run)
+				if err != nil {
+					log.Printf("job %s skipped: %v", name, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// Close stops accepting jobs and waits for queued jobs to finish
+func (queue *Queue) Close() {
+	close(queue.jobs)
+	queue.wg.Wait()
+}
+
+func (queue *Queue) work() {
+	defer queue.wg.Done()
+
+	for job := range queue.jobs {
+		backoff := queue.backoff
+		for attempt := 1; attempt <= MaxAttempts; attempt++ {
+			err := job.run()
+			if err == nil {
+				break
+			}
+
+			log.Printf("job %s failed (attempt %d/%d): %v", job.name, attempt, MaxAttempts, err)
+			if attempt < MaxAttempts {
+				time.Sleep(backoff)
+				backoff *= 2
+			} else if job.failed != nil {
+				job.failed(err)
+			}
+		}
+	}
+}
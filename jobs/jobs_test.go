@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRetries(t *testing.T) {
+	queue := NewQueue(1, 1)
+	queue.backoff = 0
+
+	var attempts int32
+	err := queue.Enqueue("flaky", func() error {
+		if atomic.AddInt32(&attempts, 1) < MaxAttempts {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error enqueueing job: %s", err.Error())
+	}
+
+	queue.Close()
+	if attempts != MaxAttempts {
+		t.Errorf("Job ran %d times", attempts)
+	}
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	queue := NewQueue(1, 1)
+	queue.backoff = 0
+
+	var attempts int32
+	var failed error
+	err := queue.EnqueueWithFailure("broken", func() error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("still broken")
+	}, func(err error) {
+		failed = err
+	})
+	if err != nil {
+		t.Fatalf("Error enqueueing job: %s", err.Error())
+	}
+
+	queue.Close()
+	if attempts != MaxAttempts || failed == nil || failed.Error() != "still broken" {
+		t.Errorf("Expected %d attempts and the last error, got %d and %v", MaxAttempts, attempts, failed)
+	}
+}
+
+func TestQueueEvery(t *testing.T) {
+	queue := NewQueue(1, 10)
+
+	ran := make(chan struct{}, 10)
+	stop := queue.Every("tick", time.Millisecond, func() error {
+		ran <- struct{}{}
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("Job did not run each interval")
+		}
+	}
+
+	stop()
+	queue.Close()
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/handlers"
 
@@ -12,7 +16,23 @@ import (
 
 func main() {
 	restClient := rest.New()
+	server := &http.Server{Addr: ":8080", Handler: handlers.CORS()(restClient.Router)}
+
+	// finish requests and background jobs before stopping
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		err := server.Shutdown(context.Background())
+		if err != nil {
+			log.Printf("error shutting down: %v", err)
+		}
+	}()
 
 	fmt.Println("Serving on 8080...")
-	log.Fatal(http.ListenAndServe(":8080", handlers.CORS()(restClient.Router)))
+	err := server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	restClient.Close()
 }
@@ -0,0 +1,120 @@
+package nutrition
+
+import (
+	"math"
+	"sort"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// below this an ingredient is reported as unmatched rather than guessed at
+const minConfidence = 0.5
+
+// how much less sure we are when a weight had to be guessed
+const estimatedPenalty = 0.5
+
+// Report is the nutrition facts for a recipe
+type Report struct {
+	Servings   int       `json:"servings"`
+	Total      Nutrients `json:"total"`
+	PerServing Nutrients `json:"perServing"`
+	// Confidence is between 0 and 1, the average confidence of every
+	// ingredient, counting unmatched ones as 0
+	Confidence  float64            `json:"confidence"`
+	Ingredients []IngredientReport `json:"ingredients"`
+	// Unmatched are the ingredients left out of the totals
+	Unmatched []string `json:"unmatched"`
+}
+
+// IngredientReport is how an ingredient was counted
+type IngredientReport struct {
+	Name       string  `json:"name"`
+	Amount     string  `json:"amount"`
+	Food       string  `json:"food,omitempty"`
+	FDCID      int     `json:"fdcId,omitempty"`
+	Grams      float64 `json:"grams,omitempty"`
+	Confidence float64 `json:"confidence"`
+	// Problem says why an ingredient wasn't counted, or what was guessed
+	Problem string `json:"problem,omitempty"`
+}
+
+// Analyze adds up the nutrients in a recipe's ingredients, a map of names to
+// amounts like Recipe.Ingredients. Servings of zero counts as one.
+func (database *Database) Analyze(ingredients map[string]string, servings int) Report {
+	if servings <= 0 {
+		servings = 1
+	}
+
+	report := Report{
+		Servings:    servings,
+		Total:       Nutrients{},
+		PerServing:  Nutrients{},
+		Ingredients: []IngredientReport{},
+		Unmatched:   []string{},
+	}
+
+	names := []string{}
+	for name := range ingredients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	totalConfidence := 0.0
+	for _, name := range names {
+		item := database.analyzeIngredient(name, ingredients[name], report.Total)
+		if item.Confidence == 0 {
+			report.Unmatched = append(report.Unmatched, name)
+		}
+		totalConfidence += item.Confidence
+		report.Ingredients = append(report.Ingredients, item)
+	}
+
+	if len(names) > 0 {
+		report.Confidence = round(totalConfidence/float64(len(names)), 2)
+	}
+	for name, amount := range report.Total {
+		report.PerServing[name] = round(amount/float64(servings), 1)
+		report.Total[name] = round(amount, 1)
+	}
+
+	return report
+}
+
+// works out one ingredient, adding it to total
+func (database *Database) analyzeIngredient(name string, amount string, total Nutrients) IngredientReport {
+	item := IngredientReport{Name: name, Amount: amount}
+
+	quantity, ok := ingredient.Parse(amount)
+	if !ok {
+		item.Problem = "no amount to weigh"
+		return item
+	}
+
+	food, confidence := database.Match(name)
+	if food == nil || confidence < minConfidence {
+		item.Problem = "no matching food"
+		return item
+	}
+	item.Food = food.Description
+	item.FDCID = food.FDCID
+
+	grams, estimated, ok := food.Grams(quantity)
+	if !ok {
+		item.Problem = "no weight for " + quantity.String()
+		return item
+	}
+	if estimated {
+		confidence *= estimatedPenalty
+		item.Problem = "weight estimated"
+	}
+
+	item.Grams = round(grams, 1)
+	item.Confidence = round(confidence, 2)
+	total.Add(food.Nutrients, grams/100)
+	return item
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,323 @@
+// Package nutrition works out nutrition facts for recipes from a local copy
+// of a USDA FoodData Central dataset. The JSON downloads of Foundation Foods,
+// SR Legacy and FNDDS survey foods all work, alone or combined.
+package nutrition
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// Nutrient names, with the FoodData Central nutrient numbers they are read
+// from. Amounts are in grams unless noted.
+const (
+	Calories      = "calories" // kcal
+	Protein       = "protein"
+	Fat           = "fat"
+	SaturatedFat  = "saturatedFat"
+	Carbohydrates = "carbohydrates"
+	Fiber         = "fiber"
+	Sugars        = "sugars"
+	Cholesterol   = "cholesterol" // mg
+	Sodium        = "sodium"      // mg
+	Calcium       = "calcium"     // mg
+	Iron          = "iron"        // mg
+	Potassium     = "potassium"   // mg
+	VitaminC      = "vitaminC"    // mg
+)
+
+var nutrientNumbers = map[string]string{
+	"208": Calories,
+	"203": Protein,
+	"204": Fat,
+	"606": SaturatedFat,
+	"205": Carbohydrates,
+	"291": Fiber,
+	"269": Sugars,
+	"601": Cholesterol,
+	"307": Sodium,
+	"301": Calcium,
+	"303": Iron,
+	"306": Potassium,
+	"401": VitaminC,
+}
+
+// Foundation Foods often only have energy calculated with Atwater factors
+var energyFallbacks = []string{"957", "958"}
+
+// Nutrients are amounts of each nutrient, keyed by the names above
+type Nutrients map[string]float64
+
+// Add adds other's amounts, scaled by factor
+func (nutrients Nutrients) Add(other Nutrients, factor float64) {
+	for name, amount := range other {
+		nutrients[name] += amount * factor
+	}
+}
+
+// Food is an entry in the dataset. Nutrients are per 100 grams.
+type Food struct {
+	FDCID       int
+	Description string
+	Nutrients   Nutrients
+	Portions    []Portion
+
+	tokens      map[string]bool
+	firstTokens map[string]bool
+}
+
+// Portion is a household measure of a food and what it weighs, like "1 cup"
+// is 125 grams
+type Portion struct {
+	Quantity ingredient.Quantity
+	Grams    float64
+}
+
+// Database is a loaded dataset
+type Database struct {
+	Foods []*Food
+}
+
+// the parts of a FoodData Central food we read
+type fdcFood struct {
+	FDCID         int    `json:"fdcId"`
+	Description   string `json:"description"`
+	FoodNutrients []struct {
+		Nutrient struct {
+			Number string `json:"number"`
+		} `json:"nutrient"`
+		Amount float64 `json:"amount"`
+	} `json:"foodNutrients"`
+	FoodPortions []struct {
+		Amount             float64 `json:"amount"`
+		GramWeight         float64 `json:"gramWeight"`
+		Modifier           string  `json:"modifier"`
+		PortionDescription string  `json:"portionDescription"`
+		MeasureUnit        struct {
+			Name string `json:"name"`
+		} `json:"measureUnit"`
+	} `json:"foodPortions"`
+}
+
+type fdcFile struct {
+	FoundationFoods []fdcFood `json:"FoundationFoods"`
+	SRLegacyFoods   []fdcFood `json:"SRLegacyFoods"`
+	SurveyFoods     []fdcFood `json:"SurveyFoods"`
+}
+
+// Load reads a dataset from a FoodData Central JSON file
+func Load(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read reads a dataset in FoodData Central's JSON format
+func Read(r io.Reader) (*Database, error) {
+	var file fdcFile
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding nutrient data: %w", err)
+	}
+
+	database := &Database{}
+	for _, foods := range [][]fdcFood{file.FoundationFoods, file.SRLegacyFoods, file.SurveyFoods} {
+		for _, fdc := range foods {
+			database.Foods = append(database.Foods, newFood(fdc))
+		}
+	}
+
+	if len(database.Foods) == 0 {
+		return nil, fmt.Errorf("nutrient data has no foods")
+	}
+	return database, nil
+}
+
+// Match finds the food an ingredient most likely is, with a confidence
+// between 0 and 1. It returns nil when nothing is close.
+func (database *Database) Match(name string) (*Food, float64) {
+	words := tokenize(name)
+	if len(words) == 0 {
+		return nil, 0
+	}
+	head := words[len(words)-1]
+
+	var best *Food
+	bestScore, bestConfidence := 0.0, 0.0
+	for _, food := range database.Foods {
+		// "brown sugar" should never turn into "brown rice"
+		if !food.tokens[head] {
+			continue
+		}
+
+		matched := 0
+		for _, word := range words {
+			if food.tokens[word] {
+				matched++
+			}
+		}
+
+		confidence := float64(matched) / float64(len(words))
+		if !food.firstTokens[head] {
+			confidence *= 0.75
+		}
+		// prefer plainer foods, "Butter, salted" over "Butter, whipped, with
+		// salt, light"
+		score := confidence - float64(len(food.tokens))*0.01
+
+		if best == nil || score > bestScore {
+			best, bestScore, bestConfidence = food, score, confidence
+		}
+	}
+
+	return best, bestConfidence
+}
+
+// Grams is how much quantity of the food weighs. Estimated is true when
+// there was no portion to go on and a guess was made.
+func (food *Food) Grams(quantity ingredient.Quantity) (grams float64, estimated bool, ok bool) {
+	switch quantity.Dimension {
+	case ingredient.Mass:
+		return quantity.Base(), false, true
+	case ingredient.Volume:
+		for _, portion := range food.Portions {
+			if portion.Quantity.Dimension == ingredient.Volume && portion.Quantity.Base() > 0 {
+				return quantity.Base() * portion.Grams / portion.Quantity.Base(), false, true
+			}
+		}
+		// about the density of water
+		return quantity.Base(), true, true
+	}
+
+	// counts, "2 cloves" or "3" (eggs)
+	for _, portion := range food.Portions {
+		if portion.Quantity.Dimension == ingredient.Count && quantity.Unit != "" && ingredient.Matches(portion.Quantity.Unit, quantity.Unit) {
+			return quantity.Amount * portion.Grams / portion.Quantity.Amount, false, true
+		}
+	}
+	for _, portion := range food.Portions {
+		if portion.Quantity.Dimension == ingredient.Count && portion.Quantity.Amount > 0 {
+			return quantity.Amount * portion.Grams / portion.Quantity.Amount, quantity.Unit != "", true
+		}
+	}
+	return 0, false, false
+}
+
+// - MARK: Helper Functions
+
+func newFood(fdc fdcFood) *Food {
+	food := &Food{
+		FDCID:       fdc.FDCID,
+		Description: fdc.Description,
+		Nutrients:   Nutrients{},
+		tokens:      map[string]bool{},
+		firstTokens: map[string]bool{},
+	}
+
+	byNumber := map[string]float64{}
+	for _, n := range fdc.FoodNutrients {
+		byNumber[n.Nutrient.Number] = n.Amount
+	}
+	for number, name := range nutrientNumbers {
+		if amount, ok := byNumber[number]; ok {
+			food.Nutrients[name] = amount
+		}
+	}
+	if _, ok := food.Nutrients[Calories]; !ok {
+		for _, number := range energyFallbacks {
+			if amount, ok := byNumber[number]; ok {
+				food.Nutrients[Calories] = amount
+				break
+			}
+		}
+	}
+
+	for _, p := range fdc.FoodPortions {
+		if p.GramWeight <= 0 {
+			continue
+		}
+		amount := p.Amount
+		if amount <= 0 {
+			amount = 1
+		}
+
+		unit := p.MeasureUnit.Name
+		if unit == "" || unit == "undetermined" {
+			unit = p.Modifier
+		}
+		if unit == "" {
+			unit = p.PortionDescription
+		}
+		// "cup, diced" or "large (2 oz)" are just a cup and large
+		if i := strings.IndexAny(unit, ",("); i >= 0 {
+			unit = unit[:i]
+		}
+
+		food.Portions = append(food.Portions, Portion{
+			Quantity: ingredient.NewQuantity(amount, unit),
+			Grams:    p.GramWeight,
+		})
+	}
+
+	for i, segment := range strings.Split(fdc.Description, ",") {
+		for _, word := range tokenize(segment) {
+			food.tokens[word] = true
+			if i == 0 {
+				food.firstTokens[word] = true
+			}
+		}
+	}
+
+	return food
+}
+
+// words that say how an ingredient is prepared rather than what it is
+var preparationWords = map[string]bool{
+	"chopped": true, "diced": true, "minced": true, "sliced": true,
+	"grated": true, "shredded": true, "crushed": true, "melted": true,
+	"softened": true, "fresh": true, "freshly": true, "large": true,
+	"small": true, "medium": true, "finely": true, "roughly": true,
+	"to": true, "taste": true, "of": true, "and": true, "or": true,
+	"for": true, "divided": true, "packed": true, "room": true,
+	"temperature": true, "cold": true, "warm": true,
+}
+
+// the singular, lower case words of a name, without preparation words
+func tokenize(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+
+	words := []string{}
+	for _, field := range fields {
+		if preparationWords[field] {
+			continue
+		}
+		words = append(words, singular(field))
+	}
+	return words
+}
+
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "ches"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,109 @@
+package nutrition
+
+import (
+	"math"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+func loadTestData(t *testing.T) *Database {
+	database, err := Load("testdata/foods.json")
+	if err != nil {
+		t.Fatalf("Error loading nutrient data: %s", err.Error())
+	}
+	return database
+}
+
+func TestLoad(t *testing.T) {
+	database := loadTestData(t)
+
+	if len(database.Foods) != 4 {
+		t.Fatalf("Expected 4 foods, got %d", len(database.Foods))
+	}
+
+	for _, food := range database.Foods {
+		if food.Description == "Sugars, granulated" && food.Nutrients[Calories] != 387 {
+			t.Errorf("Expected calories to fall back to Atwater energy, got %v", food.Nutrients[Calories])
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	database := loadTestData(t)
+
+	tests := map[string]string{
+		"butter, softened":  "Butter, salted",
+		"all-purpose flour": "Wheat flour, white, all-purpose, enriched, bleached",
+		"Eggs":              "Egg, whole, raw, fresh",
+		"sugar":             "Sugars, granulated",
+	}
+	for name, expected := range tests {
+		food, confidence := database.Match(name)
+		if food == nil || food.Description != expected {
+			t.Errorf("Match(%q) = %+v, expected %s", name, food, expected)
+			continue
+		}
+		if confidence < minConfidence {
+			t.Errorf("Match(%q) confidence too low: %v", name, confidence)
+		}
+	}
+
+	if food, _ := database.Match("brown rice"); food != nil {
+		t.Errorf("Expected no match for brown rice, got %s", food.Description)
+	}
+}
+
+func TestGrams(t *testing.T) {
+	database := loadTestData(t)
+
+	tests := []struct {
+		name      string
+		amount    string
+		grams     float64
+		estimated bool
+	}{
+		{"butter", "2 tbsp", 28.4, false},
+		{"butter", "1 stick", 113, false},
+		{"flour", "1/2 cup", 62.5, false},
+		{"flour", "100 g", 100, false},
+		{"eggs", "2", 100, false},
+		{"eggs", "2 medium", 88, false},
+	}
+	for _, test := range tests {
+		food, _ := database.Match(test.name)
+		quantity, _ := ingredient.Parse(test.amount)
+		grams, estimated, ok := food.Grams(quantity)
+		if !ok || math.Abs(grams-test.grams) > 0.5 || estimated != test.estimated {
+			t.Errorf("%s %s weighs %v (estimated %v), expected %v", test.amount, test.name, grams, estimated, test.grams)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	database := loadTestData(t)
+
+	report := database.Analyze(map[string]string{
+		"butter":       "1/2 cup",
+		"flour":        "2 cups",
+		"eggs":         "2",
+		"vanilla bean": "1",
+		"salt":         "to taste",
+	}, 4)
+
+	// 113.5 g butter, 250 g flour and 100 g egg
+	calories := 113.5*7.17 + 250*3.64 + 100*1.43
+	if math.Abs(report.Total[Calories]-calories) > 1 {
+		t.Errorf("Expected %v calories, got %v", calories, report.Total[Calories])
+	}
+	if math.Abs(report.PerServing[Calories]-calories/4) > 1 {
+		t.Errorf("Expected %v calories per serving, got %v", calories/4, report.PerServing[Calories])
+	}
+
+	if len(report.Unmatched) != 2 || report.Unmatched[0] != "salt" || report.Unmatched[1] != "vanilla bean" {
+		t.Errorf("Expected salt and vanilla to be unmatched, got %v", report.Unmatched)
+	}
+	if report.Confidence <= 0 || report.Confidence >= 1 {
+		t.Errorf("Expected confidence to reflect the unmatched ingredients, got %v", report.Confidence)
+	}
+}
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type createAPIKeyRequest struct {
+	Name  string         `json:"name"`
+	Scope database.Scope `json:"scope"`
+}
+
+type createAPIKeyResponse struct {
+	*database.APIKey
+	// Token is only ever returned once, when the key is created
+	Token string `json:"token"`
+}
+
+// handles the /keys route
+func (client *Client) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// keys can't be used to mint or revoke other keys
+	if principalFrom(r).APIKeyID != "" {
+		writeError(w, "api keys cannot manage api keys", http.StatusForbidden)
+		return
+	}
+	// keys belong to a user, so anonymous callers can't have any
+	if principalFrom(r).UserID == "" {
+		writeError(w, "log in to manage api keys", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		client.listAPIKeys(w, r)
+		return
+	case "POST":
+		client.createAPIKey(w, r)
+		return
+	case "DELETE":
+		client.revokeAPIKey(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: API key methods
+
+// create a new API key for the caller
+func (client *Client) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var request createAPIKeyRequest
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.Scope == "" {
+		request.Scope = database.ScopeRead
+	}
+	if !request.Scope.Valid() {
+		writeError(w, "scope must be read or read-write", http.StatusBadRequest)
+		return
+	}
+
+	key, token, err := client.dbClient.CreateAPIKey(principalFrom(r).UserID, request.Name, request.Scope)
+	if err != nil {
+		writeError(w, "could not create api key", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(createAPIKeyResponse{APIKey: key, Token: token})
+	if err != nil {
+		writeError(w, "could not encode api key", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// list the caller's API keys
+func (client *Client) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := client.dbClient.ListAPIKeys(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "error listing api keys", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(keys)
+	if err != nil {
+		writeError(w, "could not marshal api keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// revoke one of the caller's API keys
+func (client *Client) revokeAPIKey(w http.ResponseWriter, r *http.Request, id string) {
+	key, err := client.dbClient.GetAPIKey(id)
+	if err != nil || key.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find api key with that id", http.StatusNotFound)
+		return
+	}
+
+	err = client.dbClient.RevokeAPIKey(id)
+	if err != nil {
+		writeError(w, "could not revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
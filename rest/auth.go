@@ -0,0 +1,286 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/backends/oidc"
+)
+
+// sessionCookie holds the session token for browser clients
+const sessionCookie = "thyme_session"
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// principal is whoever is making the request
+type principal struct {
+	UserID string
+	Scope  database.Scope
+	// APIKeyID is set when the request was authenticated with an API key
+	APIKeyID string
+	// SessionID is set when the request was authenticated with a login session
+	SessionID string
+}
+
+// anonymous callers keep full access when no identity provider is
+// configured
+var anonymous = &principal{Scope: database.ScopeReadWrite}
+
+// returns the principal attached to the request by authenticate
+func principalFrom(r *http.Request) *principal {
+	p, ok := r.Context().Value(principalKey).(*principal)
+	if !ok {
+		return anonymous
+	}
+	return p
+}
+
+// authenticate resolves the Authorization header or session cookie to a
+// principal and enforces read-only scopes. Once an identity provider is
+// configured, anonymous requests are only allowed for status and login.
+// Without one there are no logins to create API keys with, so anonymous
+// requests have full access.
+func (client *Client) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := requestToken(r)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if token == "" {
+			if client.oidcProvider != nil && !isPublicPath(r.URL.Path) {
+				writeError(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var p *principal
+		if strings.HasPrefix(token, database.SessionPrefix) {
+			session, err := client.dbClient.AuthenticateSession(token)
+			if err != nil {
+				writeError(w, "invalid session", http.StatusUnauthorized)
+				return
+			}
+			p = &principal{
+				UserID:    session.UserID,
+				Scope:     database.ScopeReadWrite,
+				SessionID: session.ID,
+			}
+		} else {
+			key, err := client.dbClient.AuthenticateAPIKey(token)
+			if err != nil {
+				writeError(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+			p = &principal{
+				UserID:   key.UserID,
+				Scope:    key.Scope,
+				APIKeyID: key.ID,
+			}
+		}
+
+		if p.Scope != database.ScopeReadWrite && !isReadOnlyMethod(r.Method) {
+			writeError(w, "api key is read-only", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, p)))
+	})
+}
+
+// pulls a bearer token or session cookie off the request
+func requestToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header != "" {
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			return "", errAuthorizationHeader
+		}
+		return token, nil
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", nil
+	}
+	return cookie.Value, nil
+}
+
+var errAuthorizationHeader = errors.New("authorization header must be a bearer token")
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// paths that can be used without logging in
+func isPublicPath(path string) bool {
+	return path == "/api/status" ||
+		strings.HasPrefix(path, "/api/auth/") ||
+		strings.HasPrefix(path, "/s/") ||
+		strings.HasPrefix(path, "/ical/")
+}
+
+// - MARK: Login methods
+
+type loginResponse struct {
+	User      *database.User `json:"user"`
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+// handles the /auth/login route by redirecting to the identity provider
+func (client *Client) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if client.oidcProvider == nil {
+		writeError(w, "login is not configured", http.StatusNotFound)
+		return
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		writeError(w, "could not start login", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		writeError(w, "could not start login", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, verifier, err := client.oidcProvider.AuthCodeURL(state, nonce)
+	if err != nil {
+		writeError(w, "could not reach identity provider", http.StatusBadGateway)
+		return
+	}
+
+	err = client.dbClient.SaveLogin(database.Login{
+		ID:           state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().UTC().Add(database.LoginLifetime),
+	})
+	if err != nil {
+		writeError(w, "could not start login", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handles the /auth/callback route the identity provider redirects back to
+func (client *Client) handleLoginCallback(w http.ResponseWriter, r *http.Request) {
+	if client.oidcProvider == nil {
+		writeError(w, "login is not configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		log.Printf("identity provider refused login: %q", query.Get("error"))
+		writeError(w, "identity provider refused the login", http.StatusUnauthorized)
+		return
+	}
+
+	login, err := client.dbClient.ConsumeLogin(query.Get("state"))
+	if err != nil {
+		writeError(w, "unknown or expired login", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := client.oidcProvider.Exchange(query.Get("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		writeError(w, "could not verify login", http.StatusUnauthorized)
+		return
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	user, err := client.dbClient.EnsureUser(claims.Issuer, claims.Subject, claims.Email, name)
+	if err != nil {
+		writeError(w, "could not save user", http.StatusInternalServerError)
+		return
+	}
+
+	session, token, err := client.dbClient.CreateSession(user.ID)
+	if err != nil {
+		writeError(w, "could not create session", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(loginResponse{User: user, Token: token, ExpiresAt: session.ExpiresAt})
+	if err != nil {
+		writeError(w, "could not encode session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Write(bytes)
+}
+
+// handles the /auth/logout route
+func (client *Client) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := principalFrom(r)
+	if p.SessionID != "" {
+		err := client.dbClient.DeleteSession(p.SessionID)
+		if err != nil {
+			writeError(w, "could not log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookie,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// handles the /me route
+func (client *Client) handleMe(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	if p.UserID == "" {
+		writeError(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := client.dbClient.GetUser(p.UserID)
+	if err != nil {
+		writeError(w, "could not find user", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(user)
+	if err != nil {
+		writeError(w, "could not marshal user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestReadOnlyKeys(t *testing.T) {
+	client := newTestClient(t)
+	recipe := database.Recipe{Name: "Toast", Ingredients: map[string]string{"bread": "1 slice"}}
+
+	// without login anyone can write
+	expectStatus(t, serve(client, "POST", "/api/recipe", "", recipe), http.StatusCreated)
+
+	_, token, err := client.dbClient.CreateAPIKey("sam", "script", database.ScopeRead)
+	if err != nil {
+		t.Fatalf("Error creating api key: %s", err.Error())
+	}
+
+	expectStatus(t, serve(client, "POST", "/api/recipe", token, recipe), http.StatusForbidden)
+	expectStatus(t, serve(client, "GET", "/api/recipe", "", nil), http.StatusOK)
+	expectStatus(t, serve(client, "GET", "/api/recipe", token, nil), http.StatusOK)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	client := newTestClient(t)
+	_, readWrite, _ := client.dbClient.CreateAPIKey("sam", "writer", database.ScopeReadWrite)
+
+	expectStatus(t, serve(client, "POST", "/api/recipe", readWrite, database.Recipe{Name: "Toast"}), http.StatusCreated)
+	expectStatus(t, serve(client, "GET", "/api/recipe", "thyme_bogus_token", nil), http.StatusUnauthorized)
+	expectStatus(t, serve(client, "GET", "/api/recipe", "", nil), http.StatusOK)
+
+	// keys can't mint more keys, and anonymous callers have no keys to manage
+	expectStatus(t, serve(client, "POST", "/api/keys", readWrite, createAPIKeyRequest{Name: "more"}), http.StatusForbidden)
+	expectStatus(t, serve(client, "GET", "/api/keys", "", nil), http.StatusUnauthorized)
+
+	samID, session := login(t, client, "sam")
+	w := serve(client, "POST", "/api/keys", session, createAPIKeyRequest{Name: "more"})
+	expectStatus(t, w, http.StatusCreated)
+	var created createAPIKeyResponse
+	decode(t, w, &created)
+	if created.UserID != samID || created.Scope != database.ScopeRead {
+		t.Errorf("Expected a read-only key for sam, got %+v", created.APIKey)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	client := newTestClient(t)
+	samID, sam := login(t, client, "sam")
+	_, alex := login(t, client, "alex")
+
+	w := serve(client, "POST", "/api/pantry", sam, database.PantryItem{Name: "flour", Quantity: 2})
+	expectStatus(t, w, http.StatusCreated)
+	var item database.PantryItem
+	decode(t, w, &item)
+
+	expectStatus(t, serve(client, "GET", "/api/pantry/"+item.ID, sam, nil), http.StatusOK)
+	expectStatus(t, serve(client, "GET", "/api/pantry/"+item.ID, alex, nil), http.StatusNotFound)
+	expectStatus(t, serve(client, "DELETE", "/api/pantry/"+item.ID, alex, nil), http.StatusNotFound)
+
+	// keys can't be revoked by someone else either
+	key, _, _ := client.dbClient.CreateAPIKey(samID, "script", database.ScopeRead)
+	expectStatus(t, serve(client, "DELETE", "/api/keys/"+key.ID, alex, nil), http.StatusNotFound)
+	expectStatus(t, serve(client, "DELETE", "/api/keys/"+key.ID, sam, nil), http.StatusNoContent)
+}
@@ -0,0 +1,217 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/ical"
+)
+
+// mealTimes is when each slot's meal is served, as hours and minutes
+var mealTimes = map[string][2]int{
+	"breakfast": {8, 0},
+	"lunch":     {12, 0},
+	"snack":     {15, 0},
+	"dinner":    {18, 30},
+}
+
+// how long a meal shows up as in the calendar
+const mealDuration = time.Hour
+
+// how far back the feed goes, calendar apps keep their own history
+const feedHistory = 30 * 24 * time.Hour
+
+type calendarFeedResponse struct {
+	*database.CalendarFeed
+	URL string `json:"url"`
+}
+
+// handles the /mealplan/feed route
+func (client *Client) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		client.createCalendarFeed(w, r)
+		return
+	case "DELETE":
+		client.deleteCalendarFeed(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Calendar feed methods
+
+// mint (or rotate) the caller's feed URL, or their household's with
+// ?household=true
+func (client *Client) createCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID := principalFrom(r).UserID
+	household := r.URL.Query().Get("household") == "true"
+	if household {
+		_, err := client.dbClient.GetUserHousehold(userID)
+		if err != nil {
+			writeError(w, "you are not in a household", http.StatusBadRequest)
+			return
+		}
+	}
+
+	feed, token, err := client.dbClient.CreateCalendarFeed(userID, household)
+	if err != nil {
+		writeError(w, "could not create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(calendarFeedResponse{
+		CalendarFeed: feed,
+		URL:          baseURL(r) + "/ical/" + token + ".ics",
+	})
+	if err != nil {
+		writeError(w, "could not encode calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// revoke the caller's feed URL, or their household feed's with
+// ?household=true
+func (client *Client) deleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	household := r.URL.Query().Get("household") == "true"
+	err := client.dbClient.DeleteCalendarFeeds(principalFrom(r).UserID, household)
+	if err != nil {
+		writeError(w, "could not delete calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// handles the public /ical/{token}.ics route calendar apps subscribe to
+func (client *Client) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimSuffix(mux.Vars(r)["token"], ".ics")
+	feed, err := client.dbClient.GetCalendarFeedByToken(token)
+	if err != nil {
+		writeError(w, "could not find calendar", http.StatusNotFound)
+		return
+	}
+
+	calendar := ical.Calendar{
+		ProdID: "-//thyme//meal plan//EN",
+		Name:   "Meal plan",
+	}
+	userIDs := []string{feed.UserID}
+	if feed.Household {
+		calendar.Name = "Household meal plan"
+		userIDs = client.householdMemberIDs(feed.UserID)
+	}
+
+	from := time.Now().Add(-feedHistory).Format(database.DateFormat)
+	entries := []database.MealPlanEntry{}
+	for _, userID := range userIDs {
+		planned, err := client.dbClient.ListMealPlanEntries(userID, from, "9999-12-31")
+		if err != nil {
+			writeError(w, "error listing meal plan", http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, planned...)
+	}
+
+	recipes := map[string]*database.Recipe{}
+	for _, entry := range entries {
+		recipe, ok := recipes[entry.RecipeID]
+		if !ok {
+			// calendar apps keep the feed they have when it can't be read, which
+			// is better than showing recipes as deleted
+			recipe, err = client.dbClient.GetRecipe(entry.RecipeID)
+			if err != nil && !errors.Is(err, database.ErrRecipeNotFound) {
+				writeError(w, "error getting recipes", http.StatusInternalServerError)
+				return
+			}
+			recipes[entry.RecipeID] = recipe
+		}
+
+		event, err := mealEvent(entry, recipe, baseURL(r))
+		if err != nil {
+			continue
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	err = calendar.Encode(w)
+	if err != nil {
+		log.Printf("error writing calendar feed: %s", err)
+	}
+}
+
+// - MARK: Helper Functions
+
+// turns a meal plan entry into an event at meal time, with an alarm when it's
+// time to start cooking. recipe is nil when it has been deleted.
+func mealEvent(entry database.MealPlanEntry, recipe *database.Recipe, base string) (ical.Event, error) {
+	date, err := time.Parse(database.DateFormat, entry.Date)
+	if err != nil {
+		return ical.Event{}, err
+	}
+	mealTime := mealTimes[entry.Slot]
+
+	event := ical.Event{
+		UID:      entry.ID + "@thyme",
+		Stamp:    entry.CreatedAt,
+		Start:    date.Add(time.Duration(mealTime[0])*time.Hour + time.Duration(mealTime[1])*time.Minute),
+		Duration: mealDuration,
+		Summary:  slotName(entry.Slot) + ": " + entry.RecipeName,
+	}
+
+	description := []string{}
+	if entry.Servings > 0 {
+		description = append(description, fmt.Sprintf("Servings: %d", entry.Servings))
+	}
+	if entry.Notes != "" {
+		description = append(description, entry.Notes)
+	}
+
+	if recipe != nil {
+		event.URL = base + "/api/recipe/" + recipe.ID
+
+		if recipe.PrepTime+recipe.CookTime > 0 {
+			description = append(description, fmt.Sprintf("Prep %d min, cook %d min", recipe.PrepTime, recipe.CookTime))
+		} else if recipe.TotalTime > 0 {
+			description = append(description, fmt.Sprintf("Takes about %d min", recipe.TotalTime))
+		}
+		// the total falls back to the step timings when prep and cook time
+		// aren't set
+		if recipe.TotalTime > 0 {
+			event.Alarms = []ical.Alarm{{
+				Before:      time.Duration(recipe.TotalTime) * time.Minute,
+				Description: "Start making " + recipe.Name,
+			}}
+		}
+	} else {
+		description = append(description, "This recipe has been deleted.")
+	}
+
+	event.Description = strings.Join(description, "\n")
+	return event, nil
+}
+
+// the slot capitalized for people to read, "Dinner"
+func slotName(slot string) string {
+	if slot == "" {
+		return slot
+	}
+	return strings.ToUpper(slot[:1]) + slot[1:]
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestHouseholdCalendarFeed(t *testing.T) {
+	client := newTestClient(t)
+	aliceID, alice := login(t, client, "alice")
+	bobID, _ := login(t, client, "bob")
+	household, _ := client.dbClient.CreateHousehold("Home", aliceID)
+	client.dbClient.AddHouseholdMember(*household, bobID)
+
+	client.dbClient.SaveMealPlanEntry(database.MealPlanEntry{UserID: aliceID, Date: "2099-01-01", Slot: "dinner", RecipeName: "Chili"})
+	client.dbClient.SaveMealPlanEntry(database.MealPlanEntry{UserID: bobID, Date: "2099-01-02", Slot: "lunch", RecipeName: "Soup"})
+
+	feedPath := func(query string) string {
+		w := serve(client, "POST", "/api/mealplan/feed"+query, alice, nil)
+		expectStatus(t, w, http.StatusCreated)
+		var feed calendarFeedResponse
+		decode(t, w, &feed)
+		return feed.URL[strings.Index(feed.URL, "/ical/"):]
+	}
+	own, shared := feedPath(""), feedPath("?household=true")
+
+	w := serve(client, "GET", own, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Dinner: Chili") || strings.Contains(body, "Soup") {
+		t.Errorf("Expected only alice's meals in her feed, got:\n%s", body)
+	}
+
+	w = serve(client, "GET", shared, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "Dinner: Chili") || !strings.Contains(body, "Lunch: Soup") {
+		t.Errorf("Expected everyone's meals in the household feed, got:\n%s", body)
+	}
+
+	_, carol := login(t, client, "carol")
+	expectStatus(t, serve(client, "POST", "/api/mealplan/feed?household=true", carol, nil), http.StatusBadRequest)
+}
+
+func TestCalendarAlarmFromStepTimings(t *testing.T) {
+	client := newTestClient(t)
+	aliceID, alice := login(t, client, "alice")
+	recipe, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Chili", Steps: []string{"Simmer for 45 minutes"}})
+	client.dbClient.SaveMealPlanEntry(database.MealPlanEntry{UserID: aliceID, Date: "2099-01-01", Slot: "dinner", RecipeID: recipe.ID, RecipeName: "Chili"})
+
+	w := serve(client, "POST", "/api/mealplan/feed", alice, nil)
+	expectStatus(t, w, http.StatusCreated)
+	var feed calendarFeedResponse
+	decode(t, w, &feed)
+
+	w = serve(client, "GET", feed.URL[strings.Index(feed.URL, "/ical/"):], "", nil)
+	expectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "TRIGGER:-PT45M") || !strings.Contains(body, "Takes about 45 min") {
+		t.Errorf("Expected an alarm from the step timings, got:\n%s", body)
+	}
+}
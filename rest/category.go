@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type categoryResponse struct {
+	database.Category
+	// Path is the categories from the top of the taxonomy down to this one
+	Path     []database.Category     `json:"path"`
+	Children []database.CategoryNode `json:"children"`
+}
+
+// handles the /categories route
+func (client *Client) handleCategories(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getCategory(w, r, vars["id"])
+		} else {
+			client.listCategories(w, r)
+		}
+		return
+	case "POST":
+		client.saveCategory(w, r)
+		return
+	case "PUT":
+		client.updateCategory(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deleteCategory(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Category methods
+
+// add a category to the taxonomy, under parentId if it's given
+func (client *Client) saveCategory(w http.ResponseWriter, r *http.Request) {
+	var category database.Category
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return
+	}
+	if !validateCategory(w, &category, categories) {
+		return
+	}
+
+	savedCategory, err := client.dbClient.SaveCategory(category)
+	if err != nil {
+		writeError(w, "could not save category", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedCategory)
+	if err != nil {
+		writeError(w, "could not encode category", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// rename a category or move it under another one
+func (client *Client) updateCategory(w http.ResponseWriter, r *http.Request, id string) {
+	oldCategory, err := client.dbClient.GetCategory(id)
+	if err != nil {
+		writeError(w, "could not find category with that id", http.StatusNotFound)
+		return
+	}
+
+	var category database.Category
+	err = json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+	category.ID = oldCategory.ID
+	category.CreatedAt = oldCategory.CreatedAt
+
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return
+	}
+	if !validateCategory(w, &category, categories) {
+		return
+	}
+
+	err = client.dbClient.UpdateCategory(category, oldCategory.ID)
+	if err != nil {
+		writeError(w, "could not update category", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// return the whole taxonomy as a tree
+func (client *Client) listCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(database.CategoryTree(categories))
+	if err != nil {
+		writeError(w, "could not marshal categories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// return a category with the path to it and the categories under it
+func (client *Client) getCategory(w http.ResponseWriter, r *http.Request, id string) {
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return
+	}
+
+	path := database.CategoryPath(categories, id)
+	if len(path) == 0 {
+		writeError(w, "could not find category with that id", http.StatusNotFound)
+		return
+	}
+
+	children := []database.CategoryNode{}
+	var find func(nodes []database.CategoryNode)
+	find = func(nodes []database.CategoryNode) {
+		for _, node := range nodes {
+			if node.ID == id {
+				children = node.Children
+				return
+			}
+			find(node.Children)
+		}
+	}
+	find(database.CategoryTree(categories))
+
+	response := categoryResponse{Category: path[len(path)-1], Path: path, Children: children}
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal category", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// delete a category that has nothing under it, taking it off it's recipes
+func (client *Client) deleteCategory(w http.ResponseWriter, r *http.Request, id string) {
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return
+	}
+
+	if len(database.CategoryPath(categories, id)) == 0 {
+		writeError(w, "could not find category with that id", http.StatusNotFound)
+		return
+	}
+	if len(database.CategoryDescendants(categories, id)) > 1 {
+		writeError(w, "move or delete the categories under it first", http.StatusConflict)
+		return
+	}
+
+	err = client.dbClient.DeleteCategory(id)
+	if err != nil {
+		writeError(w, "could not delete category", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// checks the name is free among it's siblings and the parent exists and
+// isn't the category itself or under it
+func validateCategory(w http.ResponseWriter, category *database.Category, categories []database.Category) bool {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		writeError(w, "name must not be empty", http.StatusBadRequest)
+		return false
+	}
+
+	if category.ParentID != "" {
+		if len(database.CategoryPath(categories, category.ParentID)) == 0 {
+			writeError(w, "could not find category with that parentId", http.StatusBadRequest)
+			return false
+		}
+		if category.ID != "" && database.CategoryDescendants(categories, category.ID)[category.ParentID] {
+			writeError(w, "a category can't be moved under itself", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	for _, other := range categories {
+		if other.ID != category.ID && other.ParentID == category.ParentID && strings.EqualFold(other.Name, category.Name) {
+			writeError(w, "there is already a category with that name there", http.StatusConflict)
+			return false
+		}
+	}
+	return true
+}
+
+// checks every category a recipe is put in exists
+func (client *Client) validateRecipeCategories(w http.ResponseWriter, categoryIDs []string) bool {
+	if len(categoryIDs) == 0 {
+		return true
+	}
+
+	categories, err := client.dbClient.ListCategories()
+	if err != nil {
+		writeError(w, "error listing categories", http.StatusInternalServerError)
+		return false
+	}
+
+	for _, categoryID := range categoryIDs {
+		if len(database.CategoryPath(categories, categoryID)) == 0 {
+			writeError(w, "could not find category with id "+categoryID, http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
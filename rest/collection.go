@@ -0,0 +1,355 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type collectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// RecipeIDs replaces the recipes in the collection and their order,
+	// leaving it out keeps them as they are
+	RecipeIDs []string `json:"recipeIds"`
+	Shared    bool     `json:"shared"`
+}
+
+type collectionRecipeRequest struct {
+	RecipeID string `json:"recipeId"`
+	// Position is where the recipe goes, leaving it out adds it at the end
+	Position *int `json:"position"`
+}
+
+type collectionResponse struct {
+	database.Collection
+	// Recipes are the recipes in the collection, in order
+	Recipes []database.Recipe `json:"recipes"`
+}
+
+// handles the /collections route
+func (client *Client) handleCollections(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getCollection(w, r, vars["id"])
+		} else {
+			client.listCollections(w, r)
+		}
+		return
+	case "POST":
+		client.saveCollection(w, r)
+		return
+	case "PUT":
+		client.updateCollection(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deleteCollection(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /collections/{id}/recipes route
+func (client *Client) handleCollectionRecipes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	collection, ok := client.visibleCollection(w, r, vars["id"])
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		client.addCollectionRecipe(w, r, collection)
+		return
+	case "DELETE":
+		client.removeCollectionRecipe(w, r, collection, vars["recipeId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /recipe/{id}/collections route
+func (client *Client) handleRecipeCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	userID := principalFrom(r).UserID
+	collections, err := client.dbClient.ListRecipeCollections(recipe.ID, userID, client.householdMemberIDs(userID))
+	if err != nil {
+		writeError(w, "error listing collections", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(collections)
+	if err != nil {
+		writeError(w, "could not marshal collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// - MARK: Collection methods
+
+func (client *Client) saveCollection(w http.ResponseWriter, r *http.Request) {
+	var request collectionRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	collection := database.Collection{UserID: principalFrom(r).UserID, RecipeIDs: []string{}}
+	if !client.validateCollection(w, &collection, request) {
+		return
+	}
+
+	savedCollection, err := client.dbClient.SaveCollection(collection)
+	if err != nil {
+		writeError(w, "could not save collection", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedCollection)
+	if err != nil {
+		writeError(w, "could not encode collection", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// rename, describe or reorder a collection. Only the owner can share or stop
+// sharing it.
+func (client *Client) updateCollection(w http.ResponseWriter, r *http.Request, id string) {
+	oldCollection, ok := client.visibleCollection(w, r, id)
+	if !ok {
+		return
+	}
+
+	var request collectionRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.Shared != oldCollection.Shared && oldCollection.UserID != principalFrom(r).UserID {
+		writeError(w, "only the owner can change who a collection is shared with", http.StatusForbidden)
+		return
+	}
+
+	edited := *oldCollection
+	if !client.validateCollection(w, &edited, request) {
+		return
+	}
+
+	// applied to the latest collection, so recipes added since it was read
+	// stay unless the request replaces them, and a member can't undo the
+	// owner unsharing it in the meantime
+	callerID := principalFrom(r).UserID
+	_, err = client.dbClient.UpdateCollection(oldCollection.ID, func(collection *database.Collection) bool {
+		collection.Name = edited.Name
+		collection.Description = edited.Description
+		if collection.UserID == callerID {
+			collection.Shared = edited.Shared
+		}
+		if request.RecipeIDs != nil {
+			collection.RecipeIDs = edited.RecipeIDs
+		}
+		return true
+	})
+	if err != nil {
+		writeError(w, "could not update collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list the caller's collections and the ones shared with them, by name
+func (client *Client) listCollections(w http.ResponseWriter, r *http.Request) {
+	userID := principalFrom(r).UserID
+	collections, err := client.dbClient.ListCollections(userID, client.householdMemberIDs(userID))
+	if err != nil {
+		writeError(w, "error listing collections", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(collections)
+	if err != nil {
+		writeError(w, "could not marshal collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// return a collection with it's recipes
+func (client *Client) getCollection(w http.ResponseWriter, r *http.Request, id string) {
+	collection, ok := client.visibleCollection(w, r, id)
+	if !ok {
+		return
+	}
+
+	response := collectionResponse{Collection: *collection, Recipes: []database.Recipe{}}
+	for _, recipeID := range collection.RecipeIDs {
+		recipe, err := client.dbClient.GetRecipe(recipeID)
+		if err != nil {
+			continue
+		}
+		response.Recipes = append(response.Recipes, *recipe)
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// delete a collection, leaving the recipes in it alone. Only the owner can.
+func (client *Client) deleteCollection(w http.ResponseWriter, r *http.Request, id string) {
+	collection, ok := client.visibleCollection(w, r, id)
+	if !ok {
+		return
+	}
+
+	if collection.UserID != principalFrom(r).UserID {
+		writeError(w, "only the owner can delete a collection", http.StatusForbidden)
+		return
+	}
+
+	err := client.dbClient.DeleteCollection(collection.ID)
+	if err != nil {
+		writeError(w, "could not delete collection", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// add a recipe to a collection, or move it if it's already there
+func (client *Client) addCollectionRecipe(w http.ResponseWriter, r *http.Request, collection *database.Collection) {
+	var request collectionRecipeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := client.dbClient.GetRecipe(request.RecipeID); err != nil {
+		writeError(w, "could not find recipe with that recipeId", http.StatusBadRequest)
+		return
+	}
+
+	position := -1
+	if request.Position != nil {
+		if *request.Position < 0 {
+			writeError(w, "position must not be negative", http.StatusBadRequest)
+			return
+		}
+		position = *request.Position
+	}
+
+	collection, err = client.dbClient.UpdateCollection(collection.ID, func(collection *database.Collection) bool {
+		collection.AddRecipe(request.RecipeID, position)
+		return true
+	})
+	if err != nil {
+		writeError(w, "could not update collection", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(collection)
+	if err != nil {
+		writeError(w, "could not encode collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) removeCollectionRecipe(w http.ResponseWriter, r *http.Request, collection *database.Collection, recipeID string) {
+	removed := false
+	_, err := client.dbClient.UpdateCollection(collection.ID, func(collection *database.Collection) bool {
+		removed = collection.RemoveRecipe(recipeID)
+		return removed
+	})
+	if err != nil {
+		writeError(w, "could not update collection", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		writeError(w, "recipe is not in the collection", http.StatusNotFound)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// fetches a collection, writing a 404 if it doesn't exist or the caller
+// can't see it
+func (client *Client) visibleCollection(w http.ResponseWriter, r *http.Request, id string) (*database.Collection, bool) {
+	userID := principalFrom(r).UserID
+	collection, err := client.dbClient.GetCollection(id)
+	if err != nil || !collection.VisibleTo(userID, client.householdMemberIDs(userID)) {
+		writeError(w, "could not find collection with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return collection, true
+}
+
+// checks the name and recipes and copies them to the collection
+func (client *Client) validateCollection(w http.ResponseWriter, collection *database.Collection, request collectionRequest) bool {
+	collection.Name = strings.TrimSpace(request.Name)
+	if collection.Name == "" {
+		writeError(w, "name must not be empty", http.StatusBadRequest)
+		return false
+	}
+	collection.Description = strings.TrimSpace(request.Description)
+	collection.Shared = request.Shared
+
+	if request.RecipeIDs == nil {
+		return true
+	}
+
+	recipeIDs := []string{}
+	for _, recipeID := range request.RecipeIDs {
+		if database.ContainsString(recipeIDs, recipeID) {
+			writeError(w, "recipeIds must not repeat a recipe", http.StatusBadRequest)
+			return false
+		}
+		// recipes that were already in the collection don't need looking up
+		if !collection.Contains(recipeID) {
+			if _, err := client.dbClient.GetRecipe(recipeID); err != nil {
+				writeError(w, "could not find recipe with id "+recipeID, http.StatusBadRequest)
+				return false
+			}
+		}
+		recipeIDs = append(recipeIDs, recipeID)
+	}
+	collection.RecipeIDs = recipeIDs
+	return true
+}
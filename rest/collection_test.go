@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/backends/database/dynamotest"
+)
+
+// interruptedCollections runs interrupt before the next collection is saved,
+// like someone else saving it first
+type interruptedCollections struct {
+	*dynamotest.Client
+	interrupt func()
+}
+
+func (service *interruptedCollections) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if service.interrupt != nil && *input.TableName == database.CollectionTable {
+		interrupt := service.interrupt
+		service.interrupt = nil
+		interrupt()
+	}
+	return service.Client.PutItem(input)
+}
+
+func TestMembersCantReshareCollections(t *testing.T) {
+	client := newTestClient(t)
+	service := &interruptedCollections{Client: &dynamotest.Client{}}
+	client.dbClient = database.NewWithService(service)
+	samID, _ := login(t, client, "sam")
+	joID, jo := login(t, client, "jo")
+	household, _ := client.dbClient.CreateHousehold("Home", samID)
+	client.dbClient.AddHouseholdMember(*household, joID)
+
+	collection, _ := client.dbClient.SaveCollection(database.Collection{UserID: samID, Name: "Weeknight", Shared: true})
+
+	// sam stops sharing it while jo's rename is being saved
+	service.interrupt = func() {
+		_, err := client.dbClient.UpdateCollection(collection.ID, func(collection *database.Collection) bool {
+			collection.Shared = false
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Error unsharing collection: %s", err.Error())
+		}
+	}
+	w := serve(client, "PUT", "/api/collections/"+collection.ID, jo, collectionRequest{Name: "Weeknights", Shared: true})
+	expectStatus(t, w, http.StatusNoContent)
+
+	saved, _ := client.dbClient.GetCollection(collection.ID)
+	if saved.Shared || saved.Name != "Weeknights" {
+		t.Errorf("Expected the rename without sharing it again, got %+v", saved)
+	}
+}
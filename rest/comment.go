@@ -0,0 +1,346 @@
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+const (
+	defaultCommentLimit = 20
+	maxCommentLimit     = 100
+	maxCommentLength    = 5000
+)
+
+type commentRequest struct {
+	Text      string   `json:"text"`
+	ParentID  string   `json:"parentId"`
+	Private   bool     `json:"private"`
+	StepIndex *int     `json:"stepIndex"`
+	Mentions  []string `json:"mentions"`
+}
+
+type commentsResponse struct {
+	Comments []database.CommentThread `json:"comments"`
+	// Next is passed as ?after= for the next page, and empty on the last
+	Next string `json:"next,omitempty"`
+}
+
+type mentionEvent struct {
+	RecipeID   string           `json:"recipeId"`
+	RecipeName string           `json:"recipeName"`
+	Comment    database.Comment `json:"comment"`
+}
+
+// handles the /recipe/{id}/comments route
+func (client *Client) handleRecipeComments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	// comments are attributed to their author, so anonymous callers can
+	// only read them
+	if r.Method != "GET" && principalFrom(r).UserID == "" {
+		writeError(w, "log in to comment", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if commentID, ok := vars["commentId"]; ok {
+			client.getComment(w, r, recipe, commentID)
+		} else {
+			client.listComments(w, r, recipe)
+		}
+		return
+	case "POST":
+		client.saveComment(w, r, recipe)
+		return
+	case "PUT":
+		client.updateComment(w, r, recipe, vars["commentId"])
+		return
+	case "DELETE":
+		client.deleteComment(w, r, recipe, vars["commentId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Comment methods
+
+// comment on a recipe, reply to a comment, or keep a private note
+func (client *Client) saveComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	var request commentRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	userID := principalFrom(r).UserID
+	comment := database.Comment{
+		RecipeID: recipe.ID,
+		UserID:   userID,
+		Private:  request.Private,
+	}
+
+	if request.ParentID != "" {
+		parent, err := client.dbClient.GetComment(request.ParentID)
+		if err != nil || parent.RecipeID != recipe.ID || !parent.VisibleTo(userID) {
+			writeError(w, "could not find comment with that parentId", http.StatusBadRequest)
+			return
+		}
+		if parent.Private || request.Private {
+			writeError(w, "private notes can't have replies or be replies", http.StatusBadRequest)
+			return
+		}
+		comment.ParentID = parent.ID
+	}
+
+	if !client.validateComment(w, r, recipe, &comment, request) {
+		return
+	}
+
+	savedComment, err := client.dbClient.SaveComment(comment)
+	if err != nil {
+		writeError(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	client.notifyMentions(recipe, *savedComment, nil)
+
+	bytes, err := json.Marshal(savedComment)
+	if err != nil {
+		writeError(w, "could not encode comment", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// edit the caller's comment
+func (client *Client) updateComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) {
+	oldComment, ok := client.authoredComment(w, r, recipe, id)
+	if !ok {
+		return
+	}
+
+	var request commentRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	// where a comment is in a thread and who can see it can't change
+	comment := *oldComment
+	if !client.validateComment(w, r, recipe, &comment, request) {
+		return
+	}
+
+	err = client.dbClient.UpdateComment(comment, oldComment.ID)
+	if err != nil {
+		writeError(w, "could not update comment", http.StatusInternalServerError)
+		return
+	}
+	client.notifyMentions(recipe, comment, oldComment.Mentions)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list the recipe's comment threads and the caller's notes, oldest first.
+// ?step=N only lists the ones pinned to a step, and ?limit= and ?after= page
+// through them.
+func (client *Client) listComments(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	query := r.URL.Query()
+
+	limit := defaultCommentLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxCommentLimit {
+			writeError(w, "limit must be between 1 and "+strconv.Itoa(maxCommentLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	step := -1
+	if value := query.Get("step"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, "step must be a step index", http.StatusBadRequest)
+			return
+		}
+		step = parsed
+	}
+
+	comments, err := client.dbClient.ListComments(recipe.ID)
+	if err != nil {
+		writeError(w, "error listing comments", http.StatusInternalServerError)
+		return
+	}
+
+	threads := database.ThreadComments(comments, principalFrom(r).UserID)
+	if step >= 0 {
+		pinned := []database.CommentThread{}
+		for _, thread := range threads {
+			if thread.StepIndex != nil && *thread.StepIndex == step {
+				pinned = append(pinned, thread)
+			}
+		}
+		threads = pinned
+	}
+
+	page, next, err := database.PageThreads(threads, query.Get("after"), limit)
+	if err != nil {
+		writeError(w, "after must be the next value from another page", http.StatusBadRequest)
+		return
+	}
+	bytes, err := json.Marshal(commentsResponse{Comments: page, Next: next})
+	if err != nil {
+		writeError(w, "could not marshal comments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) getComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) {
+	comment, err := client.dbClient.GetComment(id)
+	if err != nil || comment.RecipeID != recipe.ID || !comment.VisibleTo(principalFrom(r).UserID) {
+		writeError(w, "could not find comment with that id", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(comment)
+	if err != nil {
+		writeError(w, "could not marshal comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// delete the caller's comment. Comments with replies are blanked out so the
+// thread still makes sense.
+func (client *Client) deleteComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) {
+	comment, ok := client.authoredComment(w, r, recipe, id)
+	if !ok {
+		return
+	}
+
+	comments, err := client.dbClient.ListComments(recipe.ID)
+	if err != nil {
+		writeError(w, "could not delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	hasReplies := false
+	for _, other := range comments {
+		hasReplies = hasReplies || other.ParentID == comment.ID
+	}
+
+	if hasReplies {
+		comment.Deleted = true
+		comment.Text = ""
+		comment.Mentions = nil
+		err = client.dbClient.UpdateComment(*comment, comment.ID)
+	} else {
+		err = client.dbClient.DeleteComment(comment.ID)
+	}
+	if err != nil {
+		writeError(w, "could not delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// only the author can change a comment, and deleted ones can't be
+func (client *Client) authoredComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) (*database.Comment, bool) {
+	comment, err := client.dbClient.GetComment(id)
+	if err != nil || comment.RecipeID != recipe.ID || comment.Deleted || comment.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find comment with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return comment, true
+}
+
+// checks the text, step and mentions and copies them to the comment.
+// Only members of the author's household can be mentioned.
+func (client *Client) validateComment(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, comment *database.Comment, request commentRequest) bool {
+	comment.Text = strings.TrimSpace(request.Text)
+	if comment.Text == "" {
+		writeError(w, "text must not be empty", http.StatusBadRequest)
+		return false
+	}
+	if len(comment.Text) > maxCommentLength {
+		writeError(w, "text must be at most "+strconv.Itoa(maxCommentLength)+" characters", http.StatusBadRequest)
+		return false
+	}
+
+	if request.StepIndex != nil && (*request.StepIndex < 0 || *request.StepIndex >= len(recipe.Steps)) {
+		writeError(w, "stepIndex must be the index of one of the recipe's steps", http.StatusBadRequest)
+		return false
+	}
+	comment.StepIndex = request.StepIndex
+
+	comment.Mentions = nil
+	if len(request.Mentions) == 0 {
+		return true
+	}
+	if comment.Private {
+		writeError(w, "private notes can't mention anyone", http.StatusBadRequest)
+		return false
+	}
+
+	household, err := client.dbClient.GetUserHousehold(comment.UserID)
+	for _, userID := range request.Mentions {
+		if err != nil || !household.HasMember(userID) || userID == comment.UserID {
+			writeError(w, "mentions must be other members of your household", http.StatusBadRequest)
+			return false
+		}
+		comment.Mentions = database.AppendUnique(comment.Mentions, userID)
+	}
+	return true
+}
+
+// lets the newly mentioned know through their webhooks
+func (client *Client) notifyMentions(recipe *database.Recipe, comment database.Comment, alreadyMentioned []string) {
+	event := mentionEvent{RecipeID: recipe.ID, RecipeName: recipe.Name, Comment: comment}
+	for _, userID := range comment.Mentions {
+		if database.ContainsString(alreadyMentioned, userID) {
+			continue
+		}
+
+		webhooks, err := client.dbClient.ListWebhooks(userID)
+		if err != nil {
+			log.Printf("error listing webhooks for %s: %v", userID, err)
+			continue
+		}
+		for _, webhook := range webhooks {
+			if !webhook.Subscribed(database.EventCommentMention) {
+				continue
+			}
+
+			webhook := webhook
+			err = client.jobs.Enqueue("webhook "+webhook.ID, func() error {
+				return client.webhooks.Deliver(webhook.URL, webhook.Secret, database.EventCommentMention, event)
+			})
+			if err != nil {
+				log.Printf("error queueing webhook %s: %v", webhook.ID, err)
+			}
+		}
+	}
+}
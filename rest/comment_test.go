@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestCommentsNeedAUser(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "alice")
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Stew"})
+	path := "/api/recipe/" + saved.ID + "/comments"
+
+	expectStatus(t, serve(client, "POST", path, "", commentRequest{Text: "anonymous"}), http.StatusUnauthorized)
+	for _, text := range []string{"first", "second", "third"} {
+		expectStatus(t, serve(client, "POST", path, token, commentRequest{Text: text}), http.StatusCreated)
+	}
+	expectStatus(t, serve(client, "POST", path, token, commentRequest{Text: "just for me", Private: true}), http.StatusCreated)
+
+	w := serve(client, "GET", path+"?limit=2", "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var response commentsResponse
+	decode(t, w, &response)
+	if len(response.Comments) != 2 || response.Next == "" {
+		t.Fatalf("Expected a first page of 2, got %+v", response)
+	}
+
+	w = serve(client, "GET", path+"?limit=2&after="+response.Next, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var last commentsResponse
+	decode(t, w, &last)
+	if len(last.Comments) != 1 || last.Comments[0].Text != "third" || last.Next != "" {
+		t.Errorf("Expected the last public comment without the private note, got %+v", last)
+	}
+
+	expectStatus(t, serve(client, "GET", path+"?after=unknown", "", nil), http.StatusBadRequest)
+}
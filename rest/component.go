@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type expandedIngredientsResponse struct {
+	RecipeID string `json:"recipeId"`
+	// Ingredients includes the ingredients of every component, all the way
+	// down, instead of the components themselves
+	Ingredients map[string]string `json:"ingredients"`
+}
+
+// handles the /recipe/{id}/ingredients route
+func (client *Client) handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	flat := client.flattenRecipe(*recipe)
+	bytes, err := json.Marshal(expandedIngredientsResponse{RecipeID: recipe.ID, Ingredients: flat.Ingredients})
+	if err != nil {
+		writeError(w, "could not marshal ingredients", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// - MARK: Helper Functions
+
+// the recipe with its components replaced by their ingredients. Cycles are
+// refused when recipes are saved, but if one slips through the recipe is
+// used as it is.
+func (client *Client) flattenRecipe(recipe database.Recipe) database.Recipe {
+	flat, err := recipe.Flatten(client.dbClient.GetRecipe)
+	if err != nil {
+		log.Printf("error flattening recipe %s: %v", recipe.ID, err)
+		return recipe
+	}
+	return flat
+}
+
+// checks every component is one of the recipe's ingredients and refers to a
+// recipe that doesn't use this one
+func (client *Client) validateComponents(w http.ResponseWriter, recipe database.Recipe) bool {
+	if len(recipe.Components) == 0 {
+		return true
+	}
+
+	for name, component := range recipe.Components {
+		if _, ok := recipe.Ingredients[name]; !ok {
+			writeError(w, "components must be keyed by one of the recipe's ingredients", http.StatusBadRequest)
+			return false
+		}
+		if component.Batches < 0 {
+			writeError(w, "batches must not be negative", http.StatusBadRequest)
+			return false
+		}
+		if _, err := client.dbClient.GetRecipe(component.RecipeID); err != nil {
+			writeError(w, "could not find recipe with id "+component.RecipeID, http.StatusBadRequest)
+			return false
+		}
+	}
+
+	_, err := recipe.Flatten(client.dbClient.GetRecipe)
+	if errors.Is(err, database.ErrRecipeCycle) {
+		writeError(w, "a recipe can't be one of its own ingredients", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		writeError(w, "could not check components", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
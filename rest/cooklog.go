@@ -0,0 +1,528 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/imaging"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// how many recipes the stats list at most
+const cookStatsLimit = 10
+
+type recipeCookedRequest struct {
+	// Servings defaults to the recipe's servings
+	Servings int `json:"servings"`
+	// CookedOn defaults to today
+	CookedOn string `json:"cookedOn"`
+	Rating   int    `json:"rating"`
+	Notes    string `json:"notes"`
+	// SkipPantry leaves the pantry alone, for logging meals after the fact
+	SkipPantry bool `json:"skipPantry"`
+}
+
+type recipeCookedResponse struct {
+	Entry *database.CookLogEntry `json:"entry"`
+	// Pantry is what's left after cooking
+	Pantry []database.PantryItem `json:"pantry"`
+	// PantryError says what couldn't be taken out of the pantry, the entry is
+	// saved either way
+	PantryError string `json:"pantryError,omitempty"`
+}
+
+type cookLogUpdateRequest struct {
+	CookedOn string `json:"cookedOn"`
+	Servings int    `json:"servings"`
+	Rating   int    `json:"rating"`
+	Notes    string `json:"notes"`
+}
+
+type recipeCookLogResponse struct {
+	RecipeID    string                  `json:"recipeId"`
+	TimesCooked int                     `json:"timesCooked"`
+	LastCooked  string                  `json:"lastCooked,omitempty"`
+	Entries     []database.CookLogEntry `json:"entries"`
+}
+
+type cookStatsResponse struct {
+	Year               int                        `json:"year"`
+	CookedThisYear     int                        `json:"cookedThisYear"`
+	MostCookedThisYear []database.RecipeCookCount `json:"mostCookedThisYear"`
+	// NotMadeSince is the date NotMadeLately is counted from
+	NotMadeSince  string                     `json:"notMadeSince"`
+	NotMadeLately []database.RecipeCookCount `json:"notMadeLately"`
+}
+
+// handles the /recipe/{id}/cooked route
+func (client *Client) handleRecipeCooked(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	client.cookRecipe(w, r, recipe)
+}
+
+// handles the /recipe/{id}/cooklog route
+func (client *Client) handleRecipeCookLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	client.listRecipeCookLog(w, r, recipe)
+}
+
+// handles the /cooklog route
+func (client *Client) handleCookLog(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getCookLogEntry(w, r, vars["id"])
+		} else {
+			client.listCookLog(w, r)
+		}
+		return
+	case "PUT":
+		client.updateCookLogEntry(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deleteCookLogEntry(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /cooklog/{id}/photo route
+func (client *Client) handleCookLogPhoto(w http.ResponseWriter, r *http.Request) {
+	entry, ok := client.ownedCookLogEntry(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		client.getCookLogPhoto(w, r, entry)
+		return
+	case "POST", "PUT":
+		client.uploadCookLogPhoto(w, r, entry)
+		return
+	case "DELETE":
+		client.deleteCookLogPhoto(w, r, entry)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Cook log methods
+
+// log the recipe as cooked and take it's ingredients out of the caller's
+// pantry
+func (client *Client) cookRecipe(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	var request recipeCookedRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	userID := principalFrom(r).UserID
+	entry := database.CookLogEntry{
+		UserID:     userID,
+		RecipeID:   recipe.ID,
+		RecipeName: recipe.Name,
+	}
+	update := cookLogUpdateRequest{
+		CookedOn: request.CookedOn,
+		Servings: request.Servings,
+		Rating:   request.Rating,
+		Notes:    request.Notes,
+	}
+	if !validateCookLogEntry(w, &entry, update) {
+		return
+	}
+
+	// the entry is what the caller asked for, so it's saved even if the
+	// pantry can't be updated
+	savedEntry, err := client.dbClient.SaveCookLogEntry(entry)
+	if err != nil {
+		writeError(w, "could not save cook log entry", http.StatusInternalServerError)
+		return
+	}
+	response := recipeCookedResponse{Entry: savedEntry}
+
+	if !request.SkipPantry {
+		scale := 1.0
+		if request.Servings > 0 && recipe.Servings > 0 {
+			scale = float64(request.Servings) / float64(recipe.Servings)
+		}
+
+		ingredients := client.flattenRecipe(*recipe).Ingredients
+		names := []string{}
+		for name := range ingredients {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		used := []string{}
+		changes := []database.PantryChange{}
+		for _, name := range names {
+			quantity, ok := ingredient.Parse(ingredients[name])
+			if !ok {
+				continue
+			}
+			used = append(used, name)
+			changes = append(changes, database.PantryChange{Name: name, Quantity: quantity.Scale(scale)})
+		}
+
+		// all the ingredients come out together, or none of them do
+		err = client.dbClient.ChangePantry(userID, changes)
+		if err != nil {
+			log.Printf("error taking %s's ingredients out of the pantry: %v", userID, err)
+			response.PantryError = "could not update pantry for " + strings.Join(used, ", ")
+		}
+	}
+
+	response.Pantry, err = client.dbClient.ListPantryItems(userID)
+	if err != nil {
+		response.Pantry = []database.PantryItem{}
+		response.PantryError = "could not list pantry"
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal cook log entry", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// change when, how much or how well the caller cooked something
+func (client *Client) updateCookLogEntry(w http.ResponseWriter, r *http.Request, id string) {
+	oldEntry, ok := client.ownedCookLogEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	var request cookLogUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	entry := *oldEntry
+	if !validateCookLogEntry(w, &entry, request) {
+		return
+	}
+
+	err = client.dbClient.UpdateCookLogEntry(entry, oldEntry.ID)
+	if err != nil {
+		writeError(w, "could not update cook log entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list what the caller has cooked, most recent first, optionally between
+// ?from= and ?to= dates
+func (client *Client) listCookLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse(database.DateFormat, date); date != "" && err != nil {
+			writeError(w, "dates must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := client.dbClient.ListCookLog([]string{principalFrom(r).UserID})
+	if err != nil {
+		writeError(w, "error listing cook log", http.StatusInternalServerError)
+		return
+	}
+
+	between := []database.CookLogEntry{}
+	for _, entry := range entries {
+		if (from == "" || entry.CookedOn >= from) && (to == "" || entry.CookedOn <= to) {
+			between = append(between, entry)
+		}
+	}
+
+	bytes, err := json.Marshal(between)
+	if err != nil {
+		writeError(w, "could not marshal cook log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// list when the caller's household cooked a recipe
+func (client *Client) listRecipeCookLog(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	entries, err := client.dbClient.ListRecipeCookLog(recipe.ID, client.householdMemberIDs(principalFrom(r).UserID))
+	if err != nil {
+		writeError(w, "error listing cook log", http.StatusInternalServerError)
+		return
+	}
+
+	response := recipeCookLogResponse{RecipeID: recipe.ID, TimesCooked: len(entries), Entries: entries}
+	if len(entries) > 0 {
+		response.LastCooked = entries[0].CookedOn
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal cook log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// handles the /cooklog/stats route: the caller's most cooked recipes this
+// year, and the ones they haven't made in ?months=N (6 by default)
+func (client *Client) handleCookStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	months := 6
+	if value := r.URL.Query().Get("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeError(w, "months must be a positive number", http.StatusBadRequest)
+			return
+		}
+		months = parsed
+	}
+
+	entries, err := client.dbClient.ListCookLog([]string{principalFrom(r).UserID})
+	if err != nil {
+		writeError(w, "error listing cook log", http.StatusInternalServerError)
+		return
+	}
+
+	today := time.Now()
+	yearStart := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, today.Location()).Format(database.DateFormat)
+	thisYear := database.CookedSince(entries, yearStart)
+
+	response := cookStatsResponse{
+		Year:               today.Year(),
+		CookedThisYear:     len(thisYear),
+		MostCookedThisYear: limitCookCounts(database.CookCounts(thisYear)),
+		NotMadeSince:       today.AddDate(0, -months, 0).Format(database.DateFormat),
+	}
+	response.NotMadeLately = limitCookCounts(database.NotCookedSince(entries, response.NotMadeSince))
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal cook stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) getCookLogEntry(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := client.ownedCookLogEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		writeError(w, "could not marshal cook log entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// delete an entry and it's photo. The pantry is left as it is.
+func (client *Client) deleteCookLogEntry(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := client.ownedCookLogEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	err := client.dbClient.DeleteCookLogEntry(entry.ID)
+	if err != nil {
+		writeError(w, "could not delete cook log entry", http.StatusInternalServerError)
+		return
+	}
+
+	if entry.Photo != nil {
+		client.deleteBlobs([]string{entry.Photo.Key})
+	}
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// attach a photo, uploaded as the "photo" field of a multipart form,
+// replacing any earlier one
+func (client *Client) uploadCookLogPhoto(w http.ResponseWriter, r *http.Request, entry *database.CookLogEntry) {
+	upload, ok := readImageUpload(w, r, "photo")
+	if !ok {
+		return
+	}
+
+	cleaned, upright, err := imaging.Sanitize(upload.data, upload.format, upload.img)
+	if err != nil {
+		writeError(w, "could not process image", http.StatusInternalServerError)
+		return
+	}
+
+	version, err := uuid.NewRandom()
+	if err != nil {
+		writeError(w, "could not save photo", http.StatusInternalServerError)
+		return
+	}
+
+	photo := &database.CookLogPhoto{
+		ContentType: upload.contentType,
+		Width:       upright.Bounds().Dx(),
+		Height:      upright.Bounds().Dy(),
+		UploadedAt:  time.Now().UTC(),
+		Key:         "cooklog/" + entry.ID + "/" + version.String() + upload.extension,
+	}
+
+	err = client.blobStore.Put(photo.Key, upload.contentType, bytes.NewReader(cleaned))
+	if err != nil {
+		writeError(w, "could not save photo", http.StatusInternalServerError)
+		return
+	}
+
+	oldPhoto := entry.Photo
+	entry.Photo = photo
+	err = client.dbClient.UpdateCookLogEntry(*entry, entry.ID)
+	if err != nil {
+		client.deleteBlobs([]string{photo.Key})
+		writeError(w, "could not save photo", http.StatusInternalServerError)
+		return
+	}
+	if oldPhoto != nil {
+		client.deleteBlobs([]string{oldPhoto.Key})
+	}
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		writeError(w, "could not encode cook log entry", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+func (client *Client) getCookLogPhoto(w http.ResponseWriter, r *http.Request, entry *database.CookLogEntry) {
+	if entry.Photo == nil {
+		writeError(w, "cook log entry has no photo", http.StatusNotFound)
+		return
+	}
+
+	// each upload gets a new key, so it works as an ETag
+	client.serveBlob(w, r, entry.Photo.Key, entry.Photo.Key, entry.Photo.UploadedAt)
+}
+
+func (client *Client) deleteCookLogPhoto(w http.ResponseWriter, r *http.Request, entry *database.CookLogEntry) {
+	if entry.Photo == nil {
+		writeError(w, "cook log entry has no photo", http.StatusNotFound)
+		return
+	}
+
+	key := entry.Photo.Key
+	entry.Photo = nil
+	err := client.dbClient.UpdateCookLogEntry(*entry, entry.ID)
+	if err != nil {
+		writeError(w, "could not delete photo", http.StatusInternalServerError)
+		return
+	}
+
+	client.deleteBlobs([]string{key})
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// fetches a cook log entry, writing a 404 if it doesn't exist or belongs to
+// someone else
+func (client *Client) ownedCookLogEntry(w http.ResponseWriter, r *http.Request, id string) (*database.CookLogEntry, bool) {
+	entry, err := client.dbClient.GetCookLogEntry(id)
+	if err != nil || entry.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find cook log entry with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return entry, true
+}
+
+// checks the date, servings and rating and copies them to the entry
+func validateCookLogEntry(w http.ResponseWriter, entry *database.CookLogEntry, request cookLogUpdateRequest) bool {
+	if request.CookedOn == "" {
+		request.CookedOn = time.Now().Format(database.DateFormat)
+	}
+	if _, err := time.Parse(database.DateFormat, request.CookedOn); err != nil {
+		writeError(w, "cookedOn must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return false
+	}
+
+	if request.Servings < 0 {
+		writeError(w, "servings must not be negative", http.StatusBadRequest)
+		return false
+	}
+
+	if request.Rating < 0 || request.Rating > database.MaxRating {
+		writeError(w, "rating must be between 1 and "+strconv.Itoa(database.MaxRating), http.StatusBadRequest)
+		return false
+	}
+
+	entry.CookedOn = request.CookedOn
+	entry.Servings = request.Servings
+	entry.Rating = request.Rating
+	entry.Notes = strings.TrimSpace(request.Notes)
+	return true
+}
+
+// the user and everyone in their household
+func (client *Client) householdMemberIDs(userID string) []string {
+	household, err := client.dbClient.GetUserHousehold(userID)
+	if err != nil {
+		return []string{userID}
+	}
+	return household.MemberIDs
+}
+
+func limitCookCounts(counts []database.RecipeCookCount) []database.RecipeCookCount {
+	if len(counts) > cookStatsLimit {
+		return counts[:cookStatsLimit]
+	}
+	return counts
+}
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/backends/database/dynamotest"
+)
+
+// readOnlyPantry fails every write to the pantry once broken is set
+type readOnlyPantry struct {
+	*dynamotest.Client
+	broken bool
+}
+
+func (service *readOnlyPantry) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if service.broken && *input.TableName == database.PantryTable {
+		return nil, errors.New("pantry unavailable")
+	}
+	return service.Client.PutItem(input)
+}
+
+func (service *readOnlyPantry) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	if service.broken {
+		return nil, errors.New("pantry unavailable")
+	}
+	return service.Client.TransactWriteItems(input)
+}
+
+func TestCookingLogsEvenWhenThePantryFails(t *testing.T) {
+	client := newTestClient(t)
+	service := &readOnlyPantry{Client: &dynamotest.Client{}}
+	client.dbClient = database.NewWithService(service)
+	userID, token := login(t, client, "alice")
+
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Toast", Servings: 1, Ingredients: map[string]string{"butter": "1 tbsp"}})
+	client.dbClient.SavePantryItem(database.PantryItem{UserID: userID, Name: "butter", Quantity: 4, Unit: "tbsp"})
+	path := "/api/recipe/" + saved.ID + "/cooked"
+
+	w := serve(client, "POST", path, token, nil)
+	expectStatus(t, w, http.StatusCreated)
+	var cooked recipeCookedResponse
+	decode(t, w, &cooked)
+	if cooked.PantryError != "" || len(cooked.Pantry) != 1 || cooked.Pantry[0].Quantity != 3 {
+		t.Errorf("Expected a tablespoon of butter to be used, got %+v", cooked)
+	}
+
+	service.broken = true
+	w = serve(client, "POST", path, token, nil)
+	expectStatus(t, w, http.StatusCreated)
+	var failed recipeCookedResponse
+	decode(t, w, &failed)
+	if failed.Entry == nil || failed.PantryError != "could not update pantry for butter" || failed.Pantry[0].Quantity != 3 {
+		t.Errorf("Expected the entry to be saved and the pantry error reported, got %+v", failed)
+	}
+
+	entries, _ := client.dbClient.ListCookLog([]string{userID})
+	if len(entries) != 2 {
+		t.Errorf("Expected both cooks to be logged, got %d", len(entries))
+	}
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type recipeCostResponse struct {
+	RecipeID string `json:"recipeId"`
+	database.CostEstimate
+}
+
+type shoppingListCostResponse struct {
+	ShoppingListID string `json:"shoppingListId"`
+	database.CostEstimate
+}
+
+type mealPlanCostResponse struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Total float64    `json:"total"`
+	Meals []mealCost `json:"meals"`
+}
+
+type mealCost struct {
+	database.MealPlanEntry
+	Cost database.CostEstimate `json:"cost"`
+}
+
+// handles the /recipe/{id}/cost route
+func (client *Client) handleRecipeCost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	scale := 1.0
+	if servings := r.URL.Query().Get("servings"); servings != "" {
+		count, err := strconv.Atoi(servings)
+		if err != nil || count <= 0 {
+			writeError(w, "servings must be a positive number", http.StatusBadRequest)
+			return
+		}
+		if recipe.Servings > 0 {
+			scale = float64(count) / float64(recipe.Servings)
+		}
+	}
+
+	prices, err := client.callerPrices(r)
+	if err != nil {
+		writeError(w, "error listing prices", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(recipeCostResponse{
+		RecipeID:     recipe.ID,
+		CostEstimate: database.EstimateRecipeCost(client.flattenRecipe(*recipe), scale, prices),
+	})
+	if err != nil {
+		writeError(w, "could not marshal cost", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// handles the /shoppinglist/{id}/cost route
+func (client *Client) handleShoppingListCost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, ok := client.ownedShoppingList(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	prices, err := client.callerPrices(r)
+	if err != nil {
+		writeError(w, "error listing prices", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shoppingListCostResponse{
+		ShoppingListID: list.ID,
+		CostEstimate:   database.EstimateShoppingCost(list.Items, prices),
+	})
+	if err != nil {
+		writeError(w, "could not marshal cost", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// handles the /mealplan/cost route, for the same week or date range as
+// listing the meal plan
+func (client *Client) handleMealPlanCost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, to, err := planRange(r)
+	if err != nil {
+		writeError(w, "dates must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := client.dbClient.ListMealPlanEntries(principalFrom(r).UserID, from, to)
+	if err != nil {
+		writeError(w, "error listing meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	prices, err := client.callerPrices(r)
+	if err != nil {
+		writeError(w, "error listing prices", http.StatusInternalServerError)
+		return
+	}
+
+	response := mealPlanCostResponse{From: from, To: to, Meals: []mealCost{}}
+	for _, entry := range entries {
+		recipe, err := client.dbClient.GetRecipe(entry.RecipeID)
+		if err != nil {
+			continue
+		}
+
+		scale := 1.0
+		if entry.Servings > 0 && recipe.Servings > 0 {
+			scale = float64(entry.Servings) / float64(recipe.Servings)
+		}
+		cost := database.EstimateRecipeCost(client.flattenRecipe(*recipe), scale, prices)
+		response.Total += cost.Total
+		response.Meals = append(response.Meals, mealCost{MealPlanEntry: entry, Cost: cost})
+	}
+	response.Total = math.Round(response.Total*100) / 100
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal cost", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+// DigestInterval is how often the expiring pantry digest is sent
+const DigestInterval = 24 * time.Hour
+
+// DigestCheckInterval is how often webhooks are checked for a digest that's
+// due, so restarts delay digests by at most this long
+const DigestCheckInterval = 15 * time.Minute
+
+// how many days ahead count as expiring soon, unless asked otherwise
+const defaultExpiringDays = 3
+
+// the most recipes suggested at once
+const maxSuggestions = 10
+
+type pantryDigest struct {
+	Expiring    []database.PantryItem       `json:"expiring"`
+	Suggestions []database.RecipeSuggestion `json:"suggestions"`
+}
+
+// handles the /pantry/expiring route
+func (client *Client) handlePantryExpiring(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultExpiringDays
+	if query := r.URL.Query().Get("days"); query != "" {
+		var err error
+		days, err = strconv.Atoi(query)
+		if err != nil || days < 0 {
+			writeError(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	digest, err := client.pantryDigest(principalFrom(r).UserID, days)
+	if err != nil {
+		writeError(w, "could not suggest recipes", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(digest)
+	if err != nil {
+		writeError(w, "could not marshal suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// - MARK: Helper Functions
+
+// the user's pantry items expiring in the next days, and the recipes that
+// use the most of them
+func (client *Client) pantryDigest(userID string, days int) (*pantryDigest, error) {
+	pantry, err := client.dbClient.ListPantryItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now()
+	digest := &pantryDigest{
+		Expiring:    database.ExpiringPantryItems(pantry, today, days),
+		Suggestions: []database.RecipeSuggestion{},
+	}
+	if len(digest.Expiring) == 0 {
+		return digest, nil
+	}
+
+	recipes, err := client.dbClient.ListAllRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Suggestions = database.SuggestRecipes(recipes, digest.Expiring, today)
+	if len(digest.Suggestions) > maxSuggestions {
+		digest.Suggestions = digest.Suggestions[:maxSuggestions]
+	}
+	return digest, nil
+}
+
+// sendPantryDigests runs every DigestCheckInterval, delivering each
+// subscribed user's digest to their webhooks a day after the last one when
+// something is about to expire
+func (client *Client) sendPantryDigests() error {
+	webhooks, err := client.dbClient.ListWebhooksForEvent(database.EventPantryExpiring)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	digests := map[string]*pantryDigest{}
+	for _, webhook := range webhooks {
+		if now.Sub(webhook.DigestSentAt) < DigestInterval {
+			continue
+		}
+		// other servers may be sending the same digests
+		claimed, err := client.dbClient.ClaimWebhookDigest(webhook, now)
+		if err != nil {
+			log.Printf("error claiming pantry digest for webhook %s: %v", webhook.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		digest, ok := digests[webhook.UserID]
+		if !ok {
+			digest, err = client.pantryDigest(webhook.UserID, defaultExpiringDays)
+			if err != nil {
+				log.Printf("error building pantry digest for %s: %v", webhook.UserID, err)
+				continue
+			}
+			digests[webhook.UserID] = digest
+		}
+		if len(digest.Expiring) == 0 {
+			continue
+		}
+
+		// each delivery is retried on it's own
+		webhook := webhook
+		err = client.jobs.Enqueue("webhook "+webhook.ID, func() error {
+			return client.webhooks.Deliver(webhook.URL, webhook.Secret, database.EventPantryExpiring, digest)
+		})
+		if err != nil {
+			log.Printf("error queueing webhook %s: %v", webhook.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestPantryDigestsAreSentOnceADay(t *testing.T) {
+	client := newTestClient(t)
+	userID, _ := login(t, client, "alice")
+	webhook, _ := client.dbClient.CreateWebhook(userID, "https://example.com/hook", []string{database.EventPantryExpiring})
+
+	err := client.sendPantryDigests()
+	if err != nil {
+		t.Fatalf("Error sending digests: %s", err.Error())
+	}
+	sent, _ := client.dbClient.GetWebhook(webhook.ID)
+	if time.Since(sent.DigestSentAt) > time.Minute {
+		t.Fatalf("Expected the digest to be recorded as sent, got %s", sent.DigestSentAt)
+	}
+
+	// the next check, maybe after a restart, has nothing to send
+	client.sendPantryDigests()
+	again, _ := client.dbClient.GetWebhook(webhook.ID)
+	if !again.DigestSentAt.Equal(sent.DigestSentAt) {
+		t.Errorf("Expected the digest not to be sent again, got %s", again.DigestSentAt)
+	}
+}
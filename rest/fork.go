@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type forkRequest struct {
+	// Name defaults to the parent's
+	Name string `json:"name"`
+}
+
+type recipeDiffResponse struct {
+	database.RecipeDiff
+	// From is the recipe the diff is against, and To the recipe asked about,
+	// with the revisions compared
+	From         string `json:"from"`
+	FromRevision int    `json:"fromRevision"`
+	To           string `json:"to"`
+	ToRevision   int    `json:"toRevision"`
+}
+
+// handles the /recipe/{id}/fork route
+func (client *Client) handleRecipeFork(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	client.forkRecipe(w, r, recipe)
+}
+
+// handles the /recipe/{id}/forks route
+func (client *Client) handleRecipeForks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	forks, err := client.dbClient.ListRecipeForks(recipe.ID)
+	if err != nil {
+		writeError(w, "error listing forks", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(forks)
+	if err != nil {
+		writeError(w, "could not marshal forks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// handles the /recipe/{id}/diff route
+func (client *Client) handleRecipeDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	client.diffRecipe(w, r, recipe)
+}
+
+// - MARK: Fork methods
+
+// copy a recipe for the caller to adapt
+func (client *Client) forkRecipe(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	var request forkRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	fork := recipe.Fork(principalFrom(r).UserID)
+	if name := strings.TrimSpace(request.Name); name != "" {
+		fork.Name = name
+	}
+	client.labelRecipe(&fork)
+
+	savedFork, err := client.dbClient.SaveRecipe(fork)
+	if err != nil {
+		writeError(w, "could not save fork", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedFork)
+	if err != nil {
+		writeError(w, "could not encode fork", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// compare a fork to its parent as it was forked, any recipe to the one in
+// ?against=, or a recipe to an earlier ?revision= of itself
+func (client *Client) diffRecipe(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	query := r.URL.Query()
+	againstID := query.Get("against")
+	if againstID == "" {
+		againstID = recipe.ParentID
+	}
+
+	var against *database.Recipe
+	var err error
+	switch {
+	case query.Get("revision") != "":
+		revision, convErr := strconv.Atoi(query.Get("revision"))
+		if convErr != nil {
+			writeError(w, "revision must be a number", http.StatusBadRequest)
+			return
+		}
+		against, err = client.dbClient.GetRecipeRevision(recipe.ID, revision)
+	case query.Get("against") == "" && recipe.ParentRevision > 0:
+		against, err = client.dbClient.GetRecipeRevision(againstID, recipe.ParentRevision)
+		// only the latest revisions are kept, so old forks compare to the
+		// parent as it is now
+		if err != nil {
+			against, err = client.dbClient.GetRecipe(againstID)
+		}
+	case againstID != "":
+		against, err = client.dbClient.GetRecipe(againstID)
+	default:
+		writeError(w, "recipe isn't a fork, pass ?against= to compare it to another recipe", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, "could not find recipe to compare against", http.StatusNotFound)
+		return
+	}
+
+	response := recipeDiffResponse{
+		RecipeDiff:   database.DiffRecipes(*against, *recipe),
+		From:         against.ID,
+		FromRevision: against.Revision,
+		To:           recipe.ID,
+		ToRevision:   recipe.Revision,
+	}
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal diff", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestDiffForkAgainstParentAsForked(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "cousin")
+	parent, _ := client.dbClient.SaveRecipe(database.Recipe{
+		Name:        "Chili",
+		Ingredients: map[string]string{"beans": "2 cans"},
+		Steps:       []string{"Simmer"},
+	})
+
+	w := serve(client, "POST", "/api/recipe/"+parent.ID+"/fork", token, nil)
+	expectStatus(t, w, http.StatusCreated)
+	var fork database.Recipe
+	decode(t, w, &fork)
+	if fork.ParentID != parent.ID || fork.ParentRevision != parent.Revision {
+		t.Fatalf("Expected the fork to remember the parent's revision, got %+v", fork)
+	}
+
+	// the parent changing afterwards isn't a difference in the fork
+	parent.Ingredients["corn"] = "1 cup"
+	_, err := client.dbClient.UpdateRecipe(*parent, parent.ID)
+	if err != nil {
+		t.Fatalf("Error updating parent: %s", err.Error())
+	}
+
+	w = serve(client, "GET", "/api/recipe/"+fork.ID+"/diff", "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var diff recipeDiffResponse
+	decode(t, w, &diff)
+	if diff.FromRevision != parent.Revision || len(diff.Ingredients.Removed) != 0 {
+		t.Errorf("Expected the diff against revision %d with nothing removed, got %+v", parent.Revision, diff)
+	}
+
+	w = serve(client, "GET", "/api/recipe/"+fork.ID+"/diff?against="+parent.ID, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var current recipeDiffResponse
+	decode(t, w, &current)
+	if current.FromRevision != parent.Revision+1 || current.Ingredients.Removed["corn"] != "1 cup" {
+		t.Errorf("Expected ?against= to compare to the parent as it is now, got %+v", current)
+	}
+}
@@ -0,0 +1,366 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type householdRequest struct {
+	Name string `json:"name"`
+}
+
+type addMemberRequest struct {
+	// Email of someone to invite, they don't need to have logged in yet
+	Email string `json:"email"`
+}
+
+// householdInvite is a household that has invited the caller
+type householdInvite struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
+type householdResponse struct {
+	*database.Household
+	Members []householdMember `json:"members"`
+}
+
+type householdMember struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// handles the /household route
+func (client *Client) handleHousehold(w http.ResponseWriter, r *http.Request) {
+	if principalFrom(r).UserID == "" {
+		writeError(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		client.getHousehold(w, r)
+		return
+	case "POST":
+		client.createHousehold(w, r)
+		return
+	case "PUT":
+		client.renameHousehold(w, r)
+		return
+	case "DELETE":
+		client.deleteHousehold(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /household/members route
+func (client *Client) handleHouseholdMembers(w http.ResponseWriter, r *http.Request) {
+	if principalFrom(r).UserID == "" {
+		writeError(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		client.addHouseholdMember(w, r)
+		return
+	case "DELETE":
+		client.removeHouseholdMember(w, r, mux.Vars(r)["userId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /household/invites route
+func (client *Client) handleHouseholdInvites(w http.ResponseWriter, r *http.Request) {
+	if principalFrom(r).UserID == "" {
+		writeError(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	householdID := mux.Vars(r)["householdId"]
+	switch {
+	case r.Method == "GET" && householdID == "":
+		client.listHouseholdInvites(w, r)
+		return
+	case r.Method == "POST" && householdID != "":
+		client.acceptHouseholdInvite(w, r, householdID)
+		return
+	case r.Method == "DELETE" && householdID != "":
+		client.declineHouseholdInvite(w, r, householdID)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Household methods
+
+// start a household with the caller in it
+func (client *Client) createHousehold(w http.ResponseWriter, r *http.Request) {
+	var request householdRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		writeError(w, "name must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	household, err := client.dbClient.CreateHousehold(name, principalFrom(r).UserID)
+	if err == database.ErrInHousehold {
+		writeError(w, "you are already in a household", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, "could not create household", http.StatusInternalServerError)
+		return
+	}
+
+	client.writeHousehold(w, household, http.StatusCreated)
+}
+
+// return the caller's household and who's in it
+func (client *Client) getHousehold(w http.ResponseWriter, r *http.Request) {
+	household, ok := client.callerHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	client.writeHousehold(w, household, http.StatusOK)
+}
+
+func (client *Client) renameHousehold(w http.ResponseWriter, r *http.Request) {
+	household, ok := client.callerHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	var request householdRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		writeError(w, "name must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	err = client.dbClient.RenameHousehold(*household, name)
+	if err != nil {
+		writeError(w, "could not update household", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// only the owner can delete the household, everyone else can leave it
+func (client *Client) deleteHousehold(w http.ResponseWriter, r *http.Request) {
+	household, ok := client.callerHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	if household.OwnerID != principalFrom(r).UserID {
+		writeError(w, "only the owner can delete the household", http.StatusForbidden)
+		return
+	}
+
+	err := client.dbClient.DeleteHousehold(*household)
+	if err != nil {
+		writeError(w, "could not delete household", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// the owner invites someone by email. The response is the same whether or
+// not anyone has logged in with it, so it can't be used to find out who has.
+func (client *Client) addHouseholdMember(w http.ResponseWriter, r *http.Request) {
+	household, ok := client.callerHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	if household.OwnerID != principalFrom(r).UserID {
+		writeError(w, "only the owner can add members", http.StatusForbidden)
+		return
+	}
+
+	var request addMemberRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(request.Email)
+	if !strings.Contains(email, "@") {
+		writeError(w, "email must be an email address", http.StatusBadRequest)
+		return
+	}
+
+	_, err = client.dbClient.InviteHouseholdMember(*household, email)
+	if err != nil {
+		writeError(w, "could not invite household member", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusAccepted)
+}
+
+// the owner removes a member, or a member leaves
+func (client *Client) removeHouseholdMember(w http.ResponseWriter, r *http.Request, userID string) {
+	household, ok := client.callerHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	callerID := principalFrom(r).UserID
+	if !household.HasMember(userID) {
+		writeError(w, "could not find household member with that id", http.StatusNotFound)
+		return
+	}
+	if callerID != household.OwnerID && callerID != userID {
+		writeError(w, "only the owner can remove other members", http.StatusForbidden)
+		return
+	}
+	if userID == household.OwnerID {
+		writeError(w, "the owner can't leave, delete the household instead", http.StatusBadRequest)
+		return
+	}
+
+	_, err := client.dbClient.RemoveHouseholdMember(*household, userID)
+	if err != nil {
+		writeError(w, "could not remove household member", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Invite methods
+
+// return the households that have invited the caller
+func (client *Client) listHouseholdInvites(w http.ResponseWriter, r *http.Request) {
+	user, err := client.dbClient.GetUser(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "could not find you", http.StatusInternalServerError)
+		return
+	}
+
+	households, err := client.dbClient.ListHouseholdInvites(user.Email)
+	if err != nil {
+		writeError(w, "error listing household invites", http.StatusInternalServerError)
+		return
+	}
+
+	invites := []householdInvite{}
+	for _, household := range households {
+		invite := householdInvite{ID: household.ID, Name: household.Name}
+		if owner, err := client.dbClient.GetUser(household.OwnerID); err == nil {
+			invite.Owner = owner.Name
+		}
+		invites = append(invites, invite)
+	}
+
+	bytes, err := json.Marshal(invites)
+	if err != nil {
+		writeError(w, "could not marshal household invites", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// join a household that invited the caller
+func (client *Client) acceptHouseholdInvite(w http.ResponseWriter, r *http.Request, householdID string) {
+	user, err := client.dbClient.GetUser(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "could not find you", http.StatusInternalServerError)
+		return
+	}
+
+	household, err := client.dbClient.AcceptHouseholdInvite(householdID, *user)
+	if err == database.ErrInHousehold {
+		writeError(w, "you are already in a household", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, "could not find an invite to that household", http.StatusNotFound)
+		return
+	}
+
+	client.writeHousehold(w, household, http.StatusOK)
+}
+
+// turn down a household's invite
+func (client *Client) declineHouseholdInvite(w http.ResponseWriter, r *http.Request, householdID string) {
+	user, err := client.dbClient.GetUser(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "could not find you", http.StatusInternalServerError)
+		return
+	}
+
+	household, err := client.dbClient.GetHousehold(householdID)
+	if err != nil || !household.Invited(user.Email) {
+		writeError(w, "could not find an invite to that household", http.StatusNotFound)
+		return
+	}
+
+	_, err = client.dbClient.DeclineHouseholdInvite(householdID, user.Email)
+	if err != nil {
+		writeError(w, "could not decline household invite", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+func (client *Client) callerHousehold(w http.ResponseWriter, r *http.Request) (*database.Household, bool) {
+	household, err := client.dbClient.GetUserHousehold(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "you are not in a household", http.StatusNotFound)
+		return nil, false
+	}
+	return household, true
+}
+
+func (client *Client) writeHousehold(w http.ResponseWriter, household *database.Household, status int) {
+	response := householdResponse{Household: household, Members: []householdMember{}}
+	for _, id := range household.MemberIDs {
+		member := householdMember{ID: id}
+		if user, err := client.dbClient.GetUser(id); err == nil {
+			member.Name, member.Email = user.Name, user.Email
+		}
+		response.Members = append(response.Members, member)
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal household", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, status)
+}
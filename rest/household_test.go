@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestHouseholdInvites(t *testing.T) {
+	client := newTestClient(t)
+	_, alice := login(t, client, "alice")
+	bobID, bob := login(t, client, "bob")
+	expectStatus(t, serve(client, "POST", "/api/household", alice, householdRequest{Name: "Home"}), http.StatusCreated)
+
+	// inviting someone who has logged in looks the same as someone who hasn't
+	known := serve(client, "POST", "/api/household/members", alice, addMemberRequest{Email: "bob@example.com"})
+	unknown := serve(client, "POST", "/api/household/members", alice, addMemberRequest{Email: "nobody@example.com"})
+	expectStatus(t, known, http.StatusAccepted)
+	expectStatus(t, unknown, http.StatusAccepted)
+	if known.Body.String() != unknown.Body.String() {
+		t.Errorf("Expected the same response either way, got %q and %q", known.Body.String(), unknown.Body.String())
+	}
+
+	// bob isn't in the household until he accepts
+	expectStatus(t, serve(client, "GET", "/api/household", bob, nil), http.StatusNotFound)
+	w := serve(client, "GET", "/api/household/invites", bob, nil)
+	expectStatus(t, w, http.StatusOK)
+	var invites []householdInvite
+	decode(t, w, &invites)
+	if len(invites) != 1 || invites[0].Name != "Home" || invites[0].Owner != "alice" {
+		t.Fatalf("Expected an invite from alice, got %+v", invites)
+	}
+
+	_, carol := login(t, client, "carol")
+	expectStatus(t, serve(client, "POST", "/api/household/invites/"+invites[0].ID, carol, nil), http.StatusNotFound)
+
+	w = serve(client, "POST", "/api/household/invites/"+invites[0].ID, bob, nil)
+	expectStatus(t, w, http.StatusOK)
+	var household database.Household
+	decode(t, w, &household)
+	if !household.HasMember(bobID) || household.Invited("bob@example.com") {
+		t.Errorf("Expected bob to join, got %+v", household)
+	}
+	if !household.Invited("nobody@example.com") {
+		t.Errorf("Expected the other invite to still be pending, got %+v", household)
+	}
+}
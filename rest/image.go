@@ -0,0 +1,357 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/blob"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/imaging"
+)
+
+// MaxImageBytes is the largest image that can be uploaded
+const MaxImageBytes = 10 << 20
+
+// the image types we accept, keyed by their sniffed content type
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// handles the /recipe/{id}/image route
+func (client *Client) handleRecipeImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		client.getRecipeImage(w, r, recipe, vars["size"])
+		return
+	case "POST", "PUT":
+		client.uploadRecipeImage(w, r, recipe)
+		return
+	case "DELETE":
+		client.deleteRecipeImage(w, r, recipe)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Image methods
+
+// store an image uploaded as the "image" field of a multipart form. The image
+// is cleaned up and thumbnailed in the background, so it starts out
+// processing.
+func (client *Client) uploadRecipeImage(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	upload, ok := readImageUpload(w, r, "image")
+	if !ok {
+		return
+	}
+
+	version, err := uuid.NewRandom()
+	if err != nil {
+		writeError(w, "could not save image", http.StatusInternalServerError)
+		return
+	}
+
+	recipeImage := &database.RecipeImage{
+		Version:     version.String(),
+		Status:      database.ImageProcessing,
+		ContentType: upload.contentType,
+		Size:        int64(len(upload.data)),
+		Width:       upload.img.Bounds().Dx(),
+		Height:      upload.img.Bounds().Dy(),
+		UploadedAt:  time.Now().UTC(),
+		IncomingKey: recipeImagePrefix(recipe.ID, version.String()) + "incoming" + upload.extension,
+		Thumbnails:  map[string]string{},
+	}
+
+	err = client.blobStore.Put(recipeImage.IncomingKey, upload.contentType, bytes.NewReader(upload.data))
+	if err != nil {
+		writeError(w, "could not save image", http.StatusInternalServerError)
+		return
+	}
+
+	err = client.dbClient.SetRecipeImage(recipe.ID, recipeImage)
+	if err != nil {
+		client.deleteBlobs(recipeImage.Keys())
+		writeError(w, "could not save image", http.StatusInternalServerError)
+		return
+	}
+
+	// the previous image is no longer referenced
+	if recipe.Image != nil {
+		client.deleteBlobs(recipe.Image.Keys())
+	}
+
+	process := func() error {
+		return client.processRecipeImage(recipe.ID, *recipeImage)
+	}
+	failed := func(err error) {
+		client.failRecipeImage(recipe.ID, *recipeImage)
+	}
+	err = client.jobs.EnqueueWithFailure("process image "+recipeImage.Version, process, failed)
+	if err != nil {
+		// too busy to do it later, so do it now
+		err = process()
+		if err != nil {
+			failed(err)
+			writeError(w, "could not process image", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(recipeImage)
+	if err != nil {
+		writeError(w, "could not encode image", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusAccepted)
+}
+
+// processRecipeImage runs in the background after an upload. It strips
+// metadata (like the GPS location of phone photos), rotates the image
+// upright, makes thumbnails and computes a placeholder.
+func (client *Client) processRecipeImage(recipeID string, recipeImage database.RecipeImage) error {
+	incoming, _, err := client.blobStore.Get(recipeImage.IncomingKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		// a newer upload already cleaned up after this one
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(incoming)
+	incoming.Close()
+	if err != nil {
+		return err
+	}
+
+	img, format, err := imaging.Decode(data)
+	if err != nil {
+		// retrying won't help
+		recipeImage.Status = database.ImageFailed
+		return client.finishRecipeImage(recipeID, recipeImage)
+	}
+
+	cleaned, upright, err := imaging.Sanitize(data, format, img)
+	if err != nil {
+		return err
+	}
+
+	prefix := recipeImagePrefix(recipeID, recipeImage.Version)
+	recipeImage.OriginalKey = prefix + "original" + imageExtensions[recipeImage.ContentType]
+	err = client.blobStore.Put(recipeImage.OriginalKey, recipeImage.ContentType, bytes.NewReader(cleaned))
+	if err != nil {
+		return err
+	}
+
+	recipeImage.Thumbnails = map[string]string{}
+	recipeImage.Sizes = nil
+	for _, size := range imaging.ThumbnailSizes {
+		thumbnail, err := imaging.EncodeJPEG(imaging.Fit(upright, size.MaxEdge))
+		if err != nil {
+			return err
+		}
+
+		key := prefix + size.Name + ".jpg"
+		err = client.blobStore.Put(key, "image/jpeg", bytes.NewReader(thumbnail))
+		if err != nil {
+			return err
+		}
+		recipeImage.Thumbnails[size.Name] = key
+		recipeImage.Sizes = append(recipeImage.Sizes, size.Name)
+	}
+
+	recipeImage.BlurHash, err = imaging.BlurHash(upright, 4, 3)
+	if err != nil {
+		return err
+	}
+	recipeImage.DominantColor = imaging.DominantColor(upright)
+	recipeImage.Width = upright.Bounds().Dx()
+	recipeImage.Height = upright.Bounds().Dy()
+	recipeImage.Size = int64(len(cleaned))
+	recipeImage.Status = database.ImageReady
+
+	return client.finishRecipeImage(recipeID, recipeImage)
+}
+
+// records that the image couldn't be processed, so it isn't left processing
+// once there are no attempts left
+func (client *Client) failRecipeImage(recipeID string, recipeImage database.RecipeImage) {
+	recipeImage.Status = database.ImageFailed
+	err := client.finishRecipeImage(recipeID, recipeImage)
+	if err != nil {
+		log.Printf("error marking image %s of recipe %s failed: %v", recipeImage.Version, recipeID, err)
+	}
+}
+
+// records the processed image and removes the upload as received
+func (client *Client) finishRecipeImage(recipeID string, recipeImage database.RecipeImage) error {
+	incomingKey := recipeImage.IncomingKey
+	recipeImage.IncomingKey = ""
+
+	err := client.dbClient.ReplaceRecipeImage(recipeID, &recipeImage)
+	if errors.Is(err, database.ErrImageReplaced) {
+		client.deleteBlobs(append(recipeImage.Keys(), incomingKey))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	client.deleteBlobs([]string{incomingKey})
+	return nil
+}
+
+// serve the original image, or one of it's thumbnails when a size is given
+func (client *Client) getRecipeImage(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, size string) {
+	if recipe.Image == nil {
+		writeError(w, "recipe does not have an image", http.StatusNotFound)
+		return
+	}
+	if recipe.Image.Status != database.ImageReady {
+		writeError(w, "image is "+recipe.Image.Status, http.StatusNotFound)
+		return
+	}
+
+	key := recipe.Image.OriginalKey
+	if size != "" {
+		var ok bool
+		key, ok = recipe.Image.Thumbnails[size]
+		if !ok {
+			writeError(w, "unknown image size", http.StatusNotFound)
+			return
+		}
+	} else {
+		size = "original"
+	}
+
+	// a new upload changes the version and so the ETag
+	client.serveBlob(w, r, key, recipe.Image.Version+"-"+size, recipe.Image.UploadedAt)
+}
+
+// remove the recipe's image and it's blobs
+func (client *Client) deleteRecipeImage(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	if recipe.Image == nil {
+		writeError(w, "recipe does not have an image", http.StatusNotFound)
+		return
+	}
+
+	err := client.dbClient.SetRecipeImage(recipe.ID, nil)
+	if err != nil {
+		writeError(w, "could not delete image", http.StatusInternalServerError)
+		return
+	}
+
+	client.deleteBlobs(recipe.Image.Keys())
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// where a recipe image's blobs are kept
+func recipeImagePrefix(recipeID string, version string) string {
+	return "recipes/" + recipeID + "/image/" + version + "/"
+}
+
+type imageUpload struct {
+	data        []byte
+	contentType string
+	extension   string
+	format      string
+	img         image.Image
+}
+
+// reads and validates an image uploaded as a multipart form field, writing an
+// error response if it isn't acceptable
+func readImageUpload(w http.ResponseWriter, r *http.Request, field string) (*imageUpload, bool) {
+	// leave some room for the rest of the multipart body
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImageBytes+1<<20)
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		writeError(w, "request must be a multipart form with an "+field+" field no larger than 10MB", http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil || len(data) > MaxImageBytes {
+		writeError(w, "image must be no larger than 10MB", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+
+	// trust the bytes rather than the client's content type
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		writeError(w, "image must be a JPEG, PNG or GIF", http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	img, format, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		writeError(w, "image dimensions are too large", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, "could not decode image", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &imageUpload{
+		data:        data,
+		contentType: contentType,
+		extension:   extension,
+		format:      format,
+		img:         img,
+	}, true
+}
+
+// serves a blob with caching headers, etag should change whenever the blob
+// does
+func (client *Client) serveBlob(w http.ResponseWriter, r *http.Request, key string, etag string, modTime time.Time) {
+	body, info, err := client.blobStore.Get(key)
+	if err != nil {
+		writeError(w, "could not find blob", http.StatusNotFound)
+		return
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		writeError(w, "could not read blob", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
+
+// deleteBlobs is best effort, a failure only leaves an orphaned blob behind
+func (client *Client) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		err := client.blobStore.Delete(key)
+		if err != nil {
+			log.Printf("error deleting blob %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// - MARK: Helper Functions
+
+// checks that overrides only name allergens and diets we know about
+func validateLabelOverrides(w http.ResponseWriter, overrides *database.LabelOverrides) bool {
+	if overrides == nil {
+		return true
+	}
+
+	for allergen := range overrides.Allergens {
+		if !ingredient.ValidAllergen(allergen) {
+			writeError(w, "allergens must be some of "+strings.Join(ingredient.Allergens, ", "), http.StatusBadRequest)
+			return false
+		}
+	}
+	for diet := range overrides.Diets {
+		if !ingredient.ValidDiet(diet) {
+			writeError(w, "diets must be some of "+strings.Join(ingredient.Diets, ", "), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// works out a recipe's labels from it's own ingredients and the ingredients
+// of it's components, looking the components up with lookup. Overrides made
+// for other ingredients are dropped.
+func labelRecipe(recipe *database.Recipe, lookup database.RecipeLookup) {
+	recipe.DropStaleLabelOverrides()
+	recipe.ApplyLabels()
+	if len(recipe.Components) == 0 {
+		return
+	}
+
+	flat, err := recipe.Flatten(lookup)
+	if err != nil {
+		log.Printf("error flattening recipe %s: %v", recipe.ID, err)
+		return
+	}
+	flat.ApplyLabels()
+	recipe.Allergens, recipe.Diets, recipe.UnknownIngredients = flat.Allergens, flat.Diets, flat.UnknownIngredients
+}
+
+func (client *Client) labelRecipe(recipe *database.Recipe) {
+	labelRecipe(recipe, client.dbClient.GetRecipe)
+}
+
+// works out the labels of recipes as they're read, so recipes saved before
+// the taxonomy last changed are judged by the current one, and recipes with
+// components by what's in the components now. Components are looked up among
+// the recipes before the database.
+func (client *Client) labelRecipes(recipes []database.Recipe) {
+	byID := map[string]database.Recipe{}
+	for _, recipe := range recipes {
+		byID[recipe.ID] = recipe
+	}
+	lookup := func(id string) (*database.Recipe, error) {
+		if recipe, ok := byID[id]; ok {
+			return &recipe, nil
+		}
+		return client.dbClient.GetRecipe(id)
+	}
+
+	for i := range recipes {
+		labelRecipe(&recipes[i], lookup)
+	}
+}
+
+// keeps the recipes free of every allergen in ?without= and labelled with
+// every diet in ?diet=
+func filterRecipeLabels(w http.ResponseWriter, r *http.Request, recipes []database.Recipe) ([]database.Recipe, bool) {
+	constraints, ok := queryConstraints(w, r)
+	if !ok {
+		return nil, false
+	}
+	if constraints.Empty() {
+		return recipes, true
+	}
+
+	filtered := []database.Recipe{}
+	for _, recipe := range recipes {
+		keep := true
+		for _, allergen := range constraints.Without {
+			keep = keep && recipe.FreeOf(allergen)
+		}
+		for _, diet := range constraints.Diets {
+			keep = keep && recipe.HasDiet(diet)
+		}
+		if keep {
+			filtered = append(filtered, recipe)
+		}
+	}
+	return filtered, true
+}
+
+// the allergens in ?without= and diets in ?diet=
+func queryConstraints(w http.ResponseWriter, r *http.Request) (ingredient.Constraints, bool) {
+	constraints := ingredient.Constraints{Without: queryList(r, "without"), Diets: queryList(r, "diet")}
+	for _, allergen := range constraints.Without {
+		if !ingredient.ValidAllergen(allergen) {
+			writeError(w, "without must be some of "+strings.Join(ingredient.Allergens, ", "), http.StatusBadRequest)
+			return constraints, false
+		}
+	}
+	for _, diet := range constraints.Diets {
+		if !ingredient.ValidDiet(diet) {
+			writeError(w, "diet must be some of "+strings.Join(ingredient.Diets, ", "), http.StatusBadRequest)
+			return constraints, false
+		}
+	}
+	return constraints, true
+}
+
+// a comma separated query parameter
+func queryList(r *http.Request, name string) []string {
+	values := []string{}
+	for _, value := range strings.Split(r.URL.Query().Get(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
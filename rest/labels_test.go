@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestLabelsIncludeComponents(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "baker")
+	crust, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Pie Crust", Ingredients: map[string]string{"flour": "2 cups", "butter": "1 cup"}})
+
+	w := serve(client, "POST", "/api/recipe", token, database.Recipe{
+		Name:        "Apple Pie",
+		Ingredients: map[string]string{"crust": "1", "apples": "6"},
+		Components:  map[string]database.Component{"crust": {RecipeID: crust.ID}},
+	})
+	expectStatus(t, w, http.StatusCreated)
+	var pie database.Recipe
+	decode(t, w, &pie)
+	if pie.FreeOf("dairy") || pie.HasDiet("vegan") || len(pie.UnknownIngredients) != 0 {
+		t.Errorf("Expected the crust's butter to count, got %+v", pie)
+	}
+
+	// the crust changing changes the pie
+	crust.Ingredients = map[string]string{"flour": "2 cups", "olive oil": "1/2 cup"}
+	_, err := client.dbClient.UpdateRecipe(*crust, crust.ID)
+	if err != nil {
+		t.Fatalf("Error updating crust: %s", err.Error())
+	}
+
+	w = serve(client, "GET", "/api/recipe/"+pie.ID, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var read database.Recipe
+	decode(t, w, &read)
+	if !read.FreeOf("dairy") || !read.HasDiet("vegan") {
+		t.Errorf("Expected the pie to be dairy-free now, got %+v", read)
+	}
+
+	w = serve(client, "GET", "/api/recipe?without=dairy", "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var listed []database.Recipe
+	decode(t, w, &listed)
+	found := false
+	for _, recipe := range listed {
+		found = found || recipe.ID == pie.ID
+	}
+	if !found {
+		t.Errorf("Expected the pie to be listed as dairy-free, got %+v", listed)
+	}
+}
+
+func TestLabelOverridesDroppedWhenIngredientsChange(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "baker")
+
+	w := serve(client, "POST", "/api/recipe", token, database.Recipe{
+		Name:           "Granola",
+		Ingredients:    map[string]string{"oats": "3 cups", "mystery crunch": "1 cup"},
+		LabelOverrides: &database.LabelOverrides{Allergens: map[string]bool{"nuts": false}},
+	})
+	expectStatus(t, w, http.StatusCreated)
+	var granola database.Recipe
+	decode(t, w, &granola)
+	if !granola.FreeOf("nuts") {
+		t.Fatalf("Expected the override to vouch for the granola, got %+v", granola)
+	}
+
+	// sending the override back with a new ingredient doesn't vouch for it
+	granola.Ingredients["cluster bits"] = "1/2 cup"
+	w = serve(client, "PUT", "/api/recipe/"+granola.ID, token, granola)
+	expectStatus(t, w, http.StatusNoContent)
+
+	w = serve(client, "GET", "/api/recipe/"+granola.ID, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var read database.Recipe
+	decode(t, w, &read)
+	if read.LabelOverrides != nil || read.FreeOf("nuts") {
+		t.Errorf("Expected the override to be dropped, got %+v", read)
+	}
+}
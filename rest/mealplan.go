@@ -0,0 +1,257 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+// handles the /mealplan route
+func (client *Client) handleMealPlan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getMealPlanEntry(w, r, vars["id"])
+		} else {
+			client.listMealPlan(w, r)
+		}
+		return
+	case "POST":
+		client.saveMealPlanEntry(w, r)
+		return
+	case "PUT":
+		client.updateMealPlanEntry(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deleteMealPlanEntry(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Meal plan methods
+
+// schedule a recipe from the body of the request in JSON format
+func (client *Client) saveMealPlanEntry(w http.ResponseWriter, r *http.Request) {
+	var entry database.MealPlanEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !client.validateMealPlanEntry(w, &entry, nil) {
+		return
+	}
+	entry.UserID = principalFrom(r).UserID
+
+	savedEntry, err := client.dbClient.SaveMealPlanEntry(entry)
+	if err != nil {
+		writeError(w, "could not save meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedEntry)
+	if err != nil {
+		writeError(w, "could not encode meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// move or change an existing meal plan entry
+func (client *Client) updateMealPlanEntry(w http.ResponseWriter, r *http.Request, id string) {
+	oldEntry, ok := client.ownedMealPlanEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	var entry database.MealPlanEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !client.validateMealPlanEntry(w, &entry, oldEntry) {
+		return
+	}
+	entry.UserID = oldEntry.UserID
+	entry.CreatedAt = oldEntry.CreatedAt
+
+	err = client.dbClient.UpdateMealPlanEntry(entry, oldEntry.ID)
+	if err != nil {
+		writeError(w, "could not update meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list the caller's meal plan for a week (?week=<any date in the week>) or a
+// date range (?from=<date>&to=<date>), defaulting to the current week
+func (client *Client) listMealPlan(w http.ResponseWriter, r *http.Request) {
+	from, to, err := planRange(r)
+	if err != nil {
+		writeError(w, "dates must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := client.dbClient.ListMealPlanEntries(principalFrom(r).UserID, from, to)
+	if err != nil {
+		writeError(w, "error listing meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	client.markDeletedRecipes(entries)
+
+	bytes, err := json.Marshal(entries)
+	if err != nil {
+		writeError(w, "could not marshal meal plan", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// return a single meal plan entry
+func (client *Client) getMealPlanEntry(w http.ResponseWriter, r *http.Request, id string) {
+	entry, ok := client.ownedMealPlanEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	entries := []database.MealPlanEntry{*entry}
+	client.markDeletedRecipes(entries)
+
+	bytes, err := json.Marshal(entries[0])
+	if err != nil {
+		writeError(w, "could not marshal meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) deleteMealPlanEntry(w http.ResponseWriter, r *http.Request, id string) {
+	_, ok := client.ownedMealPlanEntry(w, r, id)
+	if !ok {
+		return
+	}
+
+	err := client.dbClient.DeleteMealPlanEntry(id)
+	if err != nil {
+		writeError(w, "could not delete meal plan entry", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// fetches an entry, writing a 404 if it doesn't exist or belongs to someone
+// else
+func (client *Client) ownedMealPlanEntry(w http.ResponseWriter, r *http.Request, id string) (*database.MealPlanEntry, bool) {
+	entry, err := client.dbClient.GetMealPlanEntry(id)
+	if err != nil || entry.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find meal plan entry with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return entry, true
+}
+
+// checks the entry's date, slot and recipe, filling in the recipe's name. The
+// recipe is only looked up when it's new, oldEntry being nil for new entries.
+func (client *Client) validateMealPlanEntry(w http.ResponseWriter, entry *database.MealPlanEntry, oldEntry *database.MealPlanEntry) bool {
+	_, err := time.Parse(database.DateFormat, entry.Date)
+	if err != nil {
+		writeError(w, "date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return false
+	}
+
+	if !database.ValidMealSlot(entry.Slot) {
+		writeError(w, "slot must be breakfast, lunch, dinner or snack", http.StatusBadRequest)
+		return false
+	}
+
+	if entry.Servings < 0 {
+		writeError(w, "servings must not be negative", http.StatusBadRequest)
+		return false
+	}
+
+	// entries can still be edited once their recipe is deleted, as long as
+	// they keep it
+	if oldEntry != nil && entry.RecipeID == oldEntry.RecipeID {
+		entry.RecipeName = oldEntry.RecipeName
+		return true
+	}
+
+	recipe, err := client.dbClient.GetRecipe(entry.RecipeID)
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusBadRequest)
+		return false
+	}
+	entry.RecipeName = recipe.Name
+
+	return true
+}
+
+// flags entries whose recipe has since been deleted, looking each recipe up
+// once
+func (client *Client) markDeletedRecipes(entries []database.MealPlanEntry) {
+	exists := map[string]bool{}
+	for i, entry := range entries {
+		found, ok := exists[entry.RecipeID]
+		if !ok {
+			_, err := client.dbClient.GetRecipe(entry.RecipeID)
+			found = !errors.Is(err, database.ErrRecipeNotFound)
+			exists[entry.RecipeID] = found
+		}
+		entries[i].RecipeDeleted = !found
+	}
+}
+
+// the inclusive date range asked for by the request's query
+func planRange(r *http.Request) (string, string, error) {
+	query := r.URL.Query()
+
+	if query.Get("from") != "" || query.Get("to") != "" {
+		from, err := time.Parse(database.DateFormat, query.Get("from"))
+		if err != nil {
+			return "", "", err
+		}
+		to, err := time.Parse(database.DateFormat, query.Get("to"))
+		if err != nil {
+			return "", "", err
+		}
+		return from.Format(database.DateFormat), to.Format(database.DateFormat), nil
+	}
+
+	day := time.Now()
+	if query.Get("week") != "" {
+		var err error
+		day, err = time.Parse(database.DateFormat, query.Get("week"))
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	monday, sunday := weekOf(day)
+	return monday.Format(database.DateFormat), sunday.Format(database.DateFormat), nil
+}
+
+// the Monday and Sunday of the week containing day
+func weekOf(day time.Time) (time.Time, time.Time) {
+	offset := (int(day.Weekday()) + 6) % 7
+	monday := day.AddDate(0, 0, -offset)
+	return monday, monday.AddDate(0, 0, 6)
+}
@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestUpdateMealPlanEntryAfterRecipeDeleted(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "alice")
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Chili"})
+
+	w := serve(client, "POST", "/api/mealplan", token, database.MealPlanEntry{Date: "2026-10-19", Slot: "dinner", RecipeID: saved.ID})
+	expectStatus(t, w, http.StatusCreated)
+	var entry database.MealPlanEntry
+	decode(t, w, &entry)
+
+	client.dbClient.DeleteRecipe(saved.ID)
+	entry.Notes = "use the leftovers"
+	expectStatus(t, serve(client, "PUT", "/api/mealplan/"+entry.ID, token, entry), http.StatusNoContent)
+
+	updated, _ := client.dbClient.GetMealPlanEntry(entry.ID)
+	if updated.Notes != "use the leftovers" || updated.RecipeName != "Chili" {
+		t.Errorf("Expected the notes to change and the recipe name to stay, got %+v", updated)
+	}
+
+	entry.RecipeID = "missing"
+	expectStatus(t, serve(client, "PUT", "/api/mealplan/"+entry.ID, token, entry), http.StatusBadRequest)
+}
@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type recipeMergeResponse struct {
+	Recipe database.Recipe `json:"recipe"`
+	// Merged is true when the edit was merged with changes made since the
+	// revision it was based on
+	Merged    bool                     `json:"merged"`
+	Conflicts []database.MergeConflict `json:"conflicts,omitempty"`
+}
+
+// - MARK: Merge methods
+
+// save an edit made to the If-Match revision of a recipe. If the recipe has
+// changed since, the edit is merged with those changes, or the conflicts
+// are returned with a 409 if that can't be done automatically.
+func (client *Client) replaceRecipe(w http.ResponseWriter, oldRecipe *database.Recipe, updatedRecipe database.Recipe, revision int) {
+	if revision > oldRecipe.Revision {
+		setRevisionETag(w, oldRecipe.Revision)
+		writeError(w, "revision "+strconv.Itoa(revision)+" doesn't exist yet, the recipe is at revision "+strconv.Itoa(oldRecipe.Revision), http.StatusPreconditionFailed)
+		return
+	}
+
+	merged := revision != oldRecipe.Revision
+	if merged {
+		base, err := client.dbClient.GetRecipeRevision(oldRecipe.ID, revision)
+		if err != nil {
+			setRevisionETag(w, oldRecipe.Revision)
+			writeError(w, "revision is too old to merge, fetch the recipe again", http.StatusPreconditionFailed)
+			return
+		}
+
+		var conflicts []database.MergeConflict
+		updatedRecipe, conflicts = database.MergeRecipes(*base, updatedRecipe, *oldRecipe)
+		if len(conflicts) > 0 {
+			setRevisionETag(w, oldRecipe.Revision)
+			writeRecipeMerge(w, recipeMergeResponse{Recipe: *oldRecipe, Conflicts: conflicts}, http.StatusConflict)
+			return
+		}
+
+		// both edits were checked on their own, but together they can still
+		// leave a component without its ingredient or use a deleted category
+		updatedRecipe.ID = oldRecipe.ID
+		if !client.validateRecipeCategories(w, updatedRecipe.CategoryIDs) {
+			return
+		}
+		if !client.validateComponents(w, updatedRecipe) {
+			return
+		}
+		client.labelRecipe(&updatedRecipe)
+	}
+
+	err := client.dbClient.ReplaceRecipe(updatedRecipe, oldRecipe.ID, oldRecipe.Revision)
+	if err != nil {
+		writeRecipeSaveError(w, err, "could not update recipe")
+		return
+	}
+
+	client.deleteBlobs(updatedRecipe.OrphanedBlobKeys(*oldRecipe))
+
+	setRevisionETag(w, oldRecipe.Revision+1)
+	if !merged {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	updatedRecipe.ID, updatedRecipe.Revision = oldRecipe.ID, oldRecipe.Revision+1
+	writeRecipeMerge(w, recipeMergeResponse{Recipe: updatedRecipe, Merged: true}, http.StatusOK)
+}
+
+// - MARK: Helper Functions
+
+// the revision in the If-Match header, and whether there was one. Recipe
+// ETags are their revision.
+func ifMatchRevision(w http.ResponseWriter, r *http.Request) (int, bool, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, false, true
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if err != nil || revision < 0 {
+		writeError(w, "If-Match must be a recipe's ETag", http.StatusBadRequest)
+		return 0, false, false
+	}
+	return revision, true, true
+}
+
+func setRevisionETag(w http.ResponseWriter, revision int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(revision)+`"`)
+}
+
+// writes a 409 when the recipe was changed by someone else while it was
+// being saved, and message otherwise
+func writeRecipeSaveError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, database.ErrRecipeChanged) {
+		writeError(w, "recipe was changed while saving, please try again", http.StatusConflict)
+		return
+	}
+	writeError(w, message, http.StatusInternalServerError)
+}
+
+func writeRecipeMerge(w http.ResponseWriter, response recipeMergeResponse, statusCode int) {
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not encode recipe", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, statusCode)
+}
@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+// putRecipe sends an edit to a recipe based on a revision
+func putRecipe(client *Client, recipe database.Recipe, ifMatch string) *httptest.ResponseRecorder {
+	encoded, _ := json.Marshal(recipe)
+	r := httptest.NewRequest("PUT", "/api/recipe/"+recipe.ID, bytes.NewReader(encoded))
+	r.Header.Set("If-Match", ifMatch)
+
+	w := httptest.NewRecorder()
+	client.Router.ServeHTTP(w, r)
+	return w
+}
+
+func TestReplaceRecipeFromFutureRevision(t *testing.T) {
+	client := newTestClient(t)
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Bread", Ingredients: map[string]string{"flour": "3 cups"}})
+
+	w := putRecipe(client, *saved, `"5"`)
+	expectStatus(t, w, http.StatusPreconditionFailed)
+	if strings.Contains(w.Body.String(), "too old") || w.Header().Get("ETag") != `"1"` {
+		t.Errorf("Expected the current revision without calling it too old, got %s and %q", w.Body.String(), w.Header().Get("ETag"))
+	}
+}
+
+func TestReplaceRecipeValidatesMergedEdits(t *testing.T) {
+	client := newTestClient(t)
+	crust, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Pie Crust", Ingredients: map[string]string{"flour": "2 cups"}})
+	pie, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Pie", Ingredients: map[string]string{"crust": "1", "apples": "6"}})
+
+	// someone takes the crust out of the pie
+	theirs := *pie
+	theirs.Ingredients = map[string]string{"apples": "6"}
+	expectStatus(t, putRecipe(client, theirs, `"1"`), http.StatusNoContent)
+
+	// while someone else makes it a component
+	ours := *pie
+	ours.Ingredients = map[string]string{"crust": "1", "apples": "6"}
+	ours.Components = map[string]database.Component{"crust": {RecipeID: crust.ID}}
+	expectStatus(t, putRecipe(client, ours, `"1"`), http.StatusBadRequest)
+
+	recipe, _ := client.dbClient.GetRecipe(pie.ID)
+	if recipe.Revision != 2 || len(recipe.Components) != 0 {
+		t.Errorf("Expected the merge not to be saved, got %+v", recipe)
+	}
+}
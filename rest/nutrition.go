@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/nutrition"
+)
+
+type nutritionResponse struct {
+	RecipeID string `json:"recipeId"`
+	nutrition.Report
+}
+
+// handles the /recipe/{id}/nutrition route
+func (client *Client) handleRecipeNutrition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if client.nutrition == nil {
+		writeError(w, "nutrition data is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	// components count towards the recipe
+	flat := client.flattenRecipe(*recipe)
+	bytes, err := json.Marshal(nutritionResponse{
+		RecipeID: recipe.ID,
+		Report:   client.nutrition.Analyze(flat.Ingredients, recipe.Servings),
+	})
+	if err != nil {
+		writeError(w, "could not marshal nutrition", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
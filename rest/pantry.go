@@ -0,0 +1,182 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+// handles the /pantry route
+func (client *Client) handlePantry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getPantryItem(w, r, vars["id"])
+		} else {
+			client.listPantry(w, r)
+		}
+		return
+	case "POST":
+		client.savePantryItem(w, r)
+		return
+	case "PUT":
+		client.updatePantryItem(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deletePantryItem(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Pantry methods
+
+// record something the caller has at home
+func (client *Client) savePantryItem(w http.ResponseWriter, r *http.Request) {
+	var item database.PantryItem
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePantryItem(w, &item) {
+		return
+	}
+	item.UserID = principalFrom(r).UserID
+
+	savedItem, err := client.dbClient.SavePantryItem(item)
+	if err != nil {
+		writeError(w, "could not save pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedItem)
+	if err != nil {
+		writeError(w, "could not encode pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// change how much of something the caller has, or when it expires
+func (client *Client) updatePantryItem(w http.ResponseWriter, r *http.Request, id string) {
+	oldItem, ok := client.ownedPantryItem(w, r, id)
+	if !ok {
+		return
+	}
+
+	var item database.PantryItem
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePantryItem(w, &item) {
+		return
+	}
+	item.UserID = oldItem.UserID
+	item.CreatedAt = oldItem.CreatedAt
+
+	err = client.dbClient.UpdatePantryItem(item, oldItem.ID)
+	if err != nil {
+		writeError(w, "could not update pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list the caller's pantry, soonest to expire first
+func (client *Client) listPantry(w http.ResponseWriter, r *http.Request) {
+	pantry, err := client.dbClient.ListPantryItems(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "error listing pantry", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(pantry)
+	if err != nil {
+		writeError(w, "could not marshal pantry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// return a single pantry item
+func (client *Client) getPantryItem(w http.ResponseWriter, r *http.Request, id string) {
+	item, ok := client.ownedPantryItem(w, r, id)
+	if !ok {
+		return
+	}
+
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		writeError(w, "could not marshal pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) deletePantryItem(w http.ResponseWriter, r *http.Request, id string) {
+	_, ok := client.ownedPantryItem(w, r, id)
+	if !ok {
+		return
+	}
+
+	err := client.dbClient.DeletePantryItem(id)
+	if err != nil {
+		writeError(w, "could not delete pantry item", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// fetches a pantry item, writing a 404 if it doesn't exist or belongs to
+// someone else
+func (client *Client) ownedPantryItem(w http.ResponseWriter, r *http.Request, id string) (*database.PantryItem, bool) {
+	item, err := client.dbClient.GetPantryItem(id)
+	if err != nil || item.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find pantry item with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return item, true
+}
+
+// checks the item's name, quantity and expiry date
+func validatePantryItem(w http.ResponseWriter, item *database.PantryItem) bool {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		writeError(w, "name must not be empty", http.StatusBadRequest)
+		return false
+	}
+
+	if item.Quantity < 0 {
+		writeError(w, "quantity must not be negative", http.StatusBadRequest)
+		return false
+	}
+
+	if item.ExpiresOn != "" {
+		_, err := time.Parse(database.DateFormat, item.ExpiresOn)
+		if err != nil {
+			writeError(w, "expiresOn must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	return true
+}
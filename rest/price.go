@@ -0,0 +1,182 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+// handles the /prices route
+func (client *Client) handlePrices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getPrice(w, r, vars["id"])
+		} else {
+			client.listPrices(w, r)
+		}
+		return
+	case "POST":
+		client.savePrice(w, r)
+		return
+	case "PUT":
+		client.updatePrice(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deletePrice(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Price methods
+
+// add a price to the caller's household catalog
+func (client *Client) savePrice(w http.ResponseWriter, r *http.Request) {
+	var price database.Price
+	err := json.NewDecoder(r.Body).Decode(&price)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePrice(w, &price) {
+		return
+	}
+	price.HouseholdID = client.dbClient.HouseholdIDFor(principalFrom(r).UserID)
+
+	savedPrice, err := client.dbClient.SavePrice(price)
+	if err != nil {
+		writeError(w, "could not save price", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(savedPrice)
+	if err != nil {
+		writeError(w, "could not encode price", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+func (client *Client) updatePrice(w http.ResponseWriter, r *http.Request, id string) {
+	oldPrice, ok := client.ownedPrice(w, r, id)
+	if !ok {
+		return
+	}
+
+	var price database.Price
+	err := json.NewDecoder(r.Body).Decode(&price)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePrice(w, &price) {
+		return
+	}
+	price.HouseholdID = oldPrice.HouseholdID
+	price.CreatedAt = oldPrice.CreatedAt
+
+	err = client.dbClient.UpdatePrice(price, oldPrice.ID)
+	if err != nil {
+		writeError(w, "could not update price", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list the caller's household catalog
+func (client *Client) listPrices(w http.ResponseWriter, r *http.Request) {
+	prices, err := client.callerPrices(r)
+	if err != nil {
+		writeError(w, "error listing prices", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(prices)
+	if err != nil {
+		writeError(w, "could not marshal prices", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) getPrice(w http.ResponseWriter, r *http.Request, id string) {
+	price, ok := client.ownedPrice(w, r, id)
+	if !ok {
+		return
+	}
+
+	bytes, err := json.Marshal(price)
+	if err != nil {
+		writeError(w, "could not marshal price", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) deletePrice(w http.ResponseWriter, r *http.Request, id string) {
+	_, ok := client.ownedPrice(w, r, id)
+	if !ok {
+		return
+	}
+
+	err := client.dbClient.DeletePrice(id)
+	if err != nil {
+		writeError(w, "could not delete price", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Helper Functions
+
+// prices in another household's catalog are reported as missing
+func (client *Client) ownedPrice(w http.ResponseWriter, r *http.Request, id string) (*database.Price, bool) {
+	price, err := client.dbClient.GetPrice(id)
+	if err != nil || price.HouseholdID != client.dbClient.HouseholdIDFor(principalFrom(r).UserID) {
+		writeError(w, "could not find price with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return price, true
+}
+
+func (client *Client) callerPrices(r *http.Request) ([]database.Price, error) {
+	return client.dbClient.ListPrices(client.dbClient.HouseholdIDFor(principalFrom(r).UserID))
+}
+
+// checks the price has an ingredient, isn't negative and is for an amount
+func validatePrice(w http.ResponseWriter, price *database.Price) bool {
+	price.Ingredient = strings.TrimSpace(price.Ingredient)
+	if price.Ingredient == "" {
+		writeError(w, "ingredient must not be empty", http.StatusBadRequest)
+		return false
+	}
+
+	if price.Price < 0 {
+		writeError(w, "price must not be negative", http.StatusBadRequest)
+		return false
+	}
+
+	per, ok := ingredient.Parse(price.Per)
+	if !ok || per.Amount <= 0 {
+		writeError(w, "per must be an amount like 1 lb or 12", http.StatusBadRequest)
+		return false
+	}
+
+	price.Store = strings.TrimSpace(price.Store)
+	return true
+}
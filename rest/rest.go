@@ -2,15 +2,29 @@ package rest
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/blob"
 	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/backends/oidc"
+	"github.com/slichlyter12/thyme-apiserver/backends/webhook"
+	"github.com/slichlyter12/thyme-apiserver/jobs"
+	"github.com/slichlyter12/thyme-apiserver/nutrition"
 )
 
 type Client struct {
-	Router   *mux.Router
-	dbClient *database.Client
+	Router       *mux.Router
+	dbClient     *database.Client
+	blobStore    blob.Store
+	jobs         *jobs.Queue
+	webhooks     *webhook.Sender
+	nutrition    *nutrition.Database
+	oidcProvider *oidc.Provider
+	stopDigests  func()
 }
 
 func New() *Client {
@@ -19,20 +33,105 @@ func New() *Client {
 	router.Use(logging)
 
 	client := &Client{
-		Router:   router,
-		dbClient: database.New(),
+		Router:    router,
+		dbClient:  database.New(),
+		blobStore: blob.Must(blob.FromEnv()),
+		jobs:      jobs.NewQueue(2, 100),
+		webhooks:  webhook.NewSender(10 * time.Second),
 	}
+	if config := oidc.ConfigFromEnv(); config != nil {
+		client.oidcProvider = oidc.New(*config)
+	}
+	if path := os.Getenv("NUTRITION_DATA"); path != "" {
+		foods, err := nutrition.Load(path)
+		if err != nil {
+			log.Printf("error loading nutrition data: %v", err)
+		}
+		client.nutrition = foods
+	}
+	router.Use(client.authenticate)
 
 	client.setupRoutes()
 	client.dbClient.EnsureTables()
+	client.stopDigests = client.jobs.Every("pantry digest", DigestCheckInterval, client.sendPantryDigests)
 	return client
 }
 
+// Close stops the background jobs, waiting for any that are running
+func (client *Client) Close() {
+	client.stopDigests()
+	client.jobs.Close()
+}
+
 func (client *Client) setupRoutes() {
+	client.Router.HandleFunc("/s/{token}", client.handleSharedRecipe)
+	client.Router.HandleFunc("/ical/{token}", client.handleCalendar)
+
 	apiRouter := client.Router.PathPrefix("/api").Subrouter()
 	apiRouter.HandleFunc("/status", handleStatus)
 	apiRouter.HandleFunc("/recipe", client.handleRecipe)
 	apiRouter.HandleFunc("/recipe/{id}", client.handleRecipe)
+	apiRouter.HandleFunc("/recipe/{id}/image", client.handleRecipeImage)
+	apiRouter.HandleFunc("/recipe/{id}/image/{size}", client.handleRecipeImage)
+	apiRouter.HandleFunc("/recipe/{id}/step/{index}/media", client.handleStepMedia)
+	apiRouter.HandleFunc("/recipe/{id}/step/{index}/media/{mediaId}", client.handleStepMedia)
+	apiRouter.HandleFunc("/recipe/{id}/step/{index}/ingredients", client.handleStepIngredients)
+	apiRouter.HandleFunc("/recipe/{id}/share", client.handleRecipeShare)
+	apiRouter.HandleFunc("/recipe/{id}/share/{shareId}", client.handleRecipeShare)
+	apiRouter.HandleFunc("/recipe/{id}/cooked", client.handleRecipeCooked)
+	apiRouter.HandleFunc("/recipe/{id}/cooklog", client.handleRecipeCookLog)
+	apiRouter.HandleFunc("/recipe/{id}/nutrition", client.handleRecipeNutrition)
+	apiRouter.HandleFunc("/recipe/{id}/ingredients", client.handleRecipeIngredients)
+	apiRouter.HandleFunc("/recipe/{id}/substitutions", client.handleRecipeSubstitutions)
+	apiRouter.HandleFunc("/recipe/{id}/cost", client.handleRecipeCost)
+	apiRouter.HandleFunc("/recipe/{id}/reviews", client.handleRecipeReviews)
+	apiRouter.HandleFunc("/recipe/{id}/reviews/{reviewId}", client.handleRecipeReviews)
+	apiRouter.HandleFunc("/recipe/{id}/comments", client.handleRecipeComments)
+	apiRouter.HandleFunc("/recipe/{id}/comments/{commentId}", client.handleRecipeComments)
+	apiRouter.HandleFunc("/recipe/{id}/collections", client.handleRecipeCollections)
+	apiRouter.HandleFunc("/recipe/{id}/fork", client.handleRecipeFork)
+	apiRouter.HandleFunc("/recipe/{id}/forks", client.handleRecipeForks)
+	apiRouter.HandleFunc("/recipe/{id}/diff", client.handleRecipeDiff)
+	apiRouter.HandleFunc("/mealplan", client.handleMealPlan)
+	apiRouter.HandleFunc("/mealplan/feed", client.handleCalendarFeed)
+	apiRouter.HandleFunc("/mealplan/cost", client.handleMealPlanCost)
+	apiRouter.HandleFunc("/mealplan/{id}", client.handleMealPlan)
+	apiRouter.HandleFunc("/shoppinglist", client.handleShoppingList)
+	apiRouter.HandleFunc("/shoppinglist/{id}", client.handleShoppingList)
+	apiRouter.HandleFunc("/shoppinglist/{id}/cost", client.handleShoppingListCost)
+	apiRouter.HandleFunc("/shoppinglist/{id}/item", client.handleShoppingItem)
+	apiRouter.HandleFunc("/shoppinglist/{id}/item/{itemId}", client.handleShoppingItem)
+	apiRouter.HandleFunc("/pantry", client.handlePantry)
+	apiRouter.HandleFunc("/pantry/expiring", client.handlePantryExpiring)
+	apiRouter.HandleFunc("/pantry/{id}", client.handlePantry)
+	apiRouter.HandleFunc("/cooklog", client.handleCookLog)
+	apiRouter.HandleFunc("/cooklog/stats", client.handleCookStats)
+	apiRouter.HandleFunc("/cooklog/{id}", client.handleCookLog)
+	apiRouter.HandleFunc("/cooklog/{id}/photo", client.handleCookLogPhoto)
+	apiRouter.HandleFunc("/tags", client.handleTags)
+	apiRouter.HandleFunc("/tags/rename", client.handleTagRename)
+	apiRouter.HandleFunc("/tags/merge", client.handleTagMerge)
+	apiRouter.HandleFunc("/categories", client.handleCategories)
+	apiRouter.HandleFunc("/categories/{id}", client.handleCategories)
+	apiRouter.HandleFunc("/collections", client.handleCollections)
+	apiRouter.HandleFunc("/collections/{id}", client.handleCollections)
+	apiRouter.HandleFunc("/collections/{id}/recipes", client.handleCollectionRecipes)
+	apiRouter.HandleFunc("/collections/{id}/recipes/{recipeId}", client.handleCollectionRecipes)
+	apiRouter.HandleFunc("/prices", client.handlePrices)
+	apiRouter.HandleFunc("/prices/{id}", client.handlePrices)
+	apiRouter.HandleFunc("/household", client.handleHousehold)
+	apiRouter.HandleFunc("/household/members", client.handleHouseholdMembers)
+	apiRouter.HandleFunc("/household/members/{userId}", client.handleHouseholdMembers)
+	apiRouter.HandleFunc("/household/invites", client.handleHouseholdInvites)
+	apiRouter.HandleFunc("/household/invites/{householdId}", client.handleHouseholdInvites)
+	apiRouter.HandleFunc("/auth/login", client.handleLogin)
+	apiRouter.HandleFunc("/auth/callback", client.handleLoginCallback)
+	apiRouter.HandleFunc("/auth/logout", client.handleLogout)
+	apiRouter.HandleFunc("/me", client.handleMe)
+	apiRouter.HandleFunc("/keys", client.handleAPIKeys)
+	apiRouter.HandleFunc("/keys/{id}", client.handleAPIKeys)
+	apiRouter.HandleFunc("/webhooks", client.handleWebhooks)
+	apiRouter.HandleFunc("/webhooks/{id}", client.handleWebhooks)
 }
 
 // handles the /status route
@@ -83,6 +182,19 @@ func (client *Client) saveRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validateLabelOverrides(w, recipe.LabelOverrides) {
+		return
+	}
+	recipe.RecordLabelOverrides(database.Recipe{})
+	if !client.validateRecipeCategories(w, recipe.CategoryIDs) {
+		return
+	}
+	if !client.validateComponents(w, recipe) {
+		return
+	}
+	client.labelRecipe(&recipe)
+	recipe.OwnerID, recipe.ParentID, recipe.ParentRevision = principalFrom(r).UserID, "", 0
+
 	// save recipe
 	savedRecipe, err := client.dbClient.SaveRecipe(recipe)
 	if err != nil {
@@ -122,18 +234,51 @@ func (client *Client) updateRecipe(w http.ResponseWriter, r *http.Request, recip
 		return
 	}
 
+	// images and step media are managed by their own endpoints
+	updatedRecipe.PreserveManagedFields(*oldRecipe)
+
+	if !validateLabelOverrides(w, updatedRecipe.LabelOverrides) {
+		return
+	}
+	updatedRecipe.RecordLabelOverrides(*oldRecipe)
+	if !client.validateRecipeCategories(w, updatedRecipe.CategoryIDs) {
+		return
+	}
+	updatedRecipe.ID = oldRecipe.ID
+	if !client.validateComponents(w, updatedRecipe) {
+		return
+	}
+	client.labelRecipe(&updatedRecipe)
+
+	// edits based on an older revision are merged
+	revision, conditional, ok := ifMatchRevision(w, r)
+	if !ok {
+		return
+	}
+	if conditional {
+		client.replaceRecipe(w, oldRecipe, updatedRecipe, revision)
+		return
+	}
+
 	// update recipe
-	err = client.dbClient.UpdateRecipe(updatedRecipe, oldRecipe.ID)
+	revision, err = client.dbClient.UpdateRecipe(updatedRecipe, oldRecipe.ID)
 	if err != nil {
-		writeError(w, "could not update recipe", http.StatusInternalServerError)
+		writeRecipeSaveError(w, err, "could not update recipe")
 		return
 	}
 
+	// clean up media that was removed from the recipe
+	client.deleteBlobs(updatedRecipe.OrphanedBlobKeys(*oldRecipe))
+
 	// send response
+	setRevisionETag(w, revision)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// return a list of all recipes
+// return a list of all recipes, optionally only those free of allergens
+// (?without=nuts,dairy), with dietary labels (?diet=vegan), with tags
+// (?tag=weeknight) or in a category (?category=id), only the caller's with
+// ?mine=true, and best rated first with ?sort=rating
 func (client *Client) listRecipes(w http.ResponseWriter, r *http.Request) {
 	recipes, err := client.dbClient.ListAllRecipes()
 	if err != nil {
@@ -141,6 +286,39 @@ func (client *Client) listRecipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client.labelRecipes(recipes)
+	recipes, ok := filterRecipeLabels(w, r, recipes)
+	if !ok {
+		return
+	}
+	recipes, ok = client.filterRecipeTaxonomy(w, r, recipes)
+	if !ok {
+		return
+	}
+	recipes, ok = filterRecipeTime(w, r, recipes)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("mine") == "true" {
+		mine := []database.Recipe{}
+		for _, recipe := range recipes {
+			if recipe.OwnerID == principalFrom(r).UserID {
+				mine = append(mine, recipe)
+			}
+		}
+		recipes = mine
+	}
+
+	switch r.URL.Query().Get("sort") {
+	case "":
+	case "rating":
+		database.SortRecipesByRating(recipes)
+	default:
+		writeError(w, "sort must be rating", http.StatusBadRequest)
+		return
+	}
+
 	bytes, err := json.Marshal(recipes)
 	if err != nil {
 		writeError(w, "could not marshal recipes", http.StatusInternalServerError)
@@ -157,6 +335,9 @@ func (client *Client) getRecipe(w http.ResponseWriter, r *http.Request, id strin
 		writeError(w, "could not get recipe with that id", http.StatusNotFound)
 		return
 	}
+	setRevisionETag(w, recipe.Revision)
+	// components can have changed since the recipe was saved
+	client.labelRecipe(recipe)
 
 	bytes, err := json.Marshal(recipe)
 	if err != nil {
@@ -168,7 +349,7 @@ func (client *Client) getRecipe(w http.ResponseWriter, r *http.Request, id strin
 }
 
 func (client *Client) deleteRecipe(w http.ResponseWriter, r *http.Request, id string) {
-	_, err := client.dbClient.GetRecipe(id)
+	recipe, err := client.dbClient.GetRecipe(id)
 	if err != nil {
 		writeError(w, "could not find recipe with that id", http.StatusNotFound)
 		return
@@ -180,14 +361,45 @@ func (client *Client) deleteRecipe(w http.ResponseWriter, r *http.Request, id st
 		return
 	}
 
+	client.deleteBlobs(recipe.BlobKeys())
+	err = client.dbClient.DeleteRecipeReviews(id)
+	if err != nil {
+		log.Printf("error deleting reviews of recipe %s: %v", id, err)
+	}
+	err = client.dbClient.DeleteRecipeComments(id)
+	if err != nil {
+		log.Printf("error deleting comments on recipe %s: %v", id, err)
+	}
+	err = client.dbClient.RemoveRecipeFromCollections(id)
+	if err != nil {
+		log.Printf("error removing recipe %s from collections: %v", id, err)
+	}
+	err = client.dbClient.DeleteRecipeRevisions(*recipe)
+	if err != nil {
+		log.Printf("error deleting revisions of recipe %s: %v", id, err)
+	}
+	err = client.dbClient.DeleteRecipeShares(id)
+	if err != nil {
+		log.Printf("error deleting share links to recipe %s: %v", id, err)
+	}
+
 	writeBytesStatus(w, nil, http.StatusNoContent)
 }
 
 // - MARK: Helper Functions
 
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writes the message as a JSON error, it may include things the caller sent
 func writeError(w http.ResponseWriter, errorMessage string, statusCode int) {
+	bytes, err := json.Marshal(errorResponse{Error: errorMessage})
+	if err != nil {
+		bytes = []byte(`{"error": "internal error"}`)
+	}
 	w.WriteHeader(statusCode)
-	w.Write([]byte(`{"error": "` + errorMessage + `"}`))
+	w.Write(bytes)
 }
 
 func writeBytesStatus(w http.ResponseWriter, bytes []byte, statusCode int) {
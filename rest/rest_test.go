@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/blob"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/backends/database/dynamotest"
+	"github.com/slichlyter12/thyme-apiserver/backends/webhook"
+	"github.com/slichlyter12/thyme-apiserver/jobs"
+)
+
+// newTestClient is a server backed by an in-memory database and blob store,
+// without login configured
+func newTestClient(t *testing.T) *Client {
+	store, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %s", err.Error())
+	}
+
+	client := &Client{
+		Router:    mux.NewRouter(),
+		dbClient:  database.NewWithService(&dynamotest.Client{}),
+		blobStore: store,
+		jobs:      jobs.NewQueue(1, 10),
+		webhooks:  webhook.NewSender(time.Second),
+	}
+	client.Router.Use(alwaysJSON)
+	client.Router.Use(client.authenticate)
+	client.setupRoutes()
+	return client
+}
+
+// login creates a user and a session for them, returning the user's ID and
+// session token
+func login(t *testing.T, client *Client, name string) (string, string) {
+	user, err := client.dbClient.EnsureUser("https://idp.test", name, name+"@example.com", name)
+	if err != nil {
+		t.Fatalf("Error creating user: %s", err.Error())
+	}
+	_, token, err := client.dbClient.CreateSession(user.ID)
+	if err != nil {
+		t.Fatalf("Error creating session: %s", err.Error())
+	}
+	return user.ID, token
+}
+
+// serve sends a request with an optional bearer token and JSON body
+func serve(client *Client, method string, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	var encoded []byte
+	if body != nil {
+		encoded, _ = json.Marshal(body)
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	client.Router.ServeHTTP(w, r)
+	return w
+}
+
+// decode reads a JSON response, failing the test if it can't
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	err := json.Unmarshal(w.Body.Bytes(), v)
+	if err != nil {
+		t.Fatalf("Error decoding %q: %s", w.Body.String(), err.Error())
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("Expected %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+}
+
+func TestErrorsAreJSON(t *testing.T) {
+	client := newTestClient(t)
+	// the message includes the recipe id the caller sent
+	w := serve(client, "POST", "/api/recipe", "", database.Recipe{
+		Name:        "Pie",
+		Ingredients: map[string]string{"crust": "1"},
+		Components:  map[string]database.Component{"crust": {RecipeID: `x", "admin": "true`}},
+	})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	var body map[string]string
+	decode(t, w, &body)
+	if len(body) != 1 || body["error"] != `could not find recipe with id x", "admin": "true` {
+		t.Errorf("Wrong error: %+v", body)
+	}
+}
@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type reviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// handles the /recipe/{id}/reviews route
+func (client *Client) handleRecipeReviews(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	// reviews are one per user, so anonymous callers can only read them
+	if r.Method != "GET" && principalFrom(r).UserID == "" {
+		writeError(w, "log in to review", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if reviewID, ok := vars["reviewId"]; ok {
+			client.getReview(w, r, recipe, reviewID)
+		} else {
+			client.listReviews(w, r, recipe)
+		}
+		return
+	case "POST":
+		client.saveReview(w, r, recipe, "")
+		return
+	case "PUT":
+		client.saveReview(w, r, recipe, vars["reviewId"])
+		return
+	case "DELETE":
+		client.deleteReview(w, r, recipe, vars["reviewId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Review methods
+
+// rate and review a recipe. Posting again edits the caller's review, as does
+// putting to it's ID.
+func (client *Client) saveReview(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, reviewID string) {
+	userID := principalFrom(r).UserID
+
+	review := database.Review{RecipeID: recipe.ID, UserID: userID}
+	existing, err := client.dbClient.GetReview(database.ReviewID(recipe.ID, userID))
+	if err == nil {
+		review.CreatedAt = existing.CreatedAt
+	}
+	if reviewID != "" && (existing == nil || existing.ID != reviewID) {
+		writeError(w, "could not find review with that id", http.StatusNotFound)
+		return
+	}
+
+	var request reviewRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.Rating < 1 || request.Rating > database.MaxRating {
+		writeError(w, "rating must be between 1 and "+strconv.Itoa(database.MaxRating), http.StatusBadRequest)
+		return
+	}
+	review.Rating = request.Rating
+	review.Text = strings.TrimSpace(request.Text)
+
+	saved, created, err := client.dbClient.SaveReview(review)
+	if err != nil {
+		writeError(w, "could not save review", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(saved)
+	if err != nil {
+		writeError(w, "could not encode review", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeBytesStatus(w, bytes, status)
+}
+
+// list a recipe's reviews, newest first
+func (client *Client) listReviews(w http.ResponseWriter, r *http.Request, recipe *database.Recipe) {
+	reviews, err := client.dbClient.ListReviews(recipe.ID)
+	if err != nil {
+		writeError(w, "error listing reviews", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(reviews)
+	if err != nil {
+		writeError(w, "could not marshal reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) getReview(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) {
+	review, err := client.dbClient.GetReview(id)
+	if err != nil || review.RecipeID != recipe.ID {
+		writeError(w, "could not find review with that id", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(review)
+	if err != nil {
+		writeError(w, "could not marshal review", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// only the reviewer can delete their review
+func (client *Client) deleteReview(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, id string) {
+	review, err := client.dbClient.GetReview(id)
+	if err != nil || review.RecipeID != recipe.ID || review.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find review with that id", http.StatusNotFound)
+		return
+	}
+
+	err = client.dbClient.DeleteReview(id)
+	if err != nil {
+		writeError(w, "could not delete review", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
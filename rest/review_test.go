@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestReviewsNeedAUser(t *testing.T) {
+	client := newTestClient(t)
+	_, token := login(t, client, "alice")
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Stew"})
+	path := "/api/recipe/" + saved.ID + "/reviews"
+
+	expectStatus(t, serve(client, "POST", path, "", reviewRequest{Rating: 5}), http.StatusUnauthorized)
+	expectStatus(t, serve(client, "DELETE", path+"/"+database.ReviewID(saved.ID, ""), "", nil), http.StatusUnauthorized)
+
+	w := serve(client, "POST", path, token, reviewRequest{Rating: 4, Text: "hearty"})
+	expectStatus(t, w, http.StatusCreated)
+	var review database.Review
+	decode(t, w, &review)
+	expectStatus(t, serve(client, "PUT", path+"/"+review.ID, "", reviewRequest{Rating: 1}), http.StatusUnauthorized)
+
+	w = serve(client, "GET", path, "", nil)
+	expectStatus(t, w, http.StatusOK)
+	var reviews []database.Review
+	decode(t, w, &reviews)
+	if len(reviews) != 1 || reviews[0].Rating != 4 {
+		t.Errorf("Expected only alice's review, got %+v", reviews)
+	}
+}
@@ -0,0 +1,212 @@
+package rest
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type createShareRequest struct {
+	// ExpiresAt is optional, links without one last until they are revoked
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type createShareResponse struct {
+	*database.Share
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// handles the /recipe/{id}/share route. Only the recipe's owner and their
+// household can manage it's share links.
+func (client *Client) handleRecipeShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil || !client.managesRecipe(r, *recipe) {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		client.listShares(w, r, vars["id"])
+		return
+	case "POST":
+		client.createShare(w, r, vars["id"])
+		return
+	case "DELETE":
+		client.deleteShare(w, r, vars["id"], vars["shareId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Share methods
+
+// mint a new share link for the recipe
+func (client *Client) createShare(w http.ResponseWriter, r *http.Request, recipeID string) {
+	var request createShareRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		writeError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	share, token, err := client.dbClient.CreateShare(recipeID, principalFrom(r).UserID, request.ExpiresAt)
+	if err != nil {
+		writeError(w, "could not create share link", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(createShareResponse{
+		Share: share,
+		Token: token,
+		URL:   baseURL(r) + "/s/" + token,
+	})
+	if err != nil {
+		writeError(w, "could not encode share link", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// list the share links for the recipe
+func (client *Client) listShares(w http.ResponseWriter, r *http.Request, recipeID string) {
+	shares, err := client.dbClient.ListShares(recipeID)
+	if err != nil {
+		writeError(w, "error listing share links", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shares)
+	if err != nil {
+		writeError(w, "could not marshal share links", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// revoke a share link
+func (client *Client) deleteShare(w http.ResponseWriter, r *http.Request, recipeID string, shareID string) {
+	shares, err := client.dbClient.ListShares(recipeID)
+	if err != nil {
+		writeError(w, "error listing share links", http.StatusInternalServerError)
+		return
+	}
+
+	for _, share := range shares {
+		if share.ID != shareID {
+			continue
+		}
+
+		err = client.dbClient.DeleteShare(shareID)
+		if err != nil {
+			writeError(w, "could not revoke share link", http.StatusInternalServerError)
+			return
+		}
+
+		writeBytesStatus(w, nil, http.StatusNoContent)
+		return
+	}
+
+	writeError(w, "could not find share link with that id", http.StatusNotFound)
+}
+
+// handles the public /s/{token} route, rendering the shared recipe as HTML
+func (client *Client) handleSharedRecipe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	share, err := client.dbClient.GetShareByToken(mux.Vars(r)["token"])
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		sharedRecipeTemplate.Execute(w, nil)
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(share.RecipeID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		sharedRecipeTemplate.Execute(w, nil)
+		return
+	}
+
+	sharedRecipeTemplate.Execute(w, recipe)
+}
+
+// - MARK: Helper Functions
+
+// reports whether the caller owns the recipe or shares a household with
+// it's owner
+func (client *Client) managesRecipe(r *http.Request, recipe database.Recipe) bool {
+	userID := principalFrom(r).UserID
+	if recipe.OwnerID == userID {
+		return true
+	}
+	return recipe.OwnerID != "" && database.ContainsString(client.householdMemberIDs(userID), recipe.OwnerID)
+}
+
+// the scheme and host the request was made to, for building absolute links
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+var sharedRecipeTemplate = template.Must(template.New("recipe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .}}{{.Name}}{{else}}Recipe not found{{end}} · thyme</title>
+<style>
+body { font-family: -apple-system, sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; color: #222; }
+.byline { color: #666; }
+</style>
+</head>
+<body>
+{{if .}}
+<h1>{{.Name}}</h1>
+{{if .Author}}<p class="byline">by {{.Author}}{{if .Cuisine}} · {{.Cuisine}}{{end}}</p>{{end}}
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Ingredients}}
+<h2>Ingredients</h2>
+<ul>
+{{range $name, $amount := .Ingredients}}<li>{{$amount}} {{$name}}</li>
+{{end}}</ul>
+{{end}}
+{{if .Steps}}
+<h2>Steps</h2>
+<ol>
+{{range .Steps}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}
+{{else}}
+<h1>Recipe not found</h1>
+<p>This link has expired or been revoked.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+func TestShareOwnership(t *testing.T) {
+	client := newTestClient(t)
+	samID, sam := login(t, client, "sam")
+	_, alex := login(t, client, "alex")
+	joID, jo := login(t, client, "jo")
+	household, _ := client.dbClient.CreateHousehold("Home", samID)
+	client.dbClient.AddHouseholdMember(*household, joID)
+
+	w := serve(client, "POST", "/api/recipe", sam, database.Recipe{Name: "Chili"})
+	expectStatus(t, w, http.StatusCreated)
+	var recipe database.Recipe
+	decode(t, w, &recipe)
+	path := "/api/recipe/" + recipe.ID + "/share"
+
+	expectStatus(t, serve(client, "POST", path, alex, nil), http.StatusNotFound)
+	expectStatus(t, serve(client, "GET", path, alex, nil), http.StatusNotFound)
+
+	w = serve(client, "POST", path, jo, nil)
+	expectStatus(t, w, http.StatusCreated)
+	var share createShareResponse
+	decode(t, w, &share)
+
+	expectStatus(t, serve(client, "DELETE", path+"/"+share.ID, alex, nil), http.StatusNotFound)
+	expectStatus(t, serve(client, "GET", "/s/"+share.Token, "", nil), http.StatusOK)
+
+	// deleting the recipe revokes it's links
+	expectStatus(t, serve(client, "DELETE", "/api/recipe/"+recipe.ID, sam, nil), http.StatusNoContent)
+	shares, _ := client.dbClient.ListShares(recipe.ID)
+	if len(shares) != 0 {
+		t.Errorf("Expected the share links to be deleted, got %+v", shares)
+	}
+}
@@ -0,0 +1,439 @@
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+type createShoppingListRequest struct {
+	Name string `json:"name"`
+	// RecipeIDs are each made once
+	RecipeIDs []string `json:"recipeIds"`
+	// From and To take every recipe in the meal plan between the dates,
+	// scaled to the servings planned
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Items are added by hand
+	Items []database.ShoppingItem `json:"items"`
+	// IgnorePantry lists everything the recipes need, even what's already
+	// in the pantry
+	IgnorePantry bool `json:"ignorePantry"`
+}
+
+type updateShoppingItemRequest struct {
+	Name    *string `json:"name"`
+	Amount  *string `json:"amount"`
+	Aisle   *string `json:"aisle"`
+	Checked *bool   `json:"checked"`
+}
+
+type shoppingAisle struct {
+	Name  string                  `json:"name"`
+	Items []database.ShoppingItem `json:"items"`
+}
+
+type shoppingListResponse struct {
+	*database.ShoppingList
+	Aisles []shoppingAisle `json:"aisles"`
+}
+
+// handles the /shoppinglist route
+func (client *Client) handleShoppingList(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		if len(vars) > 0 {
+			client.getShoppingList(w, r, vars["id"])
+		} else {
+			client.listShoppingLists(w, r)
+		}
+		return
+	case "POST":
+		client.createShoppingList(w, r)
+		return
+	case "DELETE":
+		client.deleteShoppingList(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// handles the /shoppinglist/{id}/item route
+func (client *Client) handleShoppingItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	list, ok := client.ownedShoppingList(w, r, vars["id"])
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		client.addShoppingItem(w, r, list)
+		return
+	case "PUT", "PATCH":
+		client.updateShoppingItem(w, r, list, vars["itemId"])
+		return
+	case "DELETE":
+		client.deleteShoppingItem(w, r, list, vars["itemId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Shopping list methods
+
+// generate a shopping list from recipes and/or a stretch of the meal plan
+func (client *Client) createShoppingList(w http.ResponseWriter, r *http.Request) {
+	var request createShoppingListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	portions := []database.RecipePortion{}
+	for _, recipeID := range request.RecipeIDs {
+		recipe, err := client.dbClient.GetRecipe(recipeID)
+		if err != nil {
+			writeError(w, "could not find recipe with id "+recipeID, http.StatusBadRequest)
+			return
+		}
+		portions = append(portions, database.RecipePortion{Recipe: client.flattenRecipe(*recipe), Scale: 1})
+	}
+
+	if request.From != "" || request.To != "" {
+		planned, ok := client.plannedPortions(w, r, request.From, request.To)
+		if !ok {
+			return
+		}
+		portions = append(portions, planned...)
+	}
+
+	items, err := database.GenerateShoppingItems(portions)
+	if err != nil {
+		writeError(w, "could not generate shopping list", http.StatusInternalServerError)
+		return
+	}
+
+	if !request.IgnorePantry {
+		pantry, err := client.dbClient.ListPantryItems(principalFrom(r).UserID)
+		if err != nil {
+			writeError(w, "error listing pantry", http.StatusInternalServerError)
+			return
+		}
+		items = database.ExcludePantryItems(items, pantry)
+	}
+
+	for _, item := range request.Items {
+		manual, ok := newManualItem(w, item)
+		if !ok {
+			return
+		}
+		items = append(items, *manual)
+	}
+	database.SortShoppingItems(items)
+
+	list := database.ShoppingList{
+		UserID: principalFrom(r).UserID,
+		Name:   request.Name,
+		From:   request.From,
+		To:     request.To,
+		Items:  items,
+	}
+	for _, portion := range portions {
+		list.RecipeIDs = database.AppendUnique(list.RecipeIDs, portion.Recipe.ID)
+	}
+	if list.Name == "" {
+		list.Name = "Shopping list"
+	}
+
+	savedList, err := client.dbClient.SaveShoppingList(list)
+	if err != nil {
+		writeError(w, "could not save shopping list", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(newShoppingListResponse(savedList))
+	if err != nil {
+		writeError(w, "could not encode shopping list", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// list the caller's shopping lists
+func (client *Client) listShoppingLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := client.dbClient.ListShoppingLists(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "error listing shopping lists", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(lists)
+	if err != nil {
+		writeError(w, "could not marshal shopping lists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// return a single shopping list grouped by aisle
+func (client *Client) getShoppingList(w http.ResponseWriter, r *http.Request, id string) {
+	list, ok := client.ownedShoppingList(w, r, id)
+	if !ok {
+		return
+	}
+
+	bytes, err := json.Marshal(newShoppingListResponse(list))
+	if err != nil {
+		writeError(w, "could not marshal shopping list", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+func (client *Client) deleteShoppingList(w http.ResponseWriter, r *http.Request, id string) {
+	_, ok := client.ownedShoppingList(w, r, id)
+	if !ok {
+		return
+	}
+
+	err := client.dbClient.DeleteShoppingList(id)
+	if err != nil {
+		writeError(w, "could not delete shopping list", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
+
+// - MARK: Shopping item methods
+
+// add an item to the list by hand
+func (client *Client) addShoppingItem(w http.ResponseWriter, r *http.Request, list *database.ShoppingList) {
+	var item database.ShoppingItem
+	err := json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	manual, ok := newManualItem(w, item)
+	if !ok {
+		return
+	}
+
+	list.Items = append(list.Items, *manual)
+	database.SortShoppingItems(list.Items)
+
+	err = client.dbClient.UpdateShoppingList(*list, list.ID)
+	if err != nil {
+		writeError(w, "could not save shopping list item", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(manual)
+	if err != nil {
+		writeError(w, "could not encode shopping list item", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// check off or change an item, only the fields given are changed. Checking
+// an item off puts it in the pantry, unchecking it takes it back out.
+func (client *Client) updateShoppingItem(w http.ResponseWriter, r *http.Request, list *database.ShoppingList, itemID string) {
+	var request updateShoppingItemRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.ID != itemID {
+			continue
+		}
+		wasChecked := item.Checked
+
+		if request.Name != nil {
+			if strings.TrimSpace(*request.Name) == "" {
+				writeError(w, "name must not be empty", http.StatusBadRequest)
+				return
+			}
+			item.Name = strings.TrimSpace(*request.Name)
+		}
+		if request.Amount != nil {
+			item.Amount = strings.TrimSpace(*request.Amount)
+		}
+		if request.Aisle != nil {
+			item.Aisle = *request.Aisle
+		}
+		if request.Checked != nil {
+			item.Checked = *request.Checked
+		}
+		updated := *item
+		database.SortShoppingItems(list.Items)
+
+		err = client.dbClient.UpdateShoppingList(*list, list.ID)
+		if err != nil {
+			writeError(w, "could not update shopping list item", http.StatusInternalServerError)
+			return
+		}
+
+		if updated.Checked != wasChecked {
+			client.syncPantry(principalFrom(r).UserID, updated)
+		}
+
+		bytes, err := json.Marshal(updated)
+		if err != nil {
+			writeError(w, "could not encode shopping list item", http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(bytes)
+		return
+	}
+
+	writeError(w, "could not find shopping list item with that id", http.StatusNotFound)
+}
+
+// remove an item from the list
+func (client *Client) deleteShoppingItem(w http.ResponseWriter, r *http.Request, list *database.ShoppingList, itemID string) {
+	for i, item := range list.Items {
+		if item.ID != itemID {
+			continue
+		}
+
+		list.Items = append(list.Items[:i:i], list.Items[i+1:]...)
+		err := client.dbClient.UpdateShoppingList(*list, list.ID)
+		if err != nil {
+			writeError(w, "could not delete shopping list item", http.StatusInternalServerError)
+			return
+		}
+
+		writeBytesStatus(w, nil, http.StatusNoContent)
+		return
+	}
+
+	writeError(w, "could not find shopping list item with that id", http.StatusNotFound)
+}
+
+// - MARK: Helper Functions
+
+// fetches a list, writing a 404 if it doesn't exist or belongs to someone
+// else
+func (client *Client) ownedShoppingList(w http.ResponseWriter, r *http.Request, id string) (*database.ShoppingList, bool) {
+	list, err := client.dbClient.GetShoppingList(id)
+	if err != nil || list.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find shopping list with that id", http.StatusNotFound)
+		return nil, false
+	}
+	return list, true
+}
+
+// the recipes in the caller's meal plan between from and to, scaled to the
+// servings planned. Recipes that have been deleted are skipped.
+func (client *Client) plannedPortions(w http.ResponseWriter, r *http.Request, from string, to string) ([]database.RecipePortion, bool) {
+	_, fromErr := time.Parse(database.DateFormat, from)
+	_, toErr := time.Parse(database.DateFormat, to)
+	if fromErr != nil || toErr != nil {
+		writeError(w, "from and to must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return nil, false
+	}
+
+	entries, err := client.dbClient.ListMealPlanEntries(principalFrom(r).UserID, from, to)
+	if err != nil {
+		writeError(w, "error listing meal plan", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	portions := []database.RecipePortion{}
+	for _, entry := range entries {
+		recipe, err := client.dbClient.GetRecipe(entry.RecipeID)
+		if err != nil {
+			continue
+		}
+
+		scale := 1.0
+		if entry.Servings > 0 && recipe.Servings > 0 {
+			scale = float64(entry.Servings) / float64(recipe.Servings)
+		}
+		portions = append(portions, database.RecipePortion{Recipe: client.flattenRecipe(*recipe), Scale: scale})
+	}
+
+	return portions, true
+}
+
+// stocks the pantry with a checked off item, or takes an unchecked item back
+// out. It's best effort, the pantry can always be fixed by hand.
+func (client *Client) syncPantry(userID string, item database.ShoppingItem) {
+	changes := []database.PantryChange{}
+	for _, part := range strings.Split(item.Amount, " + ") {
+		quantity, ok := ingredient.Parse(part)
+		if !ok {
+			continue
+		}
+		changes = append(changes, database.PantryChange{Name: item.Name, Quantity: quantity, Stock: item.Checked})
+	}
+
+	err := client.dbClient.ChangePantry(userID, changes)
+	if err != nil {
+		log.Printf("error updating pantry for %s: %v", item.Name, err)
+	}
+}
+
+// validates an item added by hand, guessing it's aisle if none was given
+func newManualItem(w http.ResponseWriter, item database.ShoppingItem) (*database.ShoppingItem, bool) {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		writeError(w, "items must have a name", http.StatusBadRequest)
+		return nil, false
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		writeError(w, "could not save shopping list item", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	item.ID = id.String()
+	item.Amount = strings.TrimSpace(item.Amount)
+	item.Manual = true
+	item.RecipeIDs = nil
+	if item.Aisle == "" {
+		item.Aisle = ingredient.Aisle(item.Name)
+	}
+	return &item, true
+}
+
+// groups the list's items by aisle, in the order of the store
+func newShoppingListResponse(list *database.ShoppingList) shoppingListResponse {
+	response := shoppingListResponse{ShoppingList: list, Aisles: []shoppingAisle{}}
+	for _, item := range list.Items {
+		last := len(response.Aisles) - 1
+		if last < 0 || response.Aisles[last].Name != item.Aisle {
+			response.Aisles = append(response.Aisles, shoppingAisle{Name: item.Aisle})
+			last++
+		}
+		response.Aisles[last].Items = append(response.Aisles[last].Items, item)
+	}
+	return response
+}
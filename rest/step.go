@@ -0,0 +1,286 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/imaging"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+type videoReferenceRequest struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+}
+
+type stepIngredientsResponse struct {
+	Ingredients []ingredient.Mention `json:"ingredients"`
+	// Set is true when the ingredients were set by hand rather than linked
+	// from the step's text
+	Set bool `json:"set"`
+}
+
+// handles the /recipe/{id}/step/{index}/media route
+func (client *Client) handleStepMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil || index < 0 || index >= len(recipe.Steps) {
+		writeError(w, "could not find step with that index", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		client.getStepMedia(w, r, recipe, index, vars["mediaId"])
+		return
+	case "POST":
+		client.addStepMedia(w, r, recipe, index)
+		return
+	case "DELETE":
+		client.deleteStepMedia(w, r, recipe, index, vars["mediaId"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Step media methods
+
+// attach media to a step, either an image uploaded as the "media" field of a
+// multipart form or a JSON video reference
+func (client *Client) addStepMedia(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, index int) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		writeError(w, "could not save media", http.StatusInternalServerError)
+		return
+	}
+	media := database.StepMedia{ID: id.String()}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		upload, ok := readImageUpload(w, r, "media")
+		if !ok {
+			return
+		}
+
+		// step photos are small enough to clean up before responding
+		cleaned, upright, err := imaging.Sanitize(upload.data, upload.format, upload.img)
+		if err != nil {
+			writeError(w, "could not process image", http.StatusInternalServerError)
+			return
+		}
+
+		media.Type = database.MediaImage
+		media.Caption = r.FormValue("caption")
+		media.ContentType = upload.contentType
+		media.Width = upright.Bounds().Dx()
+		media.Height = upright.Bounds().Dy()
+		media.Key = "recipes/" + recipe.ID + "/steps/" + media.ID + upload.extension
+
+		err = client.blobStore.Put(media.Key, upload.contentType, bytes.NewReader(cleaned))
+		if err != nil {
+			writeError(w, "could not save media", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		var request videoReferenceRequest
+		err = json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			writeError(w, "error parsing JSON request", http.StatusBadRequest)
+			return
+		}
+
+		videoURL, err := url.Parse(request.URL)
+		if err != nil || (videoURL.Scheme != "https" && videoURL.Scheme != "http") || videoURL.Host == "" {
+			writeError(w, "video url must be an http or https url", http.StatusBadRequest)
+			return
+		}
+
+		media.Type = database.MediaVideo
+		media.URL = videoURL.String()
+		media.Caption = request.Caption
+	}
+
+	for len(recipe.StepDetails) <= index {
+		recipe.StepDetails = append(recipe.StepDetails, database.StepDetail{})
+	}
+	recipe.StepDetails[index].Media = append(recipe.StepDetails[index].Media, media)
+
+	// the recipe may have been edited since it was read
+	err = client.dbClient.ReplaceRecipe(*recipe, recipe.ID, recipe.Revision)
+	if err != nil {
+		if media.Key != "" {
+			client.deleteBlobs([]string{media.Key})
+		}
+		writeRecipeSaveError(w, err, "could not save media")
+		return
+	}
+
+	bytes, err := json.Marshal(media)
+	if err != nil {
+		writeError(w, "could not encode media", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// serve an uploaded step image, or list the step's media
+func (client *Client) getStepMedia(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, index int, mediaID string) {
+	media := []database.StepMedia{}
+	if index < len(recipe.StepDetails) {
+		media = recipe.StepDetails[index].Media
+	}
+
+	if mediaID == "" {
+		bytes, err := json.Marshal(media)
+		if err != nil {
+			writeError(w, "could not marshal media", http.StatusInternalServerError)
+			return
+		}
+		w.Write(bytes)
+		return
+	}
+
+	for _, m := range media {
+		if m.ID != mediaID {
+			continue
+		}
+		if m.Type != database.MediaImage {
+			http.Redirect(w, r, m.URL, http.StatusFound)
+			return
+		}
+
+		// media is never modified in place, so it's ID works as an ETag
+		client.serveBlob(w, r, m.Key, m.ID, time.Time{})
+		return
+	}
+
+	writeError(w, "could not find media with that id", http.StatusNotFound)
+}
+
+// remove media from a step, deleting it's blob
+func (client *Client) deleteStepMedia(w http.ResponseWriter, r *http.Request, recipe *database.Recipe, index int, mediaID string) {
+	if index < len(recipe.StepDetails) {
+		media := recipe.StepDetails[index].Media
+		for i, m := range media {
+			if m.ID != mediaID {
+				continue
+			}
+
+			recipe.StepDetails[index].Media = append(media[:i:i], media[i+1:]...)
+			err := client.dbClient.ReplaceRecipe(*recipe, recipe.ID, recipe.Revision)
+			if err != nil {
+				writeRecipeSaveError(w, err, "could not delete media")
+				return
+			}
+
+			if m.Key != "" {
+				client.deleteBlobs([]string{m.Key})
+			}
+			writeBytesStatus(w, nil, http.StatusNoContent)
+			return
+		}
+	}
+
+	writeError(w, "could not find media with that id", http.StatusNotFound)
+}
+
+// handles the /recipe/{id}/step/{index}/ingredients route
+func (client *Client) handleStepIngredients(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	recipe, err := client.dbClient.GetRecipe(vars["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil || index < 0 || index >= len(recipe.Steps) {
+		writeError(w, "could not find step with that index", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		// recipes saved before steps were linked are linked as they're read
+		if recipe.StepIngredients == nil {
+			recipe.ApplyStepIngredients()
+		}
+		writeStepIngredients(w, *recipe, index)
+		return
+	case "PUT":
+		var mentions []ingredient.Mention
+		err := json.NewDecoder(r.Body).Decode(&mentions)
+		if err != nil {
+			writeError(w, "error parsing JSON request", http.StatusBadRequest)
+			return
+		}
+		if name, ok := recipe.UnlistedIngredient(mentions); ok {
+			writeError(w, name+" isn't one of the recipe's ingredients", http.StatusBadRequest)
+			return
+		}
+		client.setStepIngredients(w, recipe, index, mentions, true)
+		return
+	case "DELETE":
+		client.setStepIngredients(w, recipe, index, nil, false)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Step ingredient methods
+
+// set the step's ingredients by hand, or go back to linking them from the
+// step's text when set is false
+func (client *Client) setStepIngredients(w http.ResponseWriter, recipe *database.Recipe, index int, mentions []ingredient.Mention, set bool) {
+	for len(recipe.StepDetails) <= index {
+		recipe.StepDetails = append(recipe.StepDetails, database.StepDetail{})
+	}
+	recipe.StepDetails[index].Ingredients = mentions
+	recipe.StepDetails[index].IngredientsSet = set
+
+	// the recipe may have been edited since it was read
+	err := client.dbClient.ReplaceRecipe(*recipe, recipe.ID, recipe.Revision)
+	if err != nil {
+		writeRecipeSaveError(w, err, "could not save step ingredients")
+		return
+	}
+
+	recipe.ApplyStepIngredients()
+	writeStepIngredients(w, *recipe, index)
+}
+
+func writeStepIngredients(w http.ResponseWriter, recipe database.Recipe, index int) {
+	response := stepIngredientsResponse{Ingredients: []ingredient.Mention{}}
+	if index < len(recipe.StepIngredients) && recipe.StepIngredients[index] != nil {
+		response.Ingredients = recipe.StepIngredients[index]
+	}
+	if index < len(recipe.StepDetails) {
+		response.Set = recipe.StepDetails[index].IngredientsSet
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not encode step ingredients", http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
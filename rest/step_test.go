@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+	"github.com/slichlyter12/thyme-apiserver/ingredient"
+)
+
+func TestStepMedia(t *testing.T) {
+	client := newTestClient(t)
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Bread", Steps: []string{"Knead"}})
+	path := "/api/recipe/" + saved.ID + "/step/0/media"
+
+	w := serve(client, "POST", path, "", videoReferenceRequest{URL: "https://example.com/knead"})
+	expectStatus(t, w, http.StatusCreated)
+	var media database.StepMedia
+	decode(t, w, &media)
+
+	recipe, _ := client.dbClient.GetRecipe(saved.ID)
+	if recipe.Revision != 2 || len(recipe.StepDetails) != 1 || recipe.StepDetails[0].Media[0].ID != media.ID {
+		t.Errorf("Expected the video on the first step, got %+v", recipe)
+	}
+
+	expectStatus(t, serve(client, "DELETE", path+"/"+media.ID, "", nil), http.StatusNoContent)
+	recipe, _ = client.dbClient.GetRecipe(saved.ID)
+	if recipe.Revision != 3 || len(recipe.StepDetails[0].Media) != 0 {
+		t.Errorf("Expected the video to be removed, got %+v", recipe)
+	}
+}
+
+func TestStepIngredientsKeepNewerEdits(t *testing.T) {
+	client := newTestClient(t)
+	saved, _ := client.dbClient.SaveRecipe(database.Recipe{Name: "Bread", Ingredients: map[string]string{"flour": "3 cups"}, Steps: []string{"Knead"}})
+	stale, _ := client.dbClient.GetRecipe(saved.ID)
+
+	// renamed after the step's ingredients were read
+	edited := *saved
+	edited.Name = "Sourdough"
+	_, err := client.dbClient.UpdateRecipe(edited, saved.ID)
+	if err != nil {
+		t.Fatalf("Error updating recipe: %s", err.Error())
+	}
+
+	w := httptest.NewRecorder()
+	client.setStepIngredients(w, stale, 0, []ingredient.Mention{{Name: "flour"}}, true)
+	expectStatus(t, w, http.StatusConflict)
+
+	recipe, _ := client.dbClient.GetRecipe(saved.ID)
+	if recipe.Name != "Sourdough" || len(recipe.StepDetails) != 0 {
+		t.Errorf("Expected the rename to be kept, got %+v", recipe)
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type substitutionsResponse struct {
+	RecipeID    string                           `json:"recipeId"`
+	Ingredients []database.IngredientSubstitutes `json:"ingredients"`
+	// Derived is only worked out when ?without= or ?diet= ask for it
+	Derived *derivedRecipe `json:"derived,omitempty"`
+}
+
+type derivedRecipe struct {
+	Recipe     database.Recipe         `json:"recipe"`
+	Changes    []database.Substitution `json:"changes"`
+	Unresolved []string                `json:"unresolved"`
+}
+
+// handles the /recipe/{id}/substitutions route
+func (client *Client) handleRecipeSubstitutions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	constraints, ok := queryConstraints(w, r)
+	if !ok {
+		return
+	}
+
+	recipe, err := client.dbClient.GetRecipe(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, "could not find recipe with that id", http.StatusNotFound)
+		return
+	}
+
+	response := substitutionsResponse{
+		RecipeID:    recipe.ID,
+		Ingredients: database.SuggestSubstitutes(*recipe),
+	}
+	if !constraints.Empty() {
+		derived, changes, unresolved := recipe.Substitute(constraints)
+		client.labelRecipe(&derived)
+		response.Derived = &derivedRecipe{Recipe: derived, Changes: changes, Unresolved: unresolved}
+	}
+
+	bytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "could not marshal substitutions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
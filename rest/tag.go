@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type tagRenameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type tagMergeRequest struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+}
+
+type tagRenameResponse struct {
+	Tag            string `json:"tag"`
+	RecipesUpdated int    `json:"recipesUpdated"`
+}
+
+// handles the /tags route
+func (client *Client) handleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := client.dbClient.ListTags()
+	if err != nil {
+		writeError(w, "error listing tags", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(tags)
+	if err != nil {
+		writeError(w, "could not marshal tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// handles the /tags/rename route
+func (client *Client) handleTagRename(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request tagRenameRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	client.renameTags(w, []string{request.From}, request.To)
+}
+
+// handles the /tags/merge route
+func (client *Client) handleTagMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request tagMergeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.From) == 0 {
+		writeError(w, "from must list the tags to merge", http.StatusBadRequest)
+		return
+	}
+	client.renameTags(w, request.From, request.To)
+}
+
+// - MARK: Tag methods
+
+// replace the from tags with the to tag on every recipe
+func (client *Client) renameTags(w http.ResponseWriter, from []string, to string) {
+	to = database.NormalizeTag(to)
+	if to == "" {
+		writeError(w, "to must not be empty", http.StatusBadRequest)
+		return
+	}
+	for _, tag := range from {
+		if database.NormalizeTag(tag) == "" {
+			writeError(w, "from must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	updated, err := client.dbClient.RenameTags(from, to)
+	if err != nil {
+		writeError(w, "could not rename tags", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(tagRenameResponse{Tag: to, RecipesUpdated: updated})
+	if err != nil {
+		writeError(w, "could not marshal tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// - MARK: Helper Functions
+
+// keeps the recipes with every tag in ?tag= and in the ?category= or a
+// category under it
+func (client *Client) filterRecipeTaxonomy(w http.ResponseWriter, r *http.Request, recipes []database.Recipe) ([]database.Recipe, bool) {
+	tags := queryList(r, "tag")
+	categoryID := r.URL.Query().Get("category")
+	if len(tags) == 0 && categoryID == "" {
+		return recipes, true
+	}
+
+	// how many of the tags each recipe has
+	tagged := map[string]int{}
+	for _, tag := range tags {
+		recipeIDs, err := client.dbClient.ListRecipeIDsByTag(tag)
+		if err != nil {
+			writeError(w, "error looking up tags", http.StatusInternalServerError)
+			return nil, false
+		}
+		for _, recipeID := range recipeIDs {
+			tagged[recipeID]++
+		}
+	}
+
+	var inCategory map[string]bool
+	if categoryID != "" {
+		categories, err := client.dbClient.ListCategories()
+		if err != nil {
+			writeError(w, "error listing categories", http.StatusInternalServerError)
+			return nil, false
+		}
+		if len(database.CategoryPath(categories, categoryID)) == 0 {
+			writeError(w, "could not find category with that id", http.StatusBadRequest)
+			return nil, false
+		}
+		inCategory = database.CategoryDescendants(categories, categoryID)
+	}
+
+	filtered := []database.Recipe{}
+	for _, recipe := range recipes {
+		if tagged[recipe.ID] != len(tags) {
+			continue
+		}
+		if inCategory != nil && !recipe.InCategory(inCategory) {
+			continue
+		}
+		filtered = append(filtered, recipe)
+	}
+	return filtered, true
+}
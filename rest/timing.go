@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+// - MARK: Helper Functions
+
+// keeps the recipes that take at most ?maxTime= minutes in total, and need
+// at most ?maxActiveTime= minutes of work. Recipes without any times can't
+// be vouched for so are left out. Timings are worked out again for recipes
+// saved before steps were timed.
+func filterRecipeTime(w http.ResponseWriter, r *http.Request, recipes []database.Recipe) ([]database.Recipe, bool) {
+	maxTime, ok := queryMinutes(w, r, "maxTime")
+	if !ok {
+		return nil, false
+	}
+	maxActiveTime, ok := queryMinutes(w, r, "maxActiveTime")
+	if !ok {
+		return nil, false
+	}
+	if maxTime < 0 && maxActiveTime < 0 {
+		return recipes, true
+	}
+
+	filtered := []database.Recipe{}
+	for _, recipe := range recipes {
+		recipe.ApplyTimings()
+		if recipe.TotalTime == 0 {
+			continue
+		}
+		if maxTime >= 0 && recipe.TotalTime > maxTime {
+			continue
+		}
+		if maxActiveTime >= 0 && (recipe.ActiveTime == 0 || recipe.ActiveTime > maxActiveTime) {
+			continue
+		}
+		filtered = append(filtered, recipe)
+	}
+	return filtered, true
+}
+
+// the number of minutes in the query parameter, or -1 when it isn't given
+func queryMinutes(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return -1, true
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		writeError(w, name+" must be a number of minutes", http.StatusBadRequest)
+		return 0, false
+	}
+	return minutes, true
+}
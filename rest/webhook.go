@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/slichlyter12/thyme-apiserver/backends/database"
+)
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type createWebhookResponse struct {
+	*database.Webhook
+	// Secret is only ever returned once, when the webhook is created
+	Secret string `json:"secret"`
+}
+
+// handles the /webhooks route
+func (client *Client) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	switch r.Method {
+	case "GET":
+		client.listWebhooks(w, r)
+		return
+	case "POST":
+		client.createWebhook(w, r)
+		return
+	case "DELETE":
+		client.deleteWebhook(w, r, vars["id"])
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// - MARK: Webhook methods
+
+// register a URL for the caller's events
+func (client *Client) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var request createWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, "error parsing JSON request", http.StatusBadRequest)
+		return
+	}
+
+	webhookURL, err := url.Parse(request.URL)
+	if err != nil || (webhookURL.Scheme != "https" && webhookURL.Scheme != "http") || webhookURL.Host == "" {
+		writeError(w, "url must be an http or https url", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Events) == 0 {
+		request.Events = database.WebhookEvents
+	}
+	for _, event := range request.Events {
+		if !database.ValidWebhookEvent(event) {
+			writeError(w, "events must be some of "+strings.Join(database.WebhookEvents, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+
+	webhook, err := client.dbClient.CreateWebhook(principalFrom(r).UserID, webhookURL.String(), request.Events)
+	if err != nil {
+		writeError(w, "could not create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(createWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+	if err != nil {
+		writeError(w, "could not encode webhook", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, bytes, http.StatusCreated)
+}
+
+// list the caller's webhooks
+func (client *Client) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := client.dbClient.ListWebhooks(principalFrom(r).UserID)
+	if err != nil {
+		writeError(w, "error listing webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(webhooks)
+	if err != nil {
+		writeError(w, "could not marshal webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+}
+
+// stop delivering to a webhook
+func (client *Client) deleteWebhook(w http.ResponseWriter, r *http.Request, id string) {
+	webhook, err := client.dbClient.GetWebhook(id)
+	if err != nil || webhook.UserID != principalFrom(r).UserID {
+		writeError(w, "could not find webhook with that id", http.StatusNotFound)
+		return
+	}
+
+	err = client.dbClient.DeleteWebhook(id)
+	if err != nil {
+		writeError(w, "could not delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	writeBytesStatus(w, nil, http.StatusNoContent)
+}
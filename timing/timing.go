@@ -0,0 +1,314 @@
+// Package timing reads the durations and temperatures out of recipe steps
+// written as free text ("bake 25-30 minutes at 350°F"), so clients can offer
+// timers and recipes can be totalled up.
+package timing
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Step is what a step's text says about time and temperature, in the order
+// it's written
+type Step struct {
+	Durations    []Duration    `json:"durations,omitempty"`
+	Temperatures []Temperature `json:"temperatures,omitempty"`
+}
+
+// Duration is a length of time a step mentions
+type Duration struct {
+	// Text is the duration as written, "25-30 minutes"
+	Text string `json:"text"`
+	// MinSeconds and MaxSeconds are the same unless a range was given
+	MinSeconds int `json:"minSeconds"`
+	MaxSeconds int `json:"maxSeconds"`
+	// Passive durations don't need the cook's attention, like baking,
+	// simmering or chilling
+	Passive bool `json:"passive"`
+}
+
+// Temperature is an oven or cooking temperature a step mentions, in both
+// scales
+type Temperature struct {
+	// Text is the temperature as written, "350°F"
+	Text       string `json:"text"`
+	Fahrenheit int    `json:"fahrenheit"`
+	Celsius    int    `json:"celsius"`
+}
+
+// the scale after a temperature
+const scalePattern = `(?i:fahrenheit|celsius|f|c)`
+
+// a number, "1", "1.5", "1/2", "1 1/2", "1½" or a word like "a" or "ten"
+const numberPattern = `\d+\s+\d+/\d+|\d+/\d+|\d*[½¼¾⅓⅔]|\d*\.?\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen|twenty|thirty|forty-five|forty|sixty`
+
+var (
+	// "10 minutes", "25-30 minutes", and "10 more minutes"
+	durationPattern = regexp.MustCompile(`(?i)\b(?:half an hour|overnight|(` + numberPattern + `)(?:\s*(?:-|–|to|or)\s*(` + numberPattern + `))?\s*(?:(?:more|additional)\s+)?(hours?|hrs?|minutes?|mins?|seconds?|secs?)\b)`)
+	// "350°F", "180 °C", "350 degrees", or "350F" without the degree sign,
+	// or just "to 425" or "at 350" when nothing but the end of the clause
+	// follows
+	temperaturePattern = regexp.MustCompile(`\b(\d{2,3})\s*(?:[°º]\s*(?:(` + scalePattern + `)\b)?|(?i:degrees?)\b(?:\s*(` + scalePattern + `)\b)?|([FC])\b)|(?i:\b(?:to|at)\s+)(\d{2,3})(?:\s+(?i:for|until|and|or|then|with)\b|\s*[,.;:!?)]|\s*$)`)
+	// steps are split into clauses so "stir for 2 minutes, then bake for
+	// 20" only counts the baking as passive
+	// temperatures without a scale only count after one of these in the
+	// same clause, so "rotate the pan 180 degrees" isn't a temperature
+	heatPattern    = regexp.MustCompile(`(?i)\b(?:oven|preheat|heat|bak|roast|broil|fry|fri|grill|cook|smok|temperature|thermometer|internal|reach|regist)\w*`)
+	clausePattern  = regexp.MustCompile(`[;!?]|\.(?:\s|$)|,|\bthen\b`)
+	passivePattern = regexp.MustCompile(`(?i)\b(?:bak|roast|simmer|brais|rest|chill|refrigerat|freez|marinat|rise|rising|proof|cool|soak|steep|stand|sit|set aside|slow cook|pressure cook|boil|steam|ferment|infus|dehydrat|brin|cure|poach|overnight)\w*`)
+)
+
+// seconds in each unit durations are written in
+var unitSeconds = map[string]int{
+	"hour": 3600, "hours": 3600, "hr": 3600, "hrs": 3600,
+	"minute": 60, "minutes": 60, "min": 60, "mins": 60,
+	"second": 1, "seconds": 1, "sec": 1, "secs": 1,
+}
+
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12, "fifteen": 15, "twenty": 20, "thirty": 30, "forty": 40,
+	"forty-five": 45, "sixty": 60,
+}
+
+var unicodeFractions = map[rune]float64{
+	'½': 0.5, '¼': 0.25, '¾': 0.75, '⅓': 1.0 / 3, '⅔': 2.0 / 3,
+}
+
+// Parse reads the durations and temperatures out of a step. Durations
+// written together, "1 hour 15 minutes", are read as one, alternatives,
+// "2 hours or overnight", as a range, and how often to do something,
+// "every 5 minutes", not at all. Temperatures without a scale are only read
+// after words like "bake" or "oven", and are taken as Fahrenheit above 250
+// and Celsius otherwise.
+func Parse(text string) Step {
+	step := Step{}
+
+	clauses := clausePattern.FindAllStringIndex(text, -1)
+	previousStart, previousEnd := 0, 0
+	for _, match := range durationPattern.FindAllStringSubmatchIndex(text, -1) {
+		duration, ok := parseDuration(text, match)
+		if !ok {
+			continue
+		}
+
+		// "stir every 5 minutes" is how often, not how long
+		if repeated(text[:match[0]]) {
+			continue
+		}
+
+		// "1 hour 15 minutes" and "1 hour and 15 minutes" are one duration,
+		// and "2 hours or overnight" is a range
+		last := len(step.Durations) - 1
+		if last >= 0 && joined(text[previousEnd:match[0]]) {
+			step.Durations[last].Text = text[previousStart:match[1]]
+			step.Durations[last].MinSeconds += duration.MinSeconds
+			step.Durations[last].MaxSeconds += duration.MaxSeconds
+			previousEnd = match[1]
+			continue
+		}
+		if last >= 0 && alternative(text[previousEnd:match[0]]) {
+			longest := &step.Durations[last]
+			longest.Text = text[previousStart:match[1]]
+			if duration.MinSeconds < longest.MinSeconds {
+				longest.MinSeconds = duration.MinSeconds
+			}
+			if duration.MaxSeconds > longest.MaxSeconds {
+				longest.MaxSeconds = duration.MaxSeconds
+			}
+			previousEnd = match[1]
+			continue
+		}
+
+		start, end := clauseAround(clauses, match[0], len(text))
+		duration.Passive = passivePattern.MatchString(text[start:end])
+		step.Durations = append(step.Durations, duration)
+		previousStart, previousEnd = match[0], match[1]
+	}
+
+	for _, indexes := range temperaturePattern.FindAllStringSubmatchIndex(text, -1) {
+		match := submatches(text, indexes)
+		written := strings.TrimSpace(match[0])
+		if match[5] != "" {
+			written = match[5]
+		}
+		degrees, err := strconv.Atoi(match[1] + match[5])
+		if err != nil {
+			continue
+		}
+		scale := strings.ToLower(match[2] + match[3] + match[4])
+		if scale == "" {
+			start, _ := clauseAround(clauses, indexes[0], len(text))
+			if !heatPattern.MatchString(text[start:indexes[0]]) {
+				continue
+			}
+			scale = "c"
+			if degrees > 250 {
+				scale = "f"
+			}
+		}
+
+		temperature := Temperature{Text: written}
+		if scale[0] == 'f' {
+			temperature.Fahrenheit = degrees
+			temperature.Celsius = int(math.Round(float64(degrees-32) * 5 / 9))
+		} else {
+			temperature.Celsius = degrees
+			temperature.Fahrenheit = int(math.Round(float64(degrees)*9/5 + 32))
+		}
+		step.Temperatures = append(step.Temperatures, temperature)
+	}
+
+	return step
+}
+
+// Empty reports whether the step mentions no durations or temperatures
+func (step Step) Empty() bool {
+	return len(step.Durations) == 0 && len(step.Temperatures) == 0
+}
+
+// ActiveSeconds adds up the durations that need the cook's attention,
+// using the upper bound of ranges
+func (step Step) ActiveSeconds() int {
+	return step.seconds(false)
+}
+
+// PassiveSeconds adds up the durations that don't need the cook's
+// attention, using the upper bound of ranges
+func (step Step) PassiveSeconds() int {
+	return step.seconds(true)
+}
+
+// - MARK: Helper Functions
+
+func (step Step) seconds(passive bool) int {
+	total := 0
+	for _, duration := range step.Durations {
+		if duration.Passive == passive {
+			total += duration.MaxSeconds
+		}
+	}
+	return total
+}
+
+// the text of each submatch, empty for ones that didn't match
+func submatches(text string, indexes []int) []string {
+	match := make([]string, len(indexes)/2)
+	for i := range match {
+		if indexes[2*i] >= 0 {
+			match[i] = text[indexes[2*i]:indexes[2*i+1]]
+		}
+	}
+	return match
+}
+
+// match holds the submatch indexes for durationPattern
+func parseDuration(text string, match []int) (Duration, bool) {
+	written := text[match[0]:match[1]]
+	switch strings.ToLower(written) {
+	case "half an hour":
+		return Duration{Text: written, MinSeconds: 1800, MaxSeconds: 1800}, true
+	case "overnight":
+		return Duration{Text: written, MinSeconds: 8 * 3600, MaxSeconds: 12 * 3600}, true
+	}
+
+	unit := unitSeconds[strings.ToLower(text[match[6]:match[7]])]
+	lower, ok := parseNumber(text[match[2]:match[3]])
+	if !ok {
+		return Duration{}, false
+	}
+	upper := lower
+	if match[4] >= 0 {
+		upper, ok = parseNumber(text[match[4]:match[5]])
+		if !ok {
+			return Duration{}, false
+		}
+	}
+	if upper < lower {
+		lower, upper = upper, lower
+	}
+
+	return Duration{
+		Text:       written,
+		MinSeconds: int(math.Round(lower * float64(unit))),
+		MaxSeconds: int(math.Round(upper * float64(unit))),
+	}, true
+}
+
+// reports whether the text between two durations makes them one
+func joined(between string) bool {
+	between = strings.ToLower(strings.TrimSpace(between))
+	return between == "" || between == "and"
+}
+
+// reports whether the text between two durations makes the second an
+// alternative to the first, "2 hours or overnight" or "10 minutes, or up to
+// an hour"
+func alternative(between string) bool {
+	between = strings.ToLower(strings.Join(strings.Fields(strings.Trim(between, " ,")), " "))
+	return between == "or" || between == "up to" || between == "or up to"
+}
+
+// reports whether the text before a duration says how often, "every"
+func repeated(before string) bool {
+	words := strings.Fields(strings.ToLower(before))
+	return len(words) > 0 && words[len(words)-1] == "every"
+}
+
+// the start and end of the clause the index falls in, clauses holding the
+// indexes of the separators between them
+func clauseAround(clauses [][]int, index int, length int) (int, int) {
+	i := sort.Search(len(clauses), func(i int) bool { return clauses[i][0] >= index })
+	start, end := 0, length
+	if i > 0 {
+		start = clauses[i-1][1]
+	}
+	if i < len(clauses) {
+		end = clauses[i][0]
+	}
+	return start, end
+}
+
+func parseNumber(text string) (float64, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if value, ok := numberWords[text]; ok {
+		return value, true
+	}
+
+	total := 0.0
+	fields := strings.Fields(text)
+	for _, field := range fields {
+		runes := []rune(field)
+		if fraction, ok := unicodeFractions[runes[len(runes)-1]]; ok {
+			total += fraction
+			field = string(runes[:len(runes)-1])
+			if field == "" {
+				continue
+			}
+		}
+
+		if parts := strings.SplitN(field, "/", 2); len(parts) == 2 {
+			numerator, err := strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return 0, false
+			}
+			denominator, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || denominator == 0 {
+				return 0, false
+			}
+			total += numerator / denominator
+			continue
+		}
+
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += value
+	}
+	return total, len(fields) > 0
+}
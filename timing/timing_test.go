@@ -0,0 +1,82 @@
+package timing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDurations(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []Duration
+	}{
+		{"Bake 25-30 minutes at 350°F.", []Duration{{"25-30 minutes", 1500, 1800, true}}},
+		{"Stir for 2 minutes, then simmer for 1 hour 15 minutes.", []Duration{
+			{"2 minutes", 120, 120, false},
+			{"1 hour 15 minutes", 4500, 4500, true},
+		}},
+		{"Knead for ten minutes. Let rise for 1½ hours", []Duration{
+			{"ten minutes", 600, 600, false},
+			{"1½ hours", 5400, 5400, true},
+		}},
+		{"Whisk for 30 secs", []Duration{{"30 secs", 30, 30, false}}},
+		{"Chill overnight", []Duration{{"overnight", 28800, 43200, true}}},
+		{"Cook 5 to 7 min, stirring", []Duration{{"5 to 7 min", 300, 420, false}}},
+		{"Season to taste", nil},
+		{"Bake 10 more minutes", []Duration{{"10 more minutes", 600, 600, true}}},
+		{"Simmer for 5 additional minutes", []Duration{{"5 additional minutes", 300, 300, true}}},
+		{"Marinate 2 hours or overnight", []Duration{{"2 hours or overnight", 7200, 43200, true}}},
+		{"Let rest 10 minutes, or up to 1 hour", []Duration{{"10 minutes, or up to 1 hour", 600, 3600, true}}},
+		{"Chill 30 minutes up to 2 hours", []Duration{{"30 minutes up to 2 hours", 1800, 7200, true}}},
+		{"Cook 1 to 2 hours, stirring every 15 minutes", []Duration{{"1 to 2 hours", 3600, 7200, false}}},
+	}
+
+	for _, test := range tests {
+		step := Parse(test.text)
+		if !reflect.DeepEqual(step.Durations, test.expected) {
+			t.Errorf("Parse(%q).Durations = %+v, expected %+v", test.text, step.Durations, test.expected)
+		}
+	}
+}
+
+func TestParseTemperatures(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []Temperature
+	}{
+		{"Bake 25-30 minutes at 350°F.", []Temperature{{"350°F", 350, 177}}},
+		{"Heat the oven to 180 °C", []Temperature{{"180 °C", 356, 180}}},
+		{"Roast at 425 degrees Fahrenheit", []Temperature{{"425 degrees Fahrenheit", 425, 218}}},
+		{"Preheat to 400F", []Temperature{{"400F", 400, 204}}},
+		{"Bake at 200°", []Temperature{{"200°", 392, 200}}},
+		{"Add 2 cups of flour", nil},
+		{"Rotate the pan 180 degrees", nil},
+		{"Rotate the pan 180 degrees and bake until golden", nil},
+		{"Heat the oil to 350 degrees", []Temperature{{"350 degrees", 350, 177}}},
+		{"Turn the dough 90°", nil},
+		{"Heat oven to 425", []Temperature{{"425", 425, 218}}},
+		{"Bake at 350 for 25 minutes", []Temperature{{"350", 350, 177}}},
+		{"Heat the oil to 180, then fry", []Temperature{{"180", 356, 180}}},
+		{"Bake at 350°F", []Temperature{{"350°F", 350, 177}}},
+		{"Add to 200 g of flour", nil},
+		{"Cook until reduced to 250 ml", nil},
+		{"Heat 2 tablespoons of oil and add 100 g of onions", nil},
+	}
+
+	for _, test := range tests {
+		step := Parse(test.text)
+		if !reflect.DeepEqual(step.Temperatures, test.expected) {
+			t.Errorf("Parse(%q).Temperatures = %+v, expected %+v", test.text, step.Temperatures, test.expected)
+		}
+	}
+}
+
+func TestStepSeconds(t *testing.T) {
+	step := Parse("Sauté the onions for 5 minutes, then bake 25-30 minutes")
+	if step.ActiveSeconds() != 300 {
+		t.Errorf("Expected 300 active seconds, got %d", step.ActiveSeconds())
+	}
+	if step.PassiveSeconds() != 1800 {
+		t.Errorf("Expected 1800 passive seconds, got %d", step.PassiveSeconds())
+	}
+}